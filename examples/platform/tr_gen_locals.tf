@@ -8,6 +8,8 @@ locals {
       "version" : "11",
       # The name provided here may get prefix and suffix based
       # @title: Database Name
+      # @pattern: ^[a-z][a-z0-9_]*$
+      # @maxLength: 63
       "db_name" : "default_db"
     }
   }
@@ -0,0 +1,23 @@
+// Copyright (c) Ollion
+// SPDX-License-Identifier: Apache-2.0
+
+package env
+
+import (
+	"github.com/cldcvr/terrarium/src/cli/cmd/env/render"
+	"github.com/spf13/cobra"
+)
+
+var cmd *cobra.Command
+
+func NewCmd() *cobra.Command {
+	cmd = &cobra.Command{
+		Use:   "env",
+		Short: "Terrarium app environment commands",
+		Long:  "Commands to manage the environment variables of the apps in the generated code",
+	}
+
+	cmd.AddCommand(render.NewCmd())
+
+	return cmd
+}
@@ -0,0 +1,23 @@
+// Copyright (c) Ollion
+// SPDX-License-Identifier: Apache-2.0
+
+package env
+
+import (
+	"testing"
+
+	"github.com/cldcvr/terrarium/src/pkg/testutils/clitesting"
+)
+
+func TestCmd(t *testing.T) {
+	clitest := clitesting.CLITest{
+		CmdToTest: NewCmd,
+	}
+
+	clitest.RunTests(t, []clitesting.CLITestCase{
+		{
+			Name:           "render help",
+			ValidateOutput: clitesting.ValidateOutputContains("Commands to manage the environment variables of the apps in the generated code\n\nUsage:\n  env [command]"),
+		},
+	})
+}
@@ -0,0 +1,172 @@
+// Copyright (c) Ollion
+// SPDX-License-Identifier: Apache-2.0
+
+package render
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cldcvr/terrarium/src/cli/internal/constants"
+	metautils "github.com/cldcvr/terrarium/src/pkg/metadata/utils"
+	"github.com/rotisserie/eris"
+	"github.com/spf13/cobra"
+)
+
+const (
+	templateFilePattern = "app_*.mustache"
+	templateFileSuffix  = ".mustache"
+	stdinFileName       = "-"
+)
+
+var (
+	cmd *cobra.Command
+
+	flagDir      string
+	flagTFOutput string
+	flagOutDir   string
+	flagApps     []string
+)
+
+func NewCmd() *cobra.Command {
+	cmd = &cobra.Command{
+		Use:   "render",
+		Short: "Render the app env files using the terraform outputs",
+		Long:  "Render the app env templates created by the generate command, using the output of 'terraform output -json' for the generated code.",
+		RunE:  cmdRunE,
+	}
+
+	cmd.Flags().StringVarP(&flagDir, "dir", "d", "./.terrarium", "path to the directory containing the generated code and app env templates")
+	cmd.Flags().StringVarP(&flagTFOutput, "tf-output", "t", stdinFileName, "path to the file containing the output of 'terraform output -json'. reads from stdin when set to '-'")
+	cmd.Flags().StringVarP(&flagOutDir, "output-dir", "o", "", "path to the directory to write the rendered env files to. defaults to the templates directory")
+	cmd.Flags().StringArrayVarP(&flagApps, "app", "a", nil, "id of the app to render the env files for. can be more then one. defaults to all apps")
+
+	return cmd
+}
+
+func cmdRunE(cmd *cobra.Command, args []string) error {
+	templates, err := findTemplates(flagDir, flagApps)
+	if err != nil {
+		return err
+	}
+
+	tfOutputs, err := readTFOutputs(cmd.InOrStdin(), flagTFOutput)
+	if err != nil {
+		return err
+	}
+
+	// render all templates before writing any file, so that nothing is written when an output is missing.
+	rendered := make(map[string]string, len(templates))
+	for _, tmplFile := range templates {
+		content, err := os.ReadFile(tmplFile)
+		if err != nil {
+			return eris.Wrapf(err, "failed to read file: %s", tmplFile)
+		}
+
+		// the templates with an unknown format, e.g. renamed by hand, are rendered without escaping the values.
+		format, _ := metautils.GetEnvFileFormat(filepath.Base(tmplFile))
+		rendered[tmplFile], err = metautils.RenderEnvTemplate(format, string(content), tfOutputs)
+		if err != nil {
+			return eris.Wrapf(err, "failed to render the env template '%s'", tmplFile)
+		}
+	}
+
+	outDir := flagOutDir
+	if outDir == "" {
+		outDir = flagDir
+	}
+
+	if err := checkTerraformFiles(templates, outDir, flagDir); err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(outDir, constants.ReadWriteExecutePermissions)
+	if err != nil {
+		return eris.Wrapf(err, "failed to create directory for %s", outDir)
+	}
+
+	for _, tmplFile := range templates {
+		outFile := filepath.Join(outDir, strings.TrimSuffix(filepath.Base(tmplFile), templateFileSuffix))
+		err := os.WriteFile(outFile, []byte(rendered[tmplFile]), constants.ReadWritePermissions)
+		if err != nil {
+			return eris.Wrapf(err, "failed to write file: %s", outFile)
+		}
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "Successfully rendered %d env file(s) at: %s\n", len(templates), outDir)
+	return nil
+}
+
+// checkTerraformFiles returns an error if a template renders to a terraform file in the generated module directory,
+// as terraform would load the resolved values with the generated code, and the next generate run would not manage the file.
+func checkTerraformFiles(templates []string, outDir, moduleDir string) error {
+	absOutDir, err := filepath.Abs(outDir)
+	if err != nil {
+		return eris.Wrapf(err, "failed to resolve the path: %s", outDir)
+	}
+
+	absModuleDir, err := filepath.Abs(moduleDir)
+	if err != nil {
+		return eris.Wrapf(err, "failed to resolve the path: %s", moduleDir)
+	}
+
+	if absOutDir != absModuleDir {
+		return nil
+	}
+
+	for _, tmplFile := range templates {
+		outFile := strings.TrimSuffix(filepath.Base(tmplFile), templateFileSuffix)
+		if strings.HasSuffix(outFile, ".tf") || strings.HasSuffix(outFile, ".tf.json") {
+			return eris.Errorf("can not render the terraform file '%s' into the generated module directory '%s'. use -o to write it to another directory", outFile, moduleDir)
+		}
+	}
+
+	return nil
+}
+
+// findTemplates returns the sorted paths of the app env templates in the dir, optionally filtered by the app IDs.
+func findTemplates(dir string, appIDs []string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, templateFilePattern))
+	if err != nil {
+		return nil, eris.Wrapf(err, "failed to list the app env templates in: %s", dir)
+	}
+
+	if len(appIDs) > 0 {
+		selected := []string{}
+		for _, appID := range appIDs {
+			appFiles, _ := filepath.Glob(filepath.Join(dir, "app_"+appID+".*"+templateFileSuffix))
+			if len(appFiles) == 0 {
+				return nil, eris.Errorf("no env templates found for app '%s' in: %s", appID, dir)
+			}
+			selected = append(selected, appFiles...)
+		}
+		files = selected
+	}
+
+	if len(files) == 0 {
+		return nil, eris.Errorf("no app env templates found in: %s. use 'terrarium generate' to create them", dir)
+	}
+
+	return files, nil
+}
+
+func readTFOutputs(stdin io.Reader, filePath string) (map[string]interface{}, error) {
+	var (
+		content []byte
+		err     error
+	)
+
+	if filePath == stdinFileName {
+		content, err = io.ReadAll(stdin)
+	} else {
+		content, err = os.ReadFile(filePath)
+	}
+	if err != nil {
+		return nil, eris.Wrapf(err, "failed to read the terraform outputs from: %s", filePath)
+	}
+
+	return metautils.ParseTerraformOutputs(content)
+}
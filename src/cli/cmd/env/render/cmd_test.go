@@ -0,0 +1,102 @@
+// Copyright (c) Ollion
+// SPDX-License-Identifier: Apache-2.0
+
+package render
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/cldcvr/terrarium/src/pkg/testutils/clitesting"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCmd(t *testing.T) {
+	os.RemoveAll("./testdata/.out")
+	testSetup := clitesting.CLITest{
+		CmdToTest: NewCmd,
+		TeardownTestCase: func(ctx context.Context, t *testing.T, tc clitesting.CLITestCase) {
+			os.RemoveAll("./testdata/.out")
+		},
+	}
+
+	tfOutput, err := os.ReadFile("./testdata/tf_output.json")
+	assert.NoError(t, err)
+
+	testSetup.RunTests(t, []clitesting.CLITestCase{
+		{
+			Name: "Success (from file)",
+			Args: []string{"-d", "./testdata", "-t", "./testdata/tf_output.json", "-o", "./testdata/.out", "-a", "voting_be"},
+			ValidateOutput: func(ctx context.Context, t *testing.T, cmdOpts clitesting.CmdOpts, output []byte) bool {
+				pass := assert.Equal(t, "Successfully rendered 2 env file(s) at: ./testdata/.out\n", string(output))
+				content, err := os.ReadFile("./testdata/.out/app_voting_be.env")
+				pass = assert.NoError(t, err) && pass
+				pass = assert.Equal(t, "BA_LEDGERDB_HOST=\"ledgerdb.example.com\"\nBA_LEDGERDB_PORT=\"5432\"\n", string(content)) && pass
+				content, err = os.ReadFile("./testdata/.out/app_voting_be.secrets.env")
+				pass = assert.NoError(t, err) && pass
+				pass = assert.Equal(t, "BA_LEDGERDB_PASSWORD=\"p\\\"a\\\\ss\\nword\"\n", string(content)) && pass
+				pass = assert.NoFileExists(t, "./testdata/.out/app_voting_worker.env") && pass
+				return pass
+			},
+		},
+		{
+			Name: "Success (from stdin)",
+			Args: []string{"-d", "./testdata", "-o", "./testdata/.out", "-a", "voting_be"},
+			PreExecute: func(ctx context.Context, t *testing.T, cmd *cobra.Command, cmdOpts clitesting.CmdOpts) {
+				cmd.SetIn(strings.NewReader(string(tfOutput)))
+			},
+			ValidateOutput: func(ctx context.Context, t *testing.T, cmdOpts clitesting.CmdOpts, output []byte) bool {
+				return assert.FileExists(t, "./testdata/.out/app_voting_be.env")
+			},
+		},
+		{
+			Name:     "Missing outputs",
+			Args:     []string{"-d", "./testdata", "-t", "./testdata/tf_output.json", "-o", "./testdata/.out"},
+			WantErr:  true,
+			ExpError: "failed to render the env template 'testdata/app_voting_worker.env.mustache': missing terraform outputs: tr_component_redis_host.value.cache",
+			ValidateOutput: func(ctx context.Context, t *testing.T, cmdOpts clitesting.CmdOpts, output []byte) bool {
+				return assert.NoDirExists(t, "./testdata/.out")
+			},
+		},
+		{
+			Name:     "Terraform file in the generated module directory",
+			Args:     []string{"-d", "./testdata/tf", "-t", "./testdata/tf_output.json"},
+			WantErr:  true,
+			ExpError: "can not render the terraform file 'app_voting_be.env.tf' into the generated module directory './testdata/tf'. use -o to write it to another directory",
+			ValidateOutput: func(ctx context.Context, t *testing.T, cmdOpts clitesting.CmdOpts, output []byte) bool {
+				return assert.NoFileExists(t, "./testdata/tf/app_voting_be.env.tf")
+			},
+		},
+		{
+			Name: "Success (terraform file in another directory)",
+			Args: []string{"-d", "./testdata/tf", "-t", "./testdata/tf_output.json", "-o", "./testdata/.out"},
+			ValidateOutput: func(ctx context.Context, t *testing.T, cmdOpts clitesting.CmdOpts, output []byte) bool {
+				content, err := os.ReadFile("./testdata/.out/app_voting_be.env.tf")
+				pass := assert.NoError(t, err)
+				pass = assert.Contains(t, string(content), `BA_LEDGERDB_HOST = "ledgerdb.example.com"`) && pass
+				return pass
+			},
+		},
+		{
+			Name:     "Unknown app",
+			Args:     []string{"-d", "./testdata", "-t", "./testdata/tf_output.json", "-a", "voting_fe"},
+			WantErr:  true,
+			ExpError: "no env templates found for app 'voting_fe' in: ./testdata",
+		},
+		{
+			Name:     "No templates",
+			Args:     []string{"-d", "./testdata/.out", "-t", "./testdata/tf_output.json"},
+			WantErr:  true,
+			ExpError: "no app env templates found in: ./testdata/.out",
+		},
+		{
+			Name:     "Invalid terraform outputs",
+			Args:     []string{"-d", "./testdata", "-t", "./testdata/app_voting_be.env.mustache"},
+			WantErr:  true,
+			ExpError: "failed to parse terraform outputs",
+		},
+	})
+}
@@ -72,16 +72,54 @@ func readAppDependency(appYamlPath string) ([]byte, error) {
 	return content, nil
 }
 
-func writeAppsEnv(pm *platform.PlatformMetadata, apps app.Apps) error {
+// writeAppsEnv writes the env variables template of each app in each of the given formats.
+// The sensitive env variables are written separately in each of the secretFormats.
+func writeAppsEnv(destDir string, pm *platform.PlatformMetadata, apps app.Apps, formats, secretFormats []string, manifest *genManifest) error {
 	for _, appObj := range apps {
 		vars := metautils.GetAppEnvTemplate(pm, appObj)
 		sort.Sort(vars)
-		fileName := "app_" + appObj.ID + ".env.mustache"
-		err := os.WriteFile(path.Join(flagOutDir, fileName), []byte(vars.RenderWithQuotes()), constants.ReadWritePermissions)
-		if err != nil {
-			return eris.Wrapf(err, "failed to write app env file")
+		plainVars, sensitiveVars := vars.Split()
+		for _, format := range formats {
+			if err := writeAppEnvFile(destDir, appObj.ID, format, plainVars, false, manifest); err != nil {
+				return err
+			}
+		}
+
+		if len(sensitiveVars) == 0 {
+			continue
+		}
+
+		for _, format := range secretFormats {
+			if err := writeAppEnvFile(destDir, appObj.ID, format, sensitiveVars, true, manifest); err != nil {
+				return err
+			}
 		}
 	}
 
 	return nil
 }
+
+func writeAppEnvFile(destDir, appID, format string, vars metautils.EnvVars, secret bool, manifest *genManifest) error {
+	r, err := metautils.GetEnvRenderer(metautils.EnvFormat(format))
+	if err != nil {
+		return err
+	}
+
+	content, err := r.Render(appID, vars)
+	if err != nil {
+		return err
+	}
+
+	fileName := r.FileName(appID)
+	if secret {
+		fileName = metautils.SecretFileName(r, appID)
+	}
+
+	err = os.WriteFile(path.Join(destDir, fileName), content, constants.ReadWritePermissions)
+	if err != nil {
+		return eris.Wrapf(err, "failed to write app env file")
+	}
+	manifest.addFile(fileName, "")
+
+	return nil
+}
@@ -5,11 +5,15 @@ package generate
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path"
+	"strings"
 
+	"github.com/charmbracelet/log"
 	"github.com/cldcvr/terraform-config-inspect/tfconfig"
 	"github.com/cldcvr/terrarium/src/cli/internal/constants"
+	"github.com/cldcvr/terrarium/src/pkg/metadata/app"
 	"github.com/cldcvr/terrarium/src/pkg/metadata/platform"
 	"github.com/cldcvr/terrarium/src/pkg/metadata/utils"
 	"github.com/rotisserie/eris"
@@ -19,12 +23,21 @@ import (
 var (
 	cmd *cobra.Command
 
-	flagPlatformDir         string
-	flagOutDir              string
-	flagApps                []string
-	flagProfile             string
-	flagIgnoreUnimplemented bool
-	flagSkipEnvFile         bool
+	flagPlatformDir          string
+	flagOutDir               string
+	flagApps                 []string
+	flagProfiles             []string
+	flagAllProfiles          bool
+	flagIgnoreUnimplemented  bool
+	flagSkipEnvFile          bool
+	flagDryRun               bool
+	flagEnvFormats           []string
+	flagSecretEnvFormats     []string
+	flagLayers               string
+	flagGraphOut             string
+	flagCheckLock            bool
+	flagDependencyInterfaces string
+	flagSplitApps            bool
 )
 
 func NewCmd() *cobra.Command {
@@ -35,17 +48,43 @@ func NewCmd() *cobra.Command {
 		RunE:  cmdRunE,
 	}
 
-	cmd.Flags().StringVarP(&flagPlatformDir, "platform-dir", "p", ".", "path to the directory containing the Terrarium platform template")
+	cmd.Flags().StringVarP(&flagPlatformDir, "platform-dir", "p", ".", "path to the directory containing the Terrarium platform template. can also be a git repository as '[git::]<url>[//<dir>][?ref=<ref>]' or a tarball as '<file>.tar.gz[//<dir>]'")
 	cmd.Flags().StringArrayVarP(&flagApps, "app", "a", nil, "path to the app directory or the app yaml file. can be more then one")
 	cmd.Flags().StringVarP(&flagOutDir, "output-dir", "o", "./.terrarium", "path to the directory where you want to generate the output")
-	cmd.Flags().StringVarP(&flagProfile, "configuration-profile", "c", "", "name of platform configuration profile to apply")
+	cmd.Flags().StringSliceVarP(&flagProfiles, "configuration-profile", "c", nil, "name of platform configuration profile to apply. can be more then one, in which case the code for each profile is generated in '<output-dir>/<profile>'")
+	cmd.Flags().BoolVar(&flagAllProfiles, "all-profiles", false, "generate the code for each configuration profile defined in the platform in '<output-dir>/<profile>'")
 	cmd.Flags().BoolVar(&flagIgnoreUnimplemented, "ignore-unimplemented", false, "set this to ignore errors when a component is not implemented in the platform") // not recommended
 	cmd.Flags().BoolVar(&flagSkipEnvFile, "skip-env-file", false, "set this to skip creating the env files for each app")                                         // not recommended
+	cmd.Flags().BoolVar(&flagDryRun, "dry-run", false, "show the changes to the output directory as a unified diff without writing them. exits with an error when changes are pending")
+	cmd.Flags().StringSliceVar(&flagEnvFormats, "env-format", []string{string(utils.EnvFormatDotEnv)}, fmt.Sprintf("format of the app env files. can be more then one. supported formats: %s", strings.Join(utils.EnvFormats(), ", ")))
+	cmd.Flags().StringSliceVar(&flagSecretEnvFormats, "secret-env-format", []string{string(utils.EnvFormatDotEnv)}, "format of the app env files for the env variables referring to sensitive component outputs, which are written separately from the other env variables. can be more then one")
+	cmd.Flags().StringVar(&flagLayers, "layers", "", "path to a layers manifest, listing the platforms to compose in order along with the apps of each layer. the code for each layer is generated in '<output-dir>/<layer-id>'")
+	cmd.Flags().StringVar(&flagGraphOut, "graph-out", "", "path to a file to export the graph of the pulled terraform blocks to. the format is determined by the file extension: '.dot' or '.gv' for graphviz DOT, '.mmd' or '.mermaid' for mermaid")
+	cmd.Flags().BoolVar(&flagCheckLock, "check-lock", false, "check that the platform, profile and app manifests are the same as the ones recorded in the 'terrarium.lock.json' file in the output directory, without generating the code")
+	cmd.Flags().StringVar(&flagDependencyInterfaces, "dependency-interfaces", "", "path to a dependency interfaces yaml file, or a directory containing them, to derive the taxonomy of the app dependencies from, instead of the farm database. used when the platform uses 'tr_taxon_<level>_enabled' switches, which are set based on the taxonomy levels in use")
+	cmd.Flags().BoolVar(&flagSplitApps, "split-apps", false, "generate a separate terraform root module for each app in '<output-dir>/<app-id>', and the dependencies provisioned by one app and used by another app with 'no_provision' in '<output-dir>/shared'. the app root modules read the shared dependency outputs from the state of the shared root module")
+	cmd.MarkFlagsMutuallyExclusive("configuration-profile", "all-profiles")
+	cmd.MarkFlagsMutuallyExclusive("check-lock", "dry-run")
+	for _, f := range []string{"platform-dir", "app", "configuration-profile", "all-profiles", "graph-out", "split-apps"} {
+		cmd.MarkFlagsMutuallyExclusive("layers", f)
+	}
 
 	return cmd
 }
 
 func cmdRunE(cmd *cobra.Command, args []string) error {
+	for _, formats := range [][]string{flagEnvFormats, flagSecretEnvFormats} {
+		for _, format := range formats {
+			if _, err := utils.GetEnvRenderer(utils.EnvFormat(format)); err != nil {
+				return err
+			}
+		}
+	}
+
+	if flagLayers != "" {
+		return runLayers(cmd.OutOrStdout())
+	}
+
 	if len(flagApps) == 0 {
 		return eris.New("No Apps provided. use -a flag to set apps")
 	}
@@ -55,9 +94,19 @@ func cmdRunE(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	m, _ := tfconfig.LoadModule(flagPlatformDir, &tfconfig.ResolvedModulesSchema{})
+	platformSrc, err := parsePlatformSource(flagPlatformDir)
+	if err != nil {
+		return err
+	}
 
-	existingYaml, _ := os.ReadFile(path.Join(flagPlatformDir, defaultYAMLFileName))
+	platformDir, err := resolvePlatformDir(platformSrc)
+	if err != nil {
+		return err
+	}
+
+	m, _ := tfconfig.LoadModule(platformDir, &tfconfig.ResolvedModulesSchema{})
+
+	existingYaml, _ := os.ReadFile(path.Join(platformDir, defaultYAMLFileName))
 
 	pm, _ := platform.NewPlatformMetadata(m, existingYaml)
 
@@ -66,23 +115,189 @@ func cmdRunE(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	err = os.MkdirAll(flagOutDir, constants.ReadWriteExecutePermissions)
+	targets, err := getTargets(m)
 	if err != nil {
-		return eris.Wrapf(err, "failed to create directory for %s", flagOutDir)
+		return err
+	}
+
+	if flagCheckLock && !flagSplitApps {
+		return checkTargetLocks(cmd.OutOrStdout(), targets, platformSrc, platformDir)
+	}
+
+	// the dry run does not write anything outside of its scratch directory, hence the graph is not exported.
+	if flagGraphOut != "" && !flagCheckLock && !flagDryRun {
+		if err := writeGraph(flagGraphOut, pm, apps); err != nil {
+			return err
+		}
+	}
+
+	if flagSplitApps {
+		return runSplitApps(cmd.OutOrStdout(), targets, platformSrc, pm, apps, m)
 	}
 
-	blockCount, err := writeTF(pm.Graph, flagOutDir, apps, m, flagProfile)
+	// the apps are generated after the apps they use as dependency, the same as when generating each app separately.
+	apps, err = apps.SortByDependencies()
 	if err != nil {
-		return eris.Wrapf(err, "failed to write terraform code to dir: %s", flagOutDir)
+		return err
+	}
+
+	if flagDryRun {
+		return dryRun(cmd.OutOrStdout(), func(outDir string) error {
+			return generateTargets(io.Discard, outDir, targets, platformSrc, pm, apps, m)
+		})
+	}
+
+	return generateTargets(cmd.OutOrStdout(), flagOutDir, targets, platformSrc, pm, apps, m)
+}
+
+// genTarget is a sub-directory of the output directory along with the configuration profile applied to it.
+type genTarget struct {
+	subDir  string
+	profile string
+}
+
+func (t genTarget) destDir(outDir string) string {
+	if t.subDir == "" {
+		return outDir
 	}
+	return path.Join(outDir, t.subDir)
+}
+
+// getTargets returns the directories to generate the code in. When more than one profile is requested,
+// each profile is generated in its own sub-directory of the output directory.
+func getTargets(m *tfconfig.Module) ([]genTarget, error) {
+	profiles := flagProfiles
+	if flagAllProfiles {
+		profiles = nil
+		var pArr platform.Profiles
+		pArr.Parse(m)
+		for _, p := range pArr {
+			profiles = append(profiles, p.ID)
+		}
+
+		if len(profiles) == 0 {
+			return nil, eris.Errorf("platform does not define any configuration profile in: %s", flagPlatformDir)
+		}
+	}
+
+	switch {
+	case len(profiles) == 0:
+		return []genTarget{{}}, nil
+	case len(profiles) == 1 && !flagAllProfiles:
+		return []genTarget{{profile: profiles[0]}}, nil
+	}
+
+	targets := make([]genTarget, 0, len(profiles))
+	for _, p := range profiles {
+		// fail before generating anything if any of the profiles is not defined.
+		if _, err := getProfileVariableInputSourceFile(m.Path, p); err != nil {
+			return nil, eris.Wrapf(err, "could not retrieve configuration file for platform profile '%s'", p)
+		}
+
+		targets = append(targets, genTarget{subDir: p, profile: p})
+	}
+
+	return targets, nil
+}
 
-	if !flagSkipEnvFile {
-		err = writeAppsEnv(pm, apps)
+// checkTargetLocks checks the lock file of each target in the output directory.
+func checkTargetLocks(out io.Writer, targets []genTarget, platformSrc *platformSource, platformDir string) error {
+	for _, t := range targets {
+		destDir := t.destDir(flagOutDir)
+		lock, err := newGenLock(destDir, platformSrc, platformDir, t.profile, flagApps)
 		if err != nil {
 			return err
 		}
+
+		if err := lock.checkLock(destDir); err != nil {
+			return err
+		}
+
+		fmt.Fprintf(out, "Lock file is up to date at: %s\n", destDir)
 	}
 
-	fmt.Fprintf(cmd.OutOrStdout(), "Successfully pulled %d of %d terraform blocks at: %s\n", blockCount, len(pm.Graph), flagOutDir)
 	return nil
 }
+
+// generateTargets generates the code for each target in the outDir.
+func generateTargets(out io.Writer, outDir string, targets []genTarget, platformSrc *platformSource, pm *platform.PlatformMetadata, apps app.Apps, m *tfconfig.Module) error {
+	for _, t := range targets {
+		destDir := t.destDir(outDir)
+		lock, err := newGenLock(destDir, platformSrc, m.Path, t.profile, flagApps)
+		if err != nil {
+			return err
+		}
+
+		blockCount, err := generate(destDir, t.profile, lock, pm, apps, m)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(out, "Successfully pulled %d of %d terraform blocks at: %s\n", blockCount, len(pm.Graph), destDir)
+	}
+
+	return nil
+}
+
+// generate writes the terraform code and the app env files to the destDir
+// and removes anything left over from the previous run that is no longer required.
+func generate(destDir, profile string, lock *genLock, pm *platform.PlatformMetadata, apps app.Apps, m *tfconfig.Module) (blockCount int, err error) {
+	return writeGenerated(destDir, lock, func(manifest, prev *genManifest) (int, error) {
+		blockCount, err := writeTF(pm.Graph, destDir, apps, m, profile, manifest, prev)
+		if err != nil {
+			return blockCount, eris.Wrapf(err, "failed to write terraform code to dir: %s", destDir)
+		}
+
+		if !flagSkipEnvFile {
+			err = writeAppsEnv(destDir, pm, apps, flagEnvFormats, flagSecretEnvFormats, manifest)
+		}
+
+		return blockCount, err
+	})
+}
+
+// writeGenerated calls write to write the generated files to the destDir, and then removes
+// the files and blocks left over from the previous run that were not written again.
+// The resulting module is validated before the provenance header is added to the generated files
+// and the inputs are recorded in the lock file.
+func writeGenerated(destDir string, lock *genLock, write func(manifest, prev *genManifest) (int, error)) (blockCount int, err error) {
+	err = os.MkdirAll(destDir, constants.ReadWriteExecutePermissions)
+	if err != nil {
+		return 0, eris.Wrapf(err, "failed to create directory for %s", destDir)
+	}
+
+	prevManifest, err := readGenManifest(destDir)
+	if err != nil {
+		return 0, err
+	}
+
+	manifest := newGenManifest()
+	blockCount, err = write(manifest, prevManifest)
+	if err != nil {
+		return blockCount, err
+	}
+
+	removed, err := manifest.prune(destDir, prevManifest)
+	if err != nil {
+		return blockCount, err
+	}
+	if len(removed) > 0 {
+		log.Info("removed stale generated files", "files", removed)
+	}
+
+	// the manifest is written before validating, so that the next run
+	// can replace the generated files even if this code turns out to be invalid.
+	if err := manifest.write(destDir); err != nil {
+		return blockCount, err
+	}
+
+	if err := validateGenerated(destDir, lock.Profile); err != nil {
+		return blockCount, err
+	}
+
+	if err := lock.addProvenanceHeaders(destDir, manifest); err != nil {
+		return blockCount, err
+	}
+
+	return blockCount, lock.write(destDir)
+}
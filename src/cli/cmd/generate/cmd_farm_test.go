@@ -0,0 +1,66 @@
+// Copyright (c) Ollion
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build mock
+// +build mock
+
+package generate
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/cldcvr/terrarium/src/cli/internal/config"
+	"github.com/cldcvr/terrarium/src/pkg/db"
+	"github.com/cldcvr/terrarium/src/pkg/db/mocks"
+	"github.com/cldcvr/terrarium/src/pkg/testutils/clitesting"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCmd_farmTaxonomy(t *testing.T) {
+	config.LoadDefaults()
+	mockDB := &mocks.DB{}
+	mockDB.On("QueryDependencies").Return(nil, fmt.Errorf("mock error")).Once()
+	mockDB.On("QueryDependencies").Return(db.Dependencies{}, nil).Once()
+	mockDB.On("QueryDependencies").Return(db.Dependencies{
+		{InterfaceID: "postgres", Taxonomy: &db.Taxonomy{Level1: "storage", Level2: "database", Level3: "rdbms"}},
+		{InterfaceID: "redis", Taxonomy: &db.Taxonomy{Level1: "storage", Level2: "cache"}},
+	}, nil)
+	config.SetDBMocks(mockDB)
+
+	os.RemoveAll("./testdata/.terrarium")
+	clitest := clitesting.CLITest{
+		CmdToTest: NewCmd,
+		TeardownTestCase: func(ctx context.Context, t *testing.T, tc clitesting.CLITestCase) {
+			os.RemoveAll("./testdata/.terrarium")
+		},
+	}
+
+	clitest.RunTests(t, []clitesting.CLITestCase{
+		{
+			Name:     "db failure",
+			Args:     []string{"-p", "./testdata/taxons/platform", "-a", "./testdata/taxons/app.yaml", "-o", "./testdata/.terrarium"},
+			WantErr:  true,
+			ExpError: "failed to load the taxonomy of the app dependencies to set the taxon switches [tr_taxon_cache_enabled, tr_taxon_database_enabled]",
+		},
+		{
+			Name:     "no dependency interfaces in the farm",
+			Args:     []string{"-p", "./testdata/taxons/platform", "-a", "./testdata/taxons/app.yaml", "-o", "./testdata/.terrarium"},
+			WantErr:  true,
+			ExpError: "the farm database has no dependency interfaces",
+		},
+		{
+			Name: "taxon switches from the farm dependency interfaces",
+			Args: []string{"-p", "./testdata/taxons/platform", "-a", "./testdata/taxons/app.yaml", "-o", "./testdata/.terrarium"},
+			ValidateOutput: func(ctx context.Context, t *testing.T, cmdOpts clitesting.CmdOpts, output []byte) bool {
+				localsFile, err := os.ReadFile("./testdata/.terrarium/tr_gen_locals.tf")
+				pass := assert.NoError(t, err)
+				pass = assert.Contains(t, string(localsFile), "  tr_taxon_cache_enabled        = false\n") && pass
+				pass = assert.Contains(t, string(localsFile), "  tr_taxon_database_enabled     = true\n") && pass
+				return pass
+			},
+		},
+	})
+}
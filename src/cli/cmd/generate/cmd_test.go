@@ -5,11 +5,14 @@ package generate
 
 import (
 	"context"
+	"io"
 	"os"
 	"path"
+	"strings"
 	"testing"
 
 	"github.com/cldcvr/terrarium/src/pkg/testutils/clitesting"
+	"github.com/spf13/cobra"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -43,7 +46,7 @@ func TestCmd(t *testing.T) {
 			Name: "Success (no env files)",
 			Args: []string{"-p", "../../../../examples/platform/", "-a", "../../../../examples/apps/voting-be", "-a", "../../../../examples/apps/voting-worker", "-o", "./testdata/.terrarium", "--skip-env-file"},
 			ValidateOutput: func(ctx context.Context, t *testing.T, cmdOpts clitesting.CmdOpts, output []byte) bool {
-				pass := assert.Equal(t, "Successfully pulled 13 of 22 terraform blocks at: ./testdata/.terrarium\n", string(output))
+				pass := assert.Equal(t, "Successfully pulled 14 of 23 terraform blocks at: ./testdata/.terrarium\n", string(output))
 				pass = assertFilesExists(t,
 					"./testdata/.terrarium",
 					[]string{ // shouldExist
@@ -67,7 +70,7 @@ func TestCmd(t *testing.T) {
 			Name: "Success (no profile)",
 			Args: []string{"-p", "../../../../examples/platform/", "-a", "../../../../examples/apps/voting-be", "-a", "../../../../examples/apps/voting-worker", "-o", "./testdata/.terrarium"},
 			ValidateOutput: func(ctx context.Context, t *testing.T, cmdOpts clitesting.CmdOpts, output []byte) bool {
-				pass := assert.Equal(t, "Successfully pulled 13 of 22 terraform blocks at: ./testdata/.terrarium\n", string(output))
+				pass := assert.Equal(t, "Successfully pulled 14 of 23 terraform blocks at: ./testdata/.terrarium\n", string(output))
 				pass = assertFilesExists(t,
 					"./testdata/.terrarium",
 					[]string{ // shouldExist
@@ -77,6 +80,7 @@ func TestCmd(t *testing.T) {
 						"outputs.tf",
 						"tr_base_backend.tf",
 						"tr_gen_locals.tf",
+						"tr_gen_manifest.json",
 						"vpc.tf",
 					},
 					[]string{ // shouldNotExist
@@ -91,7 +95,7 @@ func TestCmd(t *testing.T) {
 			Name: "Success (with profile)",
 			Args: []string{"-p", "../../../../examples/platform/", "-a", "../../../../examples/apps/voting-be", "-a", "../../../../examples/apps/voting-worker", "-o", "./testdata/.terrarium", "-c", "dev"},
 			ValidateOutput: func(ctx context.Context, t *testing.T, cmdOpts clitesting.CmdOpts, output []byte) bool {
-				pass := assert.Equal(t, "Successfully pulled 13 of 22 terraform blocks at: ./testdata/.terrarium\n", string(output))
+				pass := assert.Equal(t, "Successfully pulled 14 of 23 terraform blocks at: ./testdata/.terrarium\n", string(output))
 				pass = assertFilesExists(t,
 					"./testdata/.terrarium",
 					[]string{ // shouldExist
@@ -108,9 +112,87 @@ func TestCmd(t *testing.T) {
 						"component_postgres.tf",
 					},
 				) && pass
+
+				// the variables set by the profile are declared, even when no pulled block refers to them.
+				vars, err := os.ReadFile("./testdata/.terrarium/variables.tf")
+				pass = assert.NoError(t, err) && pass
+				pass = assert.Contains(t, string(vars), "variable \"all_db_instance_class\" {") && pass
 				return pass
 			},
 		},
+		{
+			Name: "Success (lock file)",
+			Args: []string{"-p", "../../../../examples/platform/", "-a", "../../../../examples/apps/voting-be", "-a", "../../../../examples/apps/voting-worker", "-o", "./testdata/.terrarium", "-c", "dev"},
+			ValidateOutput: func(ctx context.Context, t *testing.T, cmdOpts clitesting.CmdOpts, output []byte) bool {
+				lock, err := readGenLock("./testdata/.terrarium")
+				pass := assert.NoError(t, err)
+				pass = assert.Equal(t, "DEV", lock.CLIVersion) && pass
+				pass = assert.Equal(t, "../../../../../../examples/platform", lock.Platform.Source) && pass
+				pass = assert.NotEmpty(t, lock.Platform.MetadataHash) && pass
+				pass = assert.Equal(t, "dev", lock.Profile) && pass
+				pass = assert.Len(t, lock.Apps, 2) && pass
+				pass = assert.Equal(t, "voting_be", lock.Apps[0].ID) && pass
+				pass = assert.Equal(t, "../../../../../../examples/apps/voting-be", lock.Apps[0].Path) && pass
+				pass = assert.Len(t, lock.Apps[0].Hash, 64) && pass
+
+				header := "# Generated by terrarium DEV from the platform ../../../../../../examples/platform with the 'dev' profile.\n# The inputs are recorded in terrarium.lock.json.\n\n"
+				for _, f := range []string{"component_redis.tf", "tr_gen_locals.tf", "tr_gen_profile.auto.tfvars", "app_voting_be.env.mustache"} {
+					content, err := os.ReadFile(path.Join("./testdata/.terrarium", f))
+					pass = assert.NoError(t, err) && pass
+					pass = assert.True(t, strings.HasPrefix(string(content), header), "missing provenance header in %s", f) && pass
+					pass = assert.Equal(t, 1, strings.Count(string(content), "# Generated by terrarium"), "duplicate provenance header in %s", f) && pass
+				}
+				return pass
+			},
+		},
+		{
+			Name: "Check lock (up to date)",
+			Args: []string{"-p", "../../../../examples/platform/", "-a", "../../../../examples/apps/voting-be", "-a", "../../../../examples/apps/voting-worker", "-o", "./testdata/.terrarium", "-c", "dev", "--check-lock"},
+			PreExecute: func(ctx context.Context, t *testing.T, cmd *cobra.Command, cmdOpts clitesting.CmdOpts) {
+				runGenerate(t, "-p", "../../../../examples/platform/", "-a", "../../../../examples/apps/voting-be", "-a", "../../../../examples/apps/voting-worker", "-o", "./testdata/.terrarium", "-c", "dev")
+			},
+			ValidateOutput: clitesting.ValidateOutputMatch("Lock file is up to date at: ./testdata/.terrarium\n"),
+		},
+		{
+			Name:     "Check lock (inputs differ)",
+			Args:     []string{"-p", "../../../../examples/platform/", "-a", "../../../../examples/apps/voting-be", "-a", "../../../../examples/apps/voting-worker", "-o", "./testdata/.terrarium", "-c", "prod", "--check-lock"},
+			WantErr:  true,
+			ExpError: "profile: 'dev' -> 'prod'",
+			PreExecute: func(ctx context.Context, t *testing.T, cmd *cobra.Command, cmdOpts clitesting.CmdOpts) {
+				runGenerate(t, "-p", "../../../../examples/platform/", "-a", "../../../../examples/apps/voting-be", "-a", "../../../../examples/apps/voting-worker", "-o", "./testdata/.terrarium", "-c", "dev")
+			},
+		},
+		{
+			Name:     "Check lock (missing)",
+			Args:     []string{"-p", "../../../../examples/platform/", "-a", "../../../../examples/apps/voting-be", "-a", "../../../../examples/apps/voting-worker", "-o", "./testdata/.terrarium", "--check-lock"},
+			WantErr:  true,
+			ExpError: "failed to read lock file: testdata/.terrarium/terrarium.lock.json",
+		},
+		{
+			Name: "Success (env formats)",
+			Args: []string{"-p", "../../../../examples/platform/", "-a", "../../../../examples/apps/voting-be", "-a", "../../../../examples/apps/voting-worker", "-o", "./testdata/.terrarium", "--env-format", "k8s-configmap,json"},
+			ValidateOutput: func(ctx context.Context, t *testing.T, cmdOpts clitesting.CmdOpts, output []byte) bool {
+				return assertFilesExists(t,
+					"./testdata/.terrarium",
+					[]string{ // shouldExist
+						"app_voting_be.configmap.yaml.mustache",
+						"app_voting_be.env.json.mustache",
+						"app_voting_worker.configmap.yaml.mustache",
+						"app_voting_worker.env.json.mustache",
+					},
+					[]string{ // shouldNotExist
+						"app_voting_be.env.mustache",
+						"app_voting_worker.env.mustache",
+					},
+				)
+			},
+		},
+		{
+			Name:     "Invalid env format",
+			Args:     []string{"-p", "../../../../examples/platform/", "-a", "../../../../examples/apps/voting-be", "-o", "./testdata/.terrarium", "--env-format", "xml"},
+			WantErr:  true,
+			ExpError: "unsupported env format 'xml'",
+		},
 		{
 			Name: "Success (edge case absolute path empty block)",
 			Args: []string{
@@ -143,15 +225,302 @@ func TestCmd(t *testing.T) {
 				return pass
 			},
 		},
+		{
+			Name:     "Dry run (changes pending)",
+			Args:     []string{"-p", "../../../../examples/platform/", "-a", "../../../../examples/apps/voting-be", "-a", "../../../../examples/apps/voting-worker", "-o", "./testdata/.terrarium", "--dry-run", "--graph-out", "./testdata/.terrarium/pulled.mmd"},
+			WantErr:  true,
+			ExpError: "generated code has pending changes",
+			ValidateOutput: func(ctx context.Context, t *testing.T, cmdOpts clitesting.CmdOpts, output []byte) bool {
+				pass := assert.Contains(t, string(output), "--- a/component_redis.tf\n+++ b/component_redis.tf\n")
+				pass = assert.Contains(t, string(output), "+++ b/app_voting_be.env.mustache\n") && pass
+				pass = assert.NoDirExists(t, "./testdata/.terrarium") && pass
+				return pass
+			},
+		},
+		{
+			Name: "Success (all profiles)",
+			Args: []string{"-p", "../../../../examples/platform/", "-a", "../../../../examples/apps/voting-be", "-a", "../../../../examples/apps/voting-worker", "-o", "./testdata/.terrarium", "--all-profiles"},
+			ValidateOutput: func(ctx context.Context, t *testing.T, cmdOpts clitesting.CmdOpts, output []byte) bool {
+				pass := assert.Equal(t, "Successfully pulled 14 of 23 terraform blocks at: testdata/.terrarium/dev\nSuccessfully pulled 14 of 23 terraform blocks at: testdata/.terrarium/prod\n", string(output))
+				for _, profile := range []string{"dev", "prod"} {
+					pass = assertFilesExists(t,
+						path.Join("./testdata/.terrarium", profile),
+						[]string{ // shouldExist
+							"app_voting_be.env.mustache",
+							"component_redis.tf",
+							"tr_base_backend.tf",
+							"tr_gen_manifest.json",
+							"tr_gen_profile.auto.tfvars",
+							"tr_gen_profile.tfbackend",
+						},
+						nil,
+					) && pass
+				}
+
+				prodVars, err := os.ReadFile("./testdata/.terrarium/prod/tr_gen_profile.auto.tfvars")
+				pass = assert.NoError(t, err) && pass
+				pass = assert.Contains(t, string(prodVars), `all_db_instance_class = "t2.large"`) && pass
+				return pass
+			},
+		},
+		{
+			Name:     "Invalid profile in list",
+			Args:     []string{"-p", "../../../../examples/platform/", "-a", "../../../../examples/apps/voting-be", "-a", "../../../../examples/apps/voting-worker", "-o", "./testdata/.terrarium", "-c", "dev,Isle"},
+			WantErr:  true,
+			ExpError: "could not retrieve configuration file for platform profile 'Isle'",
+			ValidateOutput: func(ctx context.Context, t *testing.T, cmdOpts clitesting.CmdOpts, output []byte) bool {
+				return assert.NoDirExists(t, "./testdata/.terrarium")
+			},
+		},
 		{
 			Name:     "Invalid profile name",
 			Args:     []string{"-p", "../../../../examples/platform/", "-a", "../../../../examples/apps/voting-be", "-a", "../../../../examples/apps/voting-worker", "-o", "./testdata/.terrarium", "-c", "Isle"},
 			WantErr:  true,
 			ExpError: "could not retrieve configuration file for platform profile 'Isle'",
 		},
+		{
+			Name: "Success (graph out)",
+			Args: []string{"-p", "../../../../examples/platform/", "-a", "../../../../examples/apps/voting-be", "-a", "../../../../examples/apps/voting-worker", "-o", "./testdata/.terrarium", "--graph-out", "./testdata/.terrarium/graph/pulled.mmd"},
+			ValidateOutput: func(ctx context.Context, t *testing.T, cmdOpts clitesting.CmdOpts, output []byte) bool {
+				graph, err := os.ReadFile("./testdata/.terrarium/graph/pulled.mmd")
+				pass := assert.NoError(t, err)
+				pass = assert.Contains(t, string(graph), "flowchart LR\n") && pass
+				pass = assert.Contains(t, string(graph), `["module.tr_component_redis"]:::component`) && pass
+				pass = assert.Contains(t, string(graph), `["output.tr_component_redis_host"]:::output`) && pass
+				pass = assert.NotContains(t, string(graph), "module.tr_component_postgres") && pass
+				return pass
+			},
+		},
+		{
+			Name:     "Invalid graph out extension",
+			Args:     []string{"-p", "../../../../examples/platform/", "-a", "../../../../examples/apps/voting-be", "-a", "../../../../examples/apps/voting-worker", "-o", "./testdata/.terrarium", "--graph-out", "./testdata/.terrarium/graph.svg"},
+			WantErr:  true,
+			ExpError: "unsupported graph file extension '.svg'",
+		},
+		{
+			Name: "Success (sensitive outputs)",
+			Args: []string{"-p", "./testdata/secrets/platform", "-a", "./testdata/secrets/app.yaml", "-o", "./testdata/.terrarium", "--secret-env-format", "dotenv,k8s-secret"},
+			ValidateOutput: func(ctx context.Context, t *testing.T, cmdOpts clitesting.CmdOpts, output []byte) bool {
+				envFile, err := os.ReadFile("./testdata/.terrarium/app_api.env.mustache")
+				pass := assert.NoError(t, err)
+				pass = assert.Equal(t, "# Generated by terrarium DEV from the platform ../secrets/platform.\n# The inputs are recorded in terrarium.lock.json.\n\nAPI_DB_HOST=\"{{ tr_component_postgres_host.value.db }}\"\n", string(envFile)) && pass
+
+				secretEnvFile, err := os.ReadFile("./testdata/.terrarium/app_api.secrets.env.mustache")
+				pass = assert.NoError(t, err) && pass
+				pass = assert.Contains(t, string(secretEnvFile), "\n\nAPI_DB_URL=\"postgres://{{ tr_component_postgres_host.value.db }}/app?password={{ tr_component_postgres_password.value.db }}\"\n") && pass
+
+				k8sSecretFile, err := os.ReadFile("./testdata/.terrarium/app_api.secrets.secret.yaml.mustache")
+				pass = assert.NoError(t, err) && pass
+				pass = assert.Contains(t, string(k8sSecretFile), "kind: Secret\n") && pass
+				pass = assert.Contains(t, string(k8sSecretFile), "  API_DB_URL: \"postgres://") && pass
+				pass = assert.NotContains(t, string(k8sSecretFile), "API_DB_HOST") && pass
+				return pass
+			},
+		},
+		{
+			Name:     "Invalid secret env format",
+			Args:     []string{"-p", "./testdata/secrets/platform", "-a", "./testdata/secrets/app.yaml", "-o", "./testdata/.terrarium", "--secret-env-format", "xml"},
+			WantErr:  true,
+			ExpError: "unsupported env format 'xml'",
+		},
+		{
+			Name: "Success (taxon switches)",
+			Args: []string{"-p", "./testdata/taxons/platform", "-a", "./testdata/taxons/app.yaml", "-o", "./testdata/.terrarium", "--dependency-interfaces", "./testdata/taxons/dependencies.yaml"},
+			ValidateOutput: func(ctx context.Context, t *testing.T, cmdOpts clitesting.CmdOpts, output []byte) bool {
+				localsFile, err := os.ReadFile("./testdata/.terrarium/tr_gen_locals.tf")
+				pass := assert.NoError(t, err)
+				pass = assert.Contains(t, string(localsFile), "  tr_component_postgres_enabled = true\n") && pass
+				pass = assert.Contains(t, string(localsFile), "  tr_component_redis_enabled    = false\n") && pass
+				pass = assert.Contains(t, string(localsFile), "  tr_taxon_cache_enabled        = false\n") && pass
+				pass = assert.Contains(t, string(localsFile), "  tr_taxon_database_enabled     = true\n") && pass
+				return pass
+			},
+		},
+		{
+			Name:     "Unresolved reference in generated code",
+			Args:     []string{"-p", "./testdata/unresolved/platform", "-a", "./testdata/unresolved/app.yaml", "-o", "./testdata/.terrarium"},
+			WantErr:  true,
+			ExpError: "generated terraform code in './testdata/.terrarium' is invalid:\n  main.tf:7: reference to undeclared local.subnet_ids",
+		},
+		{
+			Name:     "App dependency with another id",
+			Args:     []string{"-p", "../../../../examples/platform/", "-a", "./testdata/appdeps/be.yaml", "-a", "./testdata/appdeps/fe_with_id.yaml", "-o", "./testdata/.terrarium"},
+			WantErr:  true,
+			ExpError: "dependency 'backend' of app 'fe' refers to the app 'be', hence its id must be 'be' or omitted",
+		},
+		{
+			Name:     "App dependency cycle",
+			Args:     []string{"-p", "../../../../examples/platform/", "-a", "./testdata/appdeps/cycle_a.yaml", "-a", "./testdata/appdeps/cycle_b.yaml", "-o", "./testdata/.terrarium"},
+			WantErr:  true,
+			ExpError: "apps depend on each other in a cycle: a -> b -> a",
+		},
+		{
+			Name: "Success (regenerate after a dependency is removed)",
+			Args: []string{"-p", "./testdata/regenerate/platform", "-a", "./testdata/regenerate/app_postgres.yaml", "-o", "./testdata/.terrarium"},
+			PreExecute: func(ctx context.Context, t *testing.T, cmd *cobra.Command, cmdOpts clitesting.CmdOpts) {
+				runGenerate(t, "-p", "./testdata/regenerate/platform", "-a", "./testdata/regenerate/app.yaml", "-o", "./testdata/.terrarium")
+				assertFilesExists(t, "./testdata/.terrarium", []string{"a.tf", "postgres.tf", "variables.tf"}, nil)
+			},
+			ValidateOutput: func(ctx context.Context, t *testing.T, cmdOpts clitesting.CmdOpts, output []byte) bool {
+				// the files generated for the removed dependency are pruned before the generated code is validated.
+				pass := assert.Equal(t, "Successfully pulled 2 of 5 terraform blocks at: ./testdata/.terrarium\n", string(output))
+				pass = assertFilesExists(t, "./testdata/.terrarium", []string{"postgres.tf", "tr_gen_locals.tf"}, []string{"a.tf", "variables.tf"}) && pass
+
+				manifest, err := readGenManifest("./testdata/.terrarium")
+				pass = assert.NoError(t, err) && pass
+				pass = assert.NotContains(t, manifest.Files, "a.tf") && pass
+				return pass
+			},
+		},
+		{
+			Name: "Success (implementation by version)",
+			Args: []string{"-p", "./testdata/versions/platform", "-a", "./testdata/versions/app.yaml", "-o", "./testdata/.terrarium"},
+			ValidateOutput: func(ctx context.Context, t *testing.T, cmdOpts clitesting.CmdOpts, output []byte) bool {
+				localsFile, err := os.ReadFile("./testdata/.terrarium/tr_gen_locals.tf")
+				pass := assert.NoError(t, err)
+				pass = assert.Contains(t, string(localsFile), "  tr_component_postgres__v11 = {\n    legacy_db = {\n      storage = 20\n    }\n  }\n") && pass
+				pass = assert.Contains(t, string(localsFile), "  tr_component_postgres__v15 = {\n    db = {\n      storage = 20\n    }\n  }\n") && pass
+				pass = assert.NotContains(t, string(localsFile), "version =", "the version input is consumed by the implementation selection") && pass
+
+				envFile, err := os.ReadFile("./testdata/.terrarium/app_ledger.env.mustache")
+				pass = assert.NoError(t, err) && pass
+				pass = assert.True(t, strings.HasSuffix(string(envFile), "\n\nLEDGER_DB_HOST=\"{{ tr_component_postgres__v15_host.value.db }}\"\nLEDGER_LEGACY_DB_HOST=\"{{ tr_component_postgres__v11_host.value.legacy_db }}\"\n"), "env var names are derived from the dependency, not its implementation") && pass
+				return pass
+			},
+		},
+		{
+			Name:     "Unsupported implementation version",
+			Args:     []string{"-p", "./testdata/versions/platform", "-a", "./testdata/versions/app_unsupported.yaml", "-o", "./testdata/.terrarium"},
+			WantErr:  true,
+			ExpError: "none of the implementations of component 'postgres' supports the version '10'. available implementations: [postgres__v11 (>= 11, < 12), postgres__v15 (>= 12)]",
+		},
+		{
+			Name: "Success (split apps)",
+			Args: []string{"-p", "./testdata/split/platform", "-a", "./testdata/split/apps/api.yaml", "-a", "./testdata/split/apps/worker.yaml", "-o", "./testdata/.terrarium", "--split-apps"},
+			ValidateOutput: func(ctx context.Context, t *testing.T, cmdOpts clitesting.CmdOpts, output []byte) bool {
+				pass := assert.Equal(t, "Successfully pulled 3 of 6 terraform blocks for root 'shared' at: testdata/.terrarium/shared\nSuccessfully pulled 3 of 6 terraform blocks for root 'api' at: testdata/.terrarium/api\nSuccessfully pulled 3 of 6 terraform blocks for root 'worker' at: testdata/.terrarium/worker\n", string(output))
+				pass = assertFilesExists(t,
+					"./testdata/.terrarium/shared",
+					[]string{"main.tf", "tr_gen_locals.tf", "terrarium.lock.json"},
+					[]string{"tr_gen_layers.tf", "app_api.env.mustache", "app_worker.env.mustache"},
+				) && pass
+				pass = assertFilesExists(t,
+					"./testdata/.terrarium/worker",
+					[]string{"main.tf", "tr_gen_locals.tf", "tr_gen_layers.tf", "app_worker.env.mustache"},
+					[]string{"app_api.env.mustache"},
+				) && pass
+
+				sharedLocals, err := os.ReadFile("./testdata/.terrarium/shared/tr_gen_locals.tf")
+				pass = assert.NoError(t, err) && pass
+				pass = assert.Contains(t, string(sharedLocals), "  tr_component_postgres = {\n    db = {") && pass
+				pass = assert.NotContains(t, string(sharedLocals), "tr_component_redis") && pass
+
+				workerLocals, err := os.ReadFile("./testdata/.terrarium/worker/tr_gen_locals.tf")
+				pass = assert.NoError(t, err) && pass
+				pass = assert.Contains(t, string(workerLocals), "  tr_component_redis = {\n    jobs = {}\n  }\n") && pass
+				pass = assert.NotContains(t, string(workerLocals), "tr_component_postgres") && pass
+
+				remoteState, err := os.ReadFile("./testdata/.terrarium/worker/tr_gen_layers.tf")
+				pass = assert.NoError(t, err) && pass
+				pass = assert.Contains(t, string(remoteState), "    path = \"../shared/terraform.tfstate\"\n") && pass
+				pass = assert.Contains(t, string(remoteState), "  tr_component_postgres_host = data.terraform_remote_state.tr_layer_shared.outputs.tr_component_postgres_host\n") && pass
+				pass = assert.Contains(t, string(remoteState), "  value = local.tr_component_postgres_host\n") && pass
+				return pass
+			},
+		},
+		{
+			Name: "Success (split apps with a private dependency of a shared component)",
+			Args: []string{"-p", "./testdata/split/platform", "-a", "./testdata/split/apps/api.yaml", "-a", "./testdata/split/apps/worker.yaml", "-a", "./testdata/split/apps/billing.yaml", "-o", "./testdata/.terrarium", "--split-apps"},
+			ValidateOutput: func(ctx context.Context, t *testing.T, cmdOpts clitesting.CmdOpts, output []byte) bool {
+				sharedLocals, err := os.ReadFile("./testdata/.terrarium/shared/tr_gen_locals.tf")
+				pass := assert.NoError(t, err)
+				pass = assert.Contains(t, string(sharedLocals), "  tr_component_postgres = {\n    db = {") && pass
+				pass = assert.NotContains(t, string(sharedLocals), "ledger") && pass
+
+				// the private postgres of the billing app is provisioned in its own root module.
+				billingLocals, err := os.ReadFile("./testdata/.terrarium/billing/tr_gen_locals.tf")
+				pass = assert.NoError(t, err) && pass
+				pass = assert.Contains(t, string(billingLocals), "  tr_component_postgres = {\n    ledger = {") && pass
+				pass = assertFilesExists(t, "./testdata/.terrarium/billing", []string{"main.tf"}, []string{"tr_gen_layers.tf"}) && pass
+				return pass
+			},
+		},
+		{
+			Name: "Check lock (split apps)",
+			Args: []string{"-p", "./testdata/split/platform", "-a", "./testdata/split/apps/api.yaml", "-a", "./testdata/split/apps/worker.yaml", "-o", "./testdata/.terrarium", "--split-apps", "--check-lock"},
+			PreExecute: func(ctx context.Context, t *testing.T, cmd *cobra.Command, cmdOpts clitesting.CmdOpts) {
+				runGenerate(t, "-p", "./testdata/split/platform", "-a", "./testdata/split/apps/api.yaml", "-a", "./testdata/split/apps/worker.yaml", "-o", "./testdata/.terrarium", "--split-apps")
+			},
+			ValidateOutput: clitesting.ValidateOutputMatch("Lock file is up to date for root 'shared' at: testdata/.terrarium/shared\nLock file is up to date for root 'api' at: testdata/.terrarium/api\nLock file is up to date for root 'worker' at: testdata/.terrarium/worker\n"),
+		},
+		{
+			Name:     "Split apps with layers",
+			Args:     []string{"--layers", "./testdata/layers/layers.yaml", "--split-apps"},
+			WantErr:  true,
+			ExpError: "if any flags in the group [layers split-apps] are set none of the others can be",
+		},
+		{
+			Name: "Success (layers)",
+			Args: []string{"--layers", "./testdata/layers/layers.yaml", "-o", "./testdata/.terrarium"},
+			ValidateOutput: func(ctx context.Context, t *testing.T, cmdOpts clitesting.CmdOpts, output []byte) bool {
+				pass := assert.Equal(t, "Successfully pulled 3 of 3 terraform blocks for layer 'base' at: testdata/.terrarium/base\nSuccessfully pulled 3 of 3 terraform blocks for layer 'service' at: testdata/.terrarium/service\n", string(output))
+				pass = assertFilesExists(t,
+					"./testdata/.terrarium/base",
+					[]string{"main.tf", "tr_gen_locals.tf", "app_base.env.mustache"},
+					[]string{"tr_gen_layers.tf"},
+				) && pass
+				pass = assertFilesExists(t,
+					"./testdata/.terrarium/service",
+					[]string{"main.tf", "tr_gen_locals.tf", "tr_gen_layers.tf", "app_service.env.mustache"},
+					nil,
+				) && pass
+
+				remoteState, err := os.ReadFile("./testdata/.terrarium/service/tr_gen_layers.tf")
+				pass = assert.NoError(t, err) && pass
+				pass = assert.Contains(t, string(remoteState), `data "terraform_remote_state" "tr_layer_base"`) && pass
+				pass = assert.Contains(t, string(remoteState), `path = "../base/terraform.tfstate"`) && pass
+				// the service platform refers to the network provisioned by the base layer, which its app does not use.
+				pass = assert.Contains(t, string(remoteState), "tr_component_network_id = data.terraform_remote_state.tr_layer_base.outputs.tr_component_network_id") && pass
+				pass = assert.Contains(t, string(remoteState), "value = local.tr_component_network_id") && pass
+
+				serviceMain, err := os.ReadFile("./testdata/.terrarium/service/main.tf")
+				pass = assert.NoError(t, err) && pass
+				pass = assert.Contains(t, string(serviceMain), `network_id     = local.tr_component_network_id["core"]`) && pass
+
+				serviceLocals, err := os.ReadFile("./testdata/.terrarium/service/tr_gen_locals.tf")
+				pass = assert.NoError(t, err) && pass
+				pass = assert.NotContains(t, string(serviceLocals), "tr_component_network") && pass
+
+				envFile, err := os.ReadFile("./testdata/.terrarium/service/app_service.env.mustache")
+				pass = assert.NoError(t, err) && pass
+				pass = assert.Equal(t, "# Generated by terrarium DEV from the platform ../../layers/platforms/service.\n# The inputs are recorded in terrarium.lock.json.\n\nSERVICE_DB_HOST=\"{{ tr_component_postgres_host.value.db }}\"\n", string(envFile)) && pass
+				return pass
+			},
+		},
+		{
+			Name:     "Invalid layers manifest",
+			Args:     []string{"--layers", "./testdata/layers/missing.yaml", "-o", "./testdata/.terrarium"},
+			WantErr:  true,
+			ExpError: "failed to read the layers manifest: ./testdata/layers/missing.yaml",
+		},
+		{
+			Name:     "Layers with platform dir",
+			Args:     []string{"--layers", "./testdata/layers/layers.yaml", "-p", "../../../../examples/platform/"},
+			WantErr:  true,
+			ExpError: "if any flags in the group [layers platform-dir] are set none of the others can be",
+		},
 	})
 }
 
+// runGenerate runs the generate command with the given args, e.g. to set up the output directory for a test case.
+func runGenerate(t *testing.T, args ...string) {
+	t.Helper()
+	c := NewCmd()
+	c.SetArgs(args)
+	c.SetOut(io.Discard)
+	require.NoError(t, c.Execute())
+}
+
 func assertFilesExists(t *testing.T, dir string, shouldExist, shouldNotExist []string) bool {
 	t.Helper()
 
@@ -0,0 +1,264 @@
+// Copyright (c) Ollion
+// SPDX-License-Identifier: Apache-2.0
+
+package generate
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/cldcvr/terrarium/src/cli/internal/constants"
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/rotisserie/eris"
+)
+
+var (
+	ErrChangesPending = eris.New("generated code has pending changes")
+)
+
+// dryRun calls gen to generate the code in a scratch directory holding a copy of the files generated in the
+// output directory, and prints a unified diff of each changed file against the output directory.
+// It returns ErrChangesPending when the output directory is not up to date.
+func dryRun(out io.Writer, gen func(outDir string) error) error {
+	outDir := filepath.Clean(flagOutDir)
+
+	// the scratch dir is created next to the output dir so that
+	// the relative module source paths are rewritten the same way.
+	parentDir := filepath.Dir(outDir)
+	err := os.MkdirAll(parentDir, constants.ReadWriteExecutePermissions)
+	if err != nil {
+		return eris.Wrapf(err, "failed to create directory for %s", parentDir)
+	}
+
+	scratchDir, err := os.MkdirTemp(parentDir, ".tr_dry_run_*")
+	if err != nil {
+		return eris.Wrap(err, "failed to create dry run directory")
+	}
+	defer os.RemoveAll(scratchDir)
+
+	generated, err := copyGenerated(outDir, scratchDir)
+	if err != nil {
+		return err
+	}
+
+	if err := gen(scratchDir); err != nil {
+		return err
+	}
+
+	diffs, err := diffDirs(outDir, scratchDir, generated)
+	if err != nil {
+		return err
+	}
+
+	for _, d := range diffs {
+		fmt.Fprint(out, d)
+	}
+
+	if len(diffs) > 0 {
+		return eris.Wrapf(ErrChangesPending, "%d file(s) in %s", len(diffs), flagOutDir)
+	}
+
+	fmt.Fprintf(out, "No changes. Generated code is up to date at: %s\n", flagOutDir)
+	return nil
+}
+
+// copyGenerated copies the output of the previous runs in srcDir to destDir, i.e. each generation manifest
+// found in srcDir along with the files it lists and the lock file next to it. Anything else in srcDir, such as
+// the '.terraform' directory, is not copied. It returns the copied files relative to srcDir.
+// A missing srcDir is treated as empty.
+func copyGenerated(srcDir, destDir string) (copied []string, err error) {
+	manifestDirs, err := findManifestDirs(srcDir)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{}
+	for _, relDir := range manifestDirs {
+		prev, err := readGenManifest(filepath.Join(srcDir, relDir))
+		if err != nil {
+			return nil, err
+		}
+
+		files = append(files, filepath.Join(relDir, manifestFileName), filepath.Join(relDir, lockFileName))
+		for relFile := range prev.Files {
+			files = append(files, filepath.Join(relDir, relFile))
+		}
+	}
+
+	for _, relFile := range files {
+		srcFile := filepath.Join(srcDir, relFile)
+		if _, err := os.Stat(srcFile); os.IsNotExist(err) {
+			continue
+		}
+
+		destFile := filepath.Join(destDir, relFile)
+		if err := os.MkdirAll(filepath.Dir(destFile), constants.ReadWriteExecutePermissions); err != nil {
+			return nil, eris.Wrapf(err, "failed to create directory for %s", destFile)
+		}
+
+		if err := copyFile(srcFile, destFile); err != nil {
+			return nil, err
+		}
+		copied = append(copied, relFile)
+	}
+
+	sort.Strings(copied)
+	return copied, nil
+}
+
+// findManifestDirs returns the directories containing a generation manifest in the dir relative to the dir.
+// The hidden directories, such as the '.terraform' directory, are skipped.
+func findManifestDirs(dir string) (dirs []string, err error) {
+	err = filepath.WalkDir(dir, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && filePath == dir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+
+		if d.IsDir() && filePath != dir && strings.HasPrefix(d.Name(), ".") {
+			return filepath.SkipDir
+		}
+
+		if !d.Type().IsRegular() || d.Name() != manifestFileName {
+			return nil
+		}
+
+		relDir, err := filepath.Rel(dir, filepath.Dir(filePath))
+		if err != nil {
+			return err
+		}
+
+		dirs = append(dirs, relDir)
+		return nil
+	})
+	if err != nil {
+		return nil, eris.Wrapf(err, "failed to find generation manifests in: %s", dir)
+	}
+
+	return dirs, nil
+}
+
+// diffDirs returns a unified diff for each file that differs between the two directories, comparing the given
+// files of the old directory and all files of the new directory.
+// The generation manifests are skipped since they only record the generated content.
+func diffDirs(oldDir, newDir string, oldFiles []string) (diffs []string, err error) {
+	newFiles, err := listFiles(newDir)
+	if err != nil {
+		return nil, err
+	}
+
+	allFiles := map[string]struct{}{}
+	for _, f := range append(oldFiles, newFiles...) {
+		if filepath.Base(f) != manifestFileName {
+			allFiles[f] = struct{}{}
+		}
+	}
+
+	sortedFiles := make([]string, 0, len(allFiles))
+	for f := range allFiles {
+		sortedFiles = append(sortedFiles, f)
+	}
+	sort.Strings(sortedFiles)
+
+	for _, relFile := range sortedFiles {
+		d, err := diffFile(oldDir, newDir, relFile)
+		if err != nil {
+			return nil, err
+		}
+		if d != "" {
+			diffs = append(diffs, d)
+		}
+	}
+
+	return diffs, nil
+}
+
+func diffFile(oldDir, newDir, relFile string) (string, error) {
+	oldContent, err := readFileIfExists(filepath.Join(oldDir, relFile))
+	if err != nil {
+		return "", err
+	}
+
+	newContent, err := readFileIfExists(filepath.Join(newDir, relFile))
+	if err != nil {
+		return "", err
+	}
+
+	if oldContent == newContent {
+		return "", nil
+	}
+
+	d, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        splitLines(oldContent),
+		B:        splitLines(newContent),
+		FromFile: "a/" + filepath.ToSlash(relFile),
+		ToFile:   "b/" + filepath.ToSlash(relFile),
+		Context:  3,
+	})
+	if err != nil {
+		return "", eris.Wrapf(err, "failed to compute the diff for file: %s", relFile)
+	}
+
+	return d, nil
+}
+
+// splitLines splits the content in lines keeping the line endings.
+// unlike difflib.SplitLines, it does not add an extra empty line at the end.
+func splitLines(content string) []string {
+	if content == "" {
+		return nil
+	}
+
+	lines := strings.SplitAfter(content, "\n")
+	if lines[len(lines)-1] == "" {
+		return lines[:len(lines)-1]
+	}
+
+	lines[len(lines)-1] += "\n"
+	return lines
+}
+
+func readFileIfExists(filePath string) (string, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil && !os.IsNotExist(err) {
+		return "", eris.Wrapf(err, "failed to read file: %s", filePath)
+	}
+
+	return string(content), nil
+}
+
+// listFiles returns the path of all regular files in the dir relative to the dir.
+func listFiles(dir string) (files []string, err error) {
+	err = filepath.WalkDir(dir, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && filePath == dir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		relFile, err := filepath.Rel(dir, filePath)
+		if err != nil {
+			return err
+		}
+
+		files = append(files, relFile)
+		return nil
+	})
+	if err != nil {
+		return nil, eris.Wrapf(err, "failed to list files in: %s", dir)
+	}
+
+	return files, nil
+}
@@ -0,0 +1,109 @@
+// Copyright (c) Ollion
+// SPDX-License-Identifier: Apache-2.0
+
+package generate
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_diffDirs(t *testing.T) {
+	writeFiles := func(t *testing.T, files map[string]string) string {
+		dir := t.TempDir()
+		for name, content := range files {
+			require.NoError(t, os.WriteFile(path.Join(dir, name), []byte(content), 0644))
+		}
+		return dir
+	}
+
+	tests := []struct {
+		name     string
+		oldFiles map[string]string
+		newFiles map[string]string
+		want     []string
+	}{
+		{
+			name:     "no changes",
+			oldFiles: map[string]string{"main.tf": "a\nb\n", manifestFileName: "{}"},
+			newFiles: map[string]string{"main.tf": "a\nb\n", manifestFileName: `{"files": {}}`},
+			want:     nil,
+		},
+		{
+			name:     "changed, added and removed files",
+			oldFiles: map[string]string{"main.tf": "a\nb\n", "old.tf": "x\n", "not_generated.tf": "z\n"},
+			newFiles: map[string]string{"main.tf": "a\nc\n", "new.tf": "y\n"},
+			want: []string{
+				"--- a/main.tf\n+++ b/main.tf\n@@ -1,2 +1,2 @@\n a\n-b\n+c\n",
+				"--- a/new.tf\n+++ b/new.tf\n@@ -0,0 +1 @@\n+y\n",
+				"--- a/old.tf\n+++ b/old.tf\n@@ -1 +0,0 @@\n-x\n",
+			},
+		},
+		{
+			name:     "missing old dir",
+			newFiles: map[string]string{"main.tf": "a\n"},
+			want:     []string{"--- a/main.tf\n+++ b/main.tf\n@@ -0,0 +1 @@\n+a\n"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldDir := path.Join(t.TempDir(), "missing")
+			if tt.oldFiles != nil {
+				oldDir = writeFiles(t, tt.oldFiles)
+			}
+
+			// the files that are not generated are not compared.
+			generated := []string{}
+			for name := range tt.oldFiles {
+				if name != "not_generated.tf" {
+					generated = append(generated, name)
+				}
+			}
+
+			got, err := diffDirs(oldDir, writeFiles(t, tt.newFiles), generated)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_copyGenerated(t *testing.T) {
+	srcDir := t.TempDir()
+	writeFiles(t, srcDir, map[string]string{
+		manifestFileName:                      `{"files": {"main.tf": {"blocks": ["module.db"]}, "app.env.mustache": {}}}`,
+		lockFileName:                          "{}",
+		"main.tf":                             "module \"db\" {}\n",
+		"app.env.mustache":                    "A=1\n",
+		"not_generated.tf":                    "locals {}\n",
+		".terraform/modules/modules.json":     "{}",
+		".terraform/" + manifestFileName:      `{"files": {"x.tf": {}}}`,
+		"prod/" + manifestFileName:            `{"files": {"main.tf": {}, "missing.tf": {}}}`,
+		"prod/main.tf":                        "module \"db\" {}\n",
+		"prod/.terraform/providers/aws.exe":   "",
+		"prod/.terraform/" + manifestFileName: "{}",
+	})
+
+	destDir := t.TempDir()
+	copied, err := copyGenerated(srcDir, destDir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"app.env.mustache",
+		"main.tf",
+		"prod/main.tf",
+		"prod/" + manifestFileName,
+		lockFileName,
+		manifestFileName,
+	}, copied)
+
+	got, err := listFiles(destDir)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, copied, got)
+
+	copied, err = copyGenerated(path.Join(t.TempDir(), "missing"), destDir)
+	assert.NoError(t, err)
+	assert.Empty(t, copied)
+}
@@ -0,0 +1,279 @@
+// Copyright (c) Ollion
+// SPDX-License-Identifier: Apache-2.0
+
+package generate
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cldcvr/terrarium/src/cli/internal/constants"
+	"github.com/cldcvr/terrarium/src/pkg/metadata/platform"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/rotisserie/eris"
+	"github.com/zclconf/go-cty/cty"
+)
+
+const (
+	hclBlockLocals            = "locals"
+	hclBlockTerraform         = "terraform"
+	hclBlockRequiredProviders = "required_providers"
+	hclAttrModuleSource       = "source"
+	hclAttrTo                 = "to"
+)
+
+// hclBlockTypes maps the top-level terraform block types to the platform block types they declare.
+var hclBlockTypes = map[string]platform.BlockType{
+	"module":   platform.BlockType_ModuleCall,
+	"resource": platform.BlockType_Resource,
+	"data":     platform.BlockType_Data,
+	"variable": platform.BlockType_Variable,
+	"output":   platform.BlockType_Output,
+	"provider": platform.BlockType_Provider,
+	"moved":    platform.BlockType_Moved,
+	"import":   platform.BlockType_Import,
+	"check":    platform.BlockType_Check,
+}
+
+// getHCLBlockID returns the platform block ID declared by the given top-level block.
+func getHCLBlockID(b *hclwrite.Block) (platform.BlockID, bool) {
+	bt, ok := hclBlockTypes[b.Type()]
+	if ok && (bt == platform.BlockType_Moved || bt == platform.BlockType_Import) {
+		// identified by the address the block refers to, since the block has no labels.
+		to := b.Body().GetAttribute(hclAttrTo)
+		if to == nil {
+			return "", false
+		}
+		return platform.NewMetaBlockID(bt, string(to.Expr().BuildTokens(nil).Bytes())), true
+	}
+
+	return getBlockIDFromLabels(b.Type(), b.Labels())
+}
+
+// getBlockIDFromLabels returns the platform block ID of a top-level block identified by its type and labels.
+func getBlockIDFromLabels(blockType string, labels []string) (platform.BlockID, bool) {
+	bt, ok := hclBlockTypes[blockType]
+	if !ok || len(labels) == 0 {
+		return "", false
+	}
+
+	switch bt {
+	case platform.BlockType_Resource, platform.BlockType_Data:
+		if len(labels) < 2 {
+			return "", false
+		}
+
+		key := labels[0] + "." + labels[1]
+		if bt == platform.BlockType_Data {
+			key = "data." + key
+		}
+		return platform.NewBlockID(bt, key), true
+	}
+
+	return platform.NewBlockID(bt, labels[0]), true
+}
+
+// filterBlocks removes the top-level blocks, locals, required providers and terraform settings from the body for which keep returns false.
+// Content that can not be identified as a platform block (e.g. a backend configuration) is kept only when keepUnknown is set.
+// It returns the IDs of the platform blocks left in the body.
+func filterBlocks(body *hclwrite.Body, keep func(platform.BlockID) bool, keepUnknown bool) (kept []platform.BlockID) {
+	for _, b := range body.Blocks() {
+		switch b.Type() {
+		case hclBlockLocals:
+			kept = append(kept, filterAttributes(b.Body(), platform.BlockType_Local, keep)...)
+		case hclBlockTerraform:
+			for _, nb := range b.Body().Blocks() {
+				if nb.Type() == hclBlockRequiredProviders {
+					kept = append(kept, filterAttributes(nb.Body(), platform.BlockType_Provider, keep)...)
+					removeIfEmpty(b.Body(), nb)
+				} else if id := platform.NewBlockID(platform.BlockType_Terraform, nb.Type()); keep(id) {
+					kept = append(kept, id) // i.e. the backend
+				} else {
+					b.Body().RemoveBlock(nb)
+				}
+			}
+
+			kept = append(kept, filterAttributes(b.Body(), platform.BlockType_Terraform, keep)...)
+		default:
+			if id, ok := getHCLBlockID(b); !ok {
+				if !keepUnknown {
+					body.RemoveBlock(b)
+				}
+			} else if keep(id) {
+				kept = append(kept, id)
+			} else {
+				body.RemoveBlock(b)
+			}
+			continue
+		}
+
+		removeIfEmpty(body, b)
+	}
+
+	return kept
+}
+
+// filterAttributes removes the attributes from the body for which keep returns false.
+// Each attribute is identified as a platform block of the given type, e.g. a local value or a required provider.
+func filterAttributes(body *hclwrite.Body, bt platform.BlockType, keep func(platform.BlockID) bool) (kept []platform.BlockID) {
+	for name := range body.Attributes() {
+		id := platform.NewBlockID(bt, name)
+		if keep(id) {
+			kept = append(kept, id)
+		} else {
+			body.RemoveAttribute(name)
+		}
+	}
+
+	return kept
+}
+
+func removeIfEmpty(parent *hclwrite.Body, b *hclwrite.Block) {
+	if len(b.Body().Attributes()) == 0 && len(b.Body().Blocks()) == 0 {
+		parent.RemoveBlock(b)
+	}
+}
+
+// extractBlocks reads the platform file and returns a new file with only the pulled blocks.
+// Leading comments are kept with each block and relative module sources are updated to work from the destDir.
+func extractBlocks(srcFile, destDir string, pulled map[platform.BlockID]struct{}) (*hclwrite.File, []platform.BlockID, error) {
+	f, err := parseHCLFile(srcFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	kept := filterBlocks(f.Body(), func(id platform.BlockID) bool {
+		_, ok := pulled[id]
+		return ok
+	}, false)
+
+	srcDir := filepath.Dir(srcFile)
+	out := hclwrite.NewEmptyFile()
+	for _, b := range f.Body().Blocks() {
+		if bt, ok := hclBlockTypes[b.Type()]; ok && bt == platform.BlockType_ModuleCall {
+			if err := updateModuleSource(b, srcDir, destDir); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		appendBlock(out.Body(), f.Body(), b)
+	}
+
+	return out, kept, nil
+}
+
+// mergeUnownedBlocks appends the blocks from the existing destination file that are not owned by generate.
+func mergeUnownedBlocks(out *hclwrite.File, destFile string, owned func(platform.BlockID) bool) error {
+	if _, err := os.Stat(destFile); os.IsNotExist(err) {
+		return nil
+	}
+
+	existing, err := parseHCLFile(destFile)
+	if err != nil {
+		return err
+	}
+
+	filterBlocks(existing.Body(), func(id platform.BlockID) bool { return !owned(id) }, true)
+	for _, b := range existing.Body().Blocks() {
+		appendBlock(out.Body(), existing.Body(), b)
+	}
+
+	return nil
+}
+
+// removeOwnedBlocks removes the blocks owned by generate from the destination file,
+// and deletes the file if nothing else is left in it.
+func removeOwnedBlocks(destFile string, owned func(platform.BlockID) bool) (isRemoved bool, err error) {
+	out := hclwrite.NewEmptyFile()
+	if err := mergeUnownedBlocks(out, destFile, owned); err != nil {
+		return false, err
+	}
+
+	if len(out.Body().Blocks()) == 0 {
+		if err := os.Remove(destFile); err != nil && !os.IsNotExist(err) {
+			return false, err
+		}
+		return true, nil
+	}
+
+	return false, writeHCLFile(destFile, out)
+}
+
+// appendBlock moves the block from the src body to the end of the dest body, separated by an empty line.
+func appendBlock(dest, src *hclwrite.Body, b *hclwrite.Block) {
+	if len(dest.Blocks()) > 0 {
+		dest.AppendNewline()
+	}
+
+	src.RemoveBlock(b)
+	dest.AppendBlock(b)
+}
+
+// updateModuleSource changes the relative module source path in the block to be relative to the destDir.
+func updateModuleSource(b *hclwrite.Block, srcDir, destDir string) error {
+	attr := b.Body().GetAttribute(hclAttrModuleSource)
+	if attr == nil {
+		return nil
+	}
+
+	expr, diags := hclsyntax.ParseExpression(attr.Expr().BuildTokens(nil).Bytes(), "", hcl.InitialPos)
+	if diags.HasErrors() {
+		return eris.Wrapf(diags, "invalid module source in module '%s'", strings.Join(b.Labels(), "."))
+	}
+
+	val, diags := expr.Value(nil)
+	if diags.HasErrors() || val.Type() != cty.String || val.IsNull() {
+		return nil // not a literal source path
+	}
+
+	src := val.AsString()
+	if newSrc := moduleSourceRelPath(src, srcDir, destDir); newSrc != src {
+		b.Body().SetAttributeValue(hclAttrModuleSource, cty.StringVal(newSrc))
+	}
+
+	return nil
+}
+
+// moduleSourceRelPath returns the module source relative to the destDir if it is a local path relative to the srcDir.
+func moduleSourceRelPath(source, srcDir, destDir string) string {
+	if !strings.HasPrefix(source, "./") && !strings.HasPrefix(source, "../") {
+		return source
+	}
+
+	newSource, err := changeBasePath(source, srcDir, destDir)
+	if err != nil {
+		return source
+	}
+
+	if newSource != ".." && !strings.HasPrefix(newSource, "../") {
+		newSource = "./" + newSource // local paths must start with ./ or ../
+	}
+
+	return filepath.ToSlash(newSource)
+}
+
+func parseHCLFile(filePath string) (*hclwrite.File, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, eris.Wrapf(err, "failed to read file: %s", filePath)
+	}
+
+	f, diags := hclwrite.ParseConfig(content, filePath, hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, eris.Wrapf(diags, "failed to parse file: %s", filePath)
+	}
+
+	return f, nil
+}
+
+func writeHCLFile(filePath string, f *hclwrite.File) error {
+	err := os.WriteFile(filePath, hclwrite.Format(f.Bytes()), constants.ReadWritePermissions)
+	if err != nil {
+		return eris.Wrapf(err, "failed to write file: %s", filePath)
+	}
+
+	return nil
+}
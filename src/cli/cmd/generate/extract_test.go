@@ -0,0 +1,182 @@
+// Copyright (c) Ollion
+// SPDX-License-Identifier: Apache-2.0
+
+package generate
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/cldcvr/terrarium/src/pkg/metadata/platform"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testPlatformFile = `terraform {
+  required_version = ">= 1.0"
+  required_providers {
+    aws    = { source = "hashicorp/aws" }
+    random = { source = "hashicorp/random" }
+  }
+}
+
+# The database
+# @title: Database
+module "tr_component_db" {
+  source = "./modules/db"
+  vpc    = module.vpc.id
+}
+
+module "vpc" { source = "../modules/vpc" }
+module "unused" { source = "./modules/unused" }
+
+locals {
+  # zones to use
+  azs = ["a", "b"]
+  unused = true
+}
+`
+
+func Test_extractBlocks(t *testing.T) {
+	srcDir := path.Join(t.TempDir(), "platform")
+	require.NoError(t, os.Mkdir(srcDir, 0755))
+	srcFile := path.Join(srcDir, "main.tf")
+	require.NoError(t, os.WriteFile(srcFile, []byte(testPlatformFile), 0644))
+
+	pulled := map[platform.BlockID]struct{}{
+		"module.tr_component_db": {},
+		"module.vpc":             {},
+		"local.azs":              {},
+		"provider.aws":           {},
+	}
+
+	out, kept, err := extractBlocks(srcFile, path.Join(srcDir, "..", "out"), pulled)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []platform.BlockID{"module.tr_component_db", "module.vpc", "local.azs", "provider.aws"}, kept)
+	assert.Equal(t, `terraform {
+  required_providers {
+    aws = { source = "hashicorp/aws" }
+  }
+}
+
+# The database
+# @title: Database
+module "tr_component_db" {
+  source = "../platform/modules/db"
+  vpc    = module.vpc.id
+}
+
+module "vpc" { source = "../modules/vpc" }
+
+locals {
+  # zones to use
+  azs = ["a", "b"]
+}
+`, string(hclwrite.Format(out.Bytes())))
+}
+
+func Test_extractBlocks_metaBlocks(t *testing.T) {
+	srcDir := t.TempDir()
+	srcFile := path.Join(srcDir, "main.tf")
+	require.NoError(t, os.WriteFile(srcFile, []byte(`terraform {
+  required_version = ">= 1.5"
+  backend "s3" {}
+}
+
+moved {
+  from = aws_iam_role.old
+  to   = aws_iam_role.db
+}
+
+moved {
+  from = aws_s3_bucket.old
+  to   = aws_s3_bucket.unused
+}
+
+import {
+  to = aws_iam_role.db
+  id = "db-role"
+}
+
+check "db_role" {
+  assert {
+    condition     = aws_iam_role.db.name != ""
+    error_message = "db role name is empty"
+  }
+}
+`), 0644))
+
+	pulled := map[platform.BlockID]struct{}{
+		"terraform.backend":        {},
+		"moved.aws_iam_role.db":    {},
+		"import.aws_iam_role.db":   {},
+		"check.db_role":            {},
+		"resource.aws_iam_role.db": {},
+	}
+
+	out, kept, err := extractBlocks(srcFile, srcDir, pulled)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []platform.BlockID{"terraform.backend", "moved.aws_iam_role.db", "import.aws_iam_role.db", "check.db_role"}, kept)
+	assert.Equal(t, `terraform {
+  backend "s3" {}
+}
+
+moved {
+  from = aws_iam_role.old
+  to   = aws_iam_role.db
+}
+
+import {
+  to = aws_iam_role.db
+  id = "db-role"
+}
+
+check "db_role" {
+  assert {
+    condition     = aws_iam_role.db.name != ""
+    error_message = "db role name is empty"
+  }
+}
+`, string(hclwrite.Format(out.Bytes())))
+}
+
+func Test_mergeUnownedBlocks(t *testing.T) {
+	destFile := mustCreateFile(t, []byte(`module "vpc" { source = "./old" }
+
+# added by hand
+resource "null_resource" "custom" {}
+
+locals {
+  azs    = []
+  custom = 1
+}
+`))
+	owned := func(id platform.BlockID) bool {
+		return id == "module.vpc" || id == "local.azs"
+	}
+
+	out := hclwrite.NewEmptyFile()
+	out.Body().AppendNewBlock("module", []string{"vpc"})
+	require.NoError(t, mergeUnownedBlocks(out, destFile, owned))
+	assert.Equal(t, `module "vpc" {
+}
+
+# added by hand
+resource "null_resource" "custom" {}
+
+locals {
+  custom = 1
+}
+`, string(hclwrite.Format(out.Bytes())))
+
+	isRemoved, err := removeOwnedBlocks(destFile, owned)
+	require.NoError(t, err)
+	assert.False(t, isRemoved)
+
+	isRemoved, err = removeOwnedBlocks(destFile, func(id platform.BlockID) bool { return true })
+	require.NoError(t, err)
+	assert.True(t, isRemoved)
+	assert.NoFileExists(t, destFile)
+}
@@ -4,17 +4,16 @@
 package generate
 
 import (
-	"bufio"
 	"fmt"
 	"io"
 	"os"
 	"path"
 	"path/filepath"
-	"regexp"
 	"strings"
 
 	"github.com/charmbracelet/log"
 	"github.com/cldcvr/terraform-config-inspect/tfconfig"
+	"github.com/cldcvr/terrarium/src/cli/internal/constants"
 	"github.com/cldcvr/terrarium/src/pkg/metadata/app"
 	"github.com/cldcvr/terrarium/src/pkg/metadata/platform"
 	tfwriter "github.com/cldcvr/terrarium/src/pkg/tf/writer"
@@ -22,10 +21,12 @@ import (
 )
 
 const (
-	localsFileName = "tr_gen_locals.tf"
-)
+	localsFileName  = "tr_gen_locals.tf"
+	baseFilePattern = "tr_base*.tf"
 
-var srcRelativePath = regexp.MustCompile(`\s*\bsource\b\s*=\s*\"((.\/|..\/)+.*)\"`)
+	profileBackendFileSuffix   = ".tfbackend"
+	profileBackendDestFileName = "tr_gen_profile.tfbackend"
+)
 
 func blocksToPull(g platform.Graph, components ...string) []platform.BlockID {
 	blockIDs := []platform.BlockID{}
@@ -36,50 +37,68 @@ func blocksToPull(g platform.Graph, components ...string) []platform.BlockID {
 
 	return blockIDs
 }
-func writeTF(g platform.Graph, destDir string, apps app.Apps, tfModule *tfconfig.Module, profileName string) (blockCount int, err error) {
+
+// writeTF writes the terraform code for the given apps to the destDir and records each written file in the manifest.
+// Blocks recorded in the manifest of the previous run (prev) are replaced by the ones pulled in this run.
+func writeTF(g platform.Graph, destDir string, apps app.Apps, tfModule *tfconfig.Module, profileName string, manifest, prev *genManifest) (blockCount int, err error) {
 	appDeps := apps.GetUniqueDependencyTypes()
 	blocks := blocksToPull(g, appDeps...)
 
 	log.Info("found dependencies", "dependencies", appDeps)
 
-	locals, fileIndex, count, err := processBlocks(g, blocks, tfModule)
+	locals, pulled, count, err := processBlocks(g, blocks, tfModule)
 	if err != nil {
 		return count, err
 	}
 
-	err = copyFilesFromIndex(tfModule.Path, destDir, fileIndex)
+	if profileName != "" {
+		if err := pullProfileVariables(tfModule, profileName, pulled); err != nil {
+			return count, err
+		}
+	}
+
+	err = writeBlocks(tfModule.Path, destDir, pulled, manifest, prev)
 	if err != nil {
 		return count, err
 	}
 
 	if len(locals) > 0 {
-		err = writeLocalsToFile(locals, destDir, apps)
+		err = writeLocalsToFile(locals, destDir, apps, tfModule)
 		if err != nil {
 			return count, err
 		}
+		manifest.addFile(localsFileName, "")
 	}
 
 	// copy base files to the generated code.
-	err = copyBaseFiles(tfModule.Path, destDir)
+	err = copyBaseFiles(tfModule.Path, destDir, manifest)
 	if err != nil {
 		return count, err
 	}
 
-	if profileName == "" {
-		return count, nil
-	}
-
-	if err := copyProfileConfigurationFile(tfModule.Path, profileName, destDir); err != nil {
-		return count, err
+	if profileName != "" {
+		if err := copyProfileConfigurationFile(tfModule.Path, profileName, destDir, manifest); err != nil {
+			return count, err
+		}
 	}
 
 	return count, nil
 }
 
-func processBlocks(g platform.Graph, blocks []platform.BlockID, tfModule *tfconfig.Module) (locals map[string]interface{}, fileIndex map[string][][2]int, blockCount int, err error) {
+func processBlocks(g platform.Graph, blocks []platform.BlockID, tfModule *tfconfig.Module) (locals map[string]interface{}, pulled map[platform.BlockID]struct{}, blockCount int, err error) {
 	locals = map[string]interface{}{}
-	fileIndex = map[string][][2]int{}
+	pulled = map[platform.BlockID]struct{}{}
+
+	// blocks requiring each other in a cycle can not be planned by terraform, hence reported before generating them.
+	sub, err := g.Subgraph(blocks)
+	if err != nil {
+		return locals, pulled, 0, err
+	}
+	if _, err := sub.Index().TopologicalSort(); err != nil {
+		return locals, pulled, 0, eris.Wrap(err, "the platform blocks required by the app dependencies can not be ordered")
+	}
 
+	mb := platform.NewModuleBlocks(tfModule)
 	err = g.Walk(blocks, func(bID platform.BlockID) error {
 		compType, compName := bID.ParseComponent()
 		if compName != "" && compType == platform.BlockType_Local {
@@ -92,60 +111,94 @@ func processBlocks(g platform.Graph, blocks []platform.BlockID, tfModule *tfconf
 			return nil
 		}
 
-		b, found := bID.GetBlock(tfModule)
-		if !found || b.GetPos().Filename == "" {
+		if bt, name := bID.Parse(); bt == platform.BlockType_Local && isTaxonSwitch(name) {
+			// skip taxon switches as they are generated from the app dependencies
+			locals[name] = false
+
+			blockCount++
 			return nil
 		}
 
-		relFilePath, err := getRelativeFilePath(tfModule.Path, b.GetPos().Filename)
-		if err != nil {
-			return eris.Wrap(err, "invalid updated module file path")
+		b, found := mb.GetBlock(bID)
+		if !found || b.GetPos().Filename == "" {
+			return nil
 		}
 
-		updateFileIndex(fileIndex, relFilePath, b)
+		pulled[bID] = struct{}{}
 
 		blockCount++
 		return nil
 	})
 
-	return locals, fileIndex, blockCount, err
-}
-
-func getRelativeFilePath(basePath, targetPath string) (string, error) {
-	return filepath.Rel(basePath, targetPath)
+	return locals, pulled, blockCount, err
 }
 
-func updateFileIndex(fileIndex map[string][][2]int, srcFilePath string, b platform.ParsedBlock) {
-	srcFileName := filepath.Base(srcFilePath)
-	// 1. skip the gen_locals file since that file is supposed to be generated entirely
-	// 2. skip anything that is not a tf file
-	if srcFileName == localsFileName || filepath.Ext(srcFileName) != ".tf" {
-		return
+// writeBlocks copies the pulled blocks from each platform file to the file with the same name in the destDir.
+// Blocks in the existing destination files that were not generated by the previous run are retained.
+func writeBlocks(srcDir, destDir string, pulled map[platform.BlockID]struct{}, manifest, prev *genManifest) error {
+	files, err := filepath.Glob(filepath.Join(srcDir, "*.tf"))
+	if err != nil {
+		return err
 	}
 
-	pos := b.GetPos()
-	fileIndex[srcFilePath] = append(fileIndex[srcFilePath], [2]int{pos.Line, pos.EndLine})
+	for _, srcFile := range files {
+		relFile := filepath.Base(srcFile)
+		// 1. skip the gen_locals file since that file is supposed to be generated entirely
+		// 2. skip the base files since those are copied as it is
+		if relFile == localsFileName || isBaseFile(relFile) {
+			continue
+		}
 
-	if parentPosGetter, ok := b.(platform.BlockParentPosGetter); ok && parentPosGetter.GetParentPos() != nil {
-		pPos := parentPosGetter.GetParentPos()
-		fileIndex[srcFilePath] = append(fileIndex[srcFilePath], [2]int{pPos.Line, pPos.Line})       // add first line
-		fileIndex[srcFilePath] = append(fileIndex[srcFilePath], [2]int{pPos.EndLine, pPos.EndLine}) // add last line
-	}
-}
+		out, blockIDs, err := extractBlocks(srcFile, destDir, pulled)
+		if err != nil {
+			return eris.Wrapf(err, "failed to extract blocks from file: %s", relFile)
+		}
+
+		if len(blockIDs) == 0 {
+			continue
+		}
 
-func copyFilesFromIndex(srcDir, destDir string, fileIndex map[string][][2]int) error {
-	for file, ranges := range fileIndex {
-		err := copyLines(srcDir, destDir, file, ranges...)
+		manifest.addBlocks(relFile, srcFile, blockIDs...)
+
+		destFile := filepath.Join(destDir, relFile)
+		err = mergeUnownedBlocks(out, destFile, func(id platform.BlockID) bool {
+			return manifest.owns(relFile, id) || prev.owns(relFile, id)
+		})
+		if err != nil {
+			return eris.Wrapf(err, "failed to merge blocks into existing file: %s", relFile)
+		}
+
+		err = writeHCLFile(destFile, out)
 		if err != nil {
-			return eris.Wrapf(err, "failed to copy lines from file: %s", file)
+			return err
 		}
 	}
+
 	return nil
 }
 
-func writeLocalsToFile(locals map[string]interface{}, destDir string, apps app.Apps) error {
+func writeLocalsToFile(locals map[string]interface{}, destDir string, apps app.Apps, tfModule *tfconfig.Module) error {
+	taxons, err := getUsedTaxons(apps, locals)
+	if err != nil {
+		return err
+	}
+
 	for k := range locals {
+		if isTaxonSwitch(k) {
+			_, enabled := taxons[strings.TrimSuffix(strings.TrimPrefix(k, platform.TaxonPrefix), platform.SwitchSuffix)]
+			locals[k] = enabled
+			continue
+		}
+
 		compName := strings.TrimPrefix(k, platform.ComponentPrefix)
+		if switchCompName, ok := strings.CutSuffix(compName, platform.SwitchSuffix); ok {
+			// the switch of a component is set based on whether the component is used by any app
+			if _, isComponent := tfModule.Locals[platform.ComponentPrefix+switchCompName]; isComponent {
+				locals[k] = len(apps.GetDependenciesByType(switchCompName)) > 0
+				continue
+			}
+		}
+
 		locals[k] = apps.GetDependenciesByType(compName).GetInputs()
 	}
 
@@ -158,111 +211,121 @@ func writeLocalsToFile(locals map[string]interface{}, destDir string, apps app.A
 	return tfwriter.WriteLocals(locals, localsFile)
 }
 
-func readAllLines(filename string) ([]string, error) {
-	file, err := os.Open(filename)
-	if err != nil {
-		return nil, err
-	}
-	defer file.Close()
+// changeBasePath changes the base of a given relative path from oldBase to newBase.
+func changeBasePath(pathRelToOldBase, oldBase, newBase string) (string, error) {
+	// Construct the full old path
+	fullOldPath := filepath.Join(oldBase, pathRelToOldBase)
 
-	var lines []string
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		lines = append(lines, scanner.Text())
+	// Find the relative path from newBase to fullOldPath
+	relPathToNewBase, err := filepath.Rel(newBase, fullOldPath)
+	if err != nil {
+		return "", err
 	}
 
-	return lines, scanner.Err()
+	return filepath.Clean(relPathToNewBase), nil
 }
 
-func isInRange(lineNum int, ranges ...[2]int) bool {
-	for _, r := range ranges {
-		if lineNum >= r[0] && lineNum <= r[1] {
-			return true
-		}
+func copyProfileConfigurationFile(moduleDirPath string, profileName string, codeDestDirPath string, manifest *genManifest) error {
+	sourcePath, err := getProfileVariableInputSourceFile(moduleDirPath, profileName)
+	if err != nil {
+		return eris.Wrapf(err, "could not retrieve configuration file for platform profile '%s'", profileName)
 	}
 
-	return false
-}
+	destPath, err := getProfileVariableInputDestFile(codeDestDirPath)
+	if err != nil {
+		return eris.Wrapf(err, "could not retrieve configuration target path for platform profile '%s'", profileName)
+	}
 
-// copyLines copies specific line ranges from the source file to the destination file.
-// The rest of the lines in the destination file remain unchanged.
-// If the destination file has fewer lines than expected, it inserts empty lines.
-func copyLines(srcDir, destDir, relFile string, ranges ...[2]int) error {
-	srcFile, destFile := filepath.Join(srcDir, relFile), filepath.Join(destDir, relFile)
+	if err := writeProfileConfigurationFile(moduleDirPath, profileName, sourcePath, destPath); err != nil {
+		return err
+	}
+	manifest.addFile(filepath.Base(destPath), sourcePath)
 
-	srcLines, err := readAllLines(srcFile)
-	if err != nil {
-		return eris.Wrapf(err, "failed to read lines to copy. file: %s", srcFile)
+	// the backend configuration for the profile is optional, and is passed to 'terraform init -backend-config'.
+	backendSourcePath := path.Join(moduleDirPath, profileName+profileBackendFileSuffix)
+	if _, err := os.Stat(backendSourcePath); os.IsNotExist(err) {
+		return nil
 	}
 
-	destLines, err := readAllLines(destFile)
-	if err != nil && !os.IsNotExist(err) {
-		return eris.Wrapf(err, "failed to read destination file: %s", srcFile)
+	backendDestPath := path.Join(codeDestDirPath, profileBackendDestFileName)
+	if err := copyFile(backendSourcePath, backendDestPath); err != nil {
+		return eris.Wrapf(err, "could not copy platform '%s' profile backend configuration", profileName)
 	}
+	manifest.addFile(profileBackendDestFileName, backendSourcePath)
 
-	output, err := os.Create(destFile)
+	return nil
+}
+
+// writeProfileConfigurationFile copies the profile configuration to the destination. When the profile extends other
+// profiles through the '@extends' doc tag, the configurations are merged, the profile overriding the variables it inherits.
+func writeProfileConfigurationFile(moduleDirPath, profileName, sourcePath, destPath string) error {
+	files, err := getProfileLineageFiles(moduleDirPath, profileName)
 	if err != nil {
-		return eris.Wrapf(err, "failed to create destination file: %s", destFile)
+		return err
 	}
-	defer output.Close()
 
-	writer := bufio.NewWriter(output)
-
-	for i, line := range srcLines {
-		lineNum := i + 1
-		if isInRange(lineNum, ranges...) {
-			line = updateRelPath(line, srcDir, destDir)
-			writer.WriteString(line + "\n")
-		} else if lineNum <= len(destLines) {
-			writer.WriteString(destLines[lineNum-1] + "\n")
-		} else {
-			writer.WriteString("\n")
+	if len(files) < 2 {
+		if err := copyFile(sourcePath, destPath); err != nil {
+			return eris.Wrapf(err, "could not copy platform '%s' profile configuration", profileName)
 		}
+		return nil
 	}
 
-	return writer.Flush()
-}
-
-func updateRelPath(line, srcDir, destDir string) string {
-	matches := srcRelativePath.FindStringSubmatch(line)
-	if len(matches) == 0 {
-		return line
+	content, err := platform.MergeProfileFiles(files...)
+	if err != nil {
+		return eris.Wrapf(err, "could not merge platform '%s' profile configuration with the profiles it extends", profileName)
 	}
 
-	relPath := matches[1]
-	newRelPath, _ := changeBasePath(relPath, srcDir, destDir)
+	if err := os.WriteFile(destPath, content, constants.ReadWritePermissions); err != nil {
+		return eris.Wrapf(err, "could not write platform '%s' profile configuration", profileName)
+	}
 
-	newLine := strings.Replace(line, relPath, newRelPath, 1)
-	return newLine
+	return nil
 }
 
-// changeBasePath changes the base of a given relative path from oldBase to newBase.
-func changeBasePath(pathRelToOldBase, oldBase, newBase string) (string, error) {
-	// Construct the full old path
-	fullOldPath := filepath.Join(oldBase, pathRelToOldBase)
+// getProfileLineageFiles returns the configuration files of the profile and of the profiles it extends,
+// starting with the most distant ancestor.
+func getProfileLineageFiles(moduleDirPath, profileName string) ([]string, error) {
+	profiles := platform.Profiles{}
+	profiles.Parse(&tfconfig.Module{Path: moduleDirPath})
 
-	// Find the relative path from newBase to fullOldPath
-	relPathToNewBase, err := filepath.Rel(newBase, fullOldPath)
+	lineage, err := profiles.GetLineage(profileName)
 	if err != nil {
-		return "", err
+		return nil, eris.Wrapf(err, "could not resolve the profiles extended by platform profile '%s'", profileName)
 	}
 
-	return filepath.Clean(relPathToNewBase), nil
+	files := make([]string, 0, len(lineage))
+	for _, id := range lineage {
+		files = append(files, platform.ProfileFilePath(moduleDirPath, id))
+	}
+
+	return files, nil
 }
 
-func copyProfileConfigurationFile(moduleDirPath string, profileName string, codeDestDirPath string) error {
-	sourcePath, err := getProfileVariableInputSourceFile(moduleDirPath, profileName)
-	if err != nil {
+// pullProfileVariables adds the platform variables set by the profile configuration to the pulled blocks,
+// so that the generated code declares them even when no pulled block refers to them.
+func pullProfileVariables(tfModule *tfconfig.Module, profileName string, pulled map[platform.BlockID]struct{}) error {
+	if _, err := getProfileVariableInputSourceFile(tfModule.Path, profileName); err != nil {
 		return eris.Wrapf(err, "could not retrieve configuration file for platform profile '%s'", profileName)
 	}
 
-	destPath, err := getProfileVariableInputDestFile(codeDestDirPath)
+	files, err := getProfileLineageFiles(tfModule.Path, profileName)
 	if err != nil {
-		return eris.Wrapf(err, "could not retrieve configuration target path for platform profile '%s'", profileName)
+		return err
 	}
 
-	if copyFile(sourcePath, destPath); err != nil {
-		return eris.Wrapf(err, "could not copy platform '%s' profile configuration", profileName)
+	// only the variable names are read, the values are left for terraform to validate.
+	for _, file := range files {
+		attrs, err := readProfileAttributes(file)
+		if err != nil {
+			return eris.Wrapf(err, "could not read platform '%s' profile configuration", profileName)
+		}
+
+		for name := range attrs {
+			if _, ok := tfModule.Variables[name]; ok {
+				pulled[platform.NewBlockID(platform.BlockType_Variable, name)] = struct{}{}
+			}
+		}
 	}
 
 	return nil
@@ -270,8 +333,8 @@ func copyProfileConfigurationFile(moduleDirPath string, profileName string, code
 
 // copyBaseFiles copy all files from src dir to dest dir that
 // matches the following file name pattern: 'tr_base*.tf'
-func copyBaseFiles(srcDirPath, destDirPath string) error {
-	pattern := filepath.Join(srcDirPath, "tr_base*.tf")
+func copyBaseFiles(srcDirPath, destDirPath string, manifest *genManifest) error {
+	pattern := filepath.Join(srcDirPath, baseFilePattern)
 	files, err := filepath.Glob(pattern)
 	if err != nil {
 		return err
@@ -283,11 +346,17 @@ func copyBaseFiles(srcDirPath, destDirPath string) error {
 		if err := copyFile(file, destFile); err != nil {
 			return eris.Wrapf(err, "failed to copy the file '%s'", filename)
 		}
+		manifest.addFile(filename, file)
 	}
 
 	return nil
 }
 
+func isBaseFile(fileName string) bool {
+	matched, _ := filepath.Match(baseFilePattern, fileName)
+	return matched
+}
+
 func getProfileVariableInputSourceFile(moduleDirPath string, profileName string) (filePath string, err error) {
 	profileSourceFileName := fmt.Sprintf("%s.%s", profileName, "tfvars")
 	profileSourceFilePath := path.Join(moduleDirPath, profileSourceFileName)
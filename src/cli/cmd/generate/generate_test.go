@@ -8,7 +8,10 @@ import (
 	"path"
 	"testing"
 
+	"github.com/cldcvr/terraform-config-inspect/tfconfig"
+	"github.com/cldcvr/terrarium/src/pkg/metadata/platform"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func Test_copyFile(t *testing.T) {
@@ -107,9 +110,9 @@ func assertFileSame(t *testing.T, filePathExpected string, filePathActual string
 	assert.Equal(t, expected, actual)
 }
 
-func Test_updateRelPath(t *testing.T) {
+func Test_moduleSourceRelPath(t *testing.T) {
 	type args struct {
-		line    string
+		source  string
 		srcDir  string
 		destDir string
 	}
@@ -120,41 +123,124 @@ func Test_updateRelPath(t *testing.T) {
 	}{
 		{
 			args: args{
-				line:    `  source = "../../modules"`,
+				source:  `../../modules`,
 				srcDir:  `/a/b/c`,
 				destDir: `/a/x/y`,
 			},
-			want: `  source = "../../modules"`,
+			want: `../../modules`,
 		},
 		{
 			args: args{
-				line:    `  source = "./../modules"`,
+				source:  `./../modules`,
 				srcDir:  `a/b/c`,
 				destDir: `a/x/y`,
 			},
-			want: `  source = "../../b/modules"`,
+			want: `../../b/modules`,
 		},
 		{
 			args: args{
-				line:    `  source = "./modules"`,
+				source:  `./modules`,
 				srcDir:  `a/b/c`,
 				destDir: `a/x/y`,
 			},
-			want: `  source = "../../b/c/modules"`,
+			want: `../../b/c/modules`,
 		},
 		{
 			args: args{
-				line:    `  source = "git.com/modules"`,
+				source:  `../modules`,
+				srcDir:  `a/b/c`,
+				destDir: `a/b`,
+			},
+			want: `./modules`,
+		},
+		{
+			args: args{
+				source:  `git.com/modules`,
 				srcDir:  `a/b/c`,
 				destDir: `a/x/y`,
 			},
-			want: `  source = "git.com/modules"`,
+			want: `git.com/modules`,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := updateRelPath(tt.args.line, tt.args.srcDir, tt.args.destDir)
+			got := moduleSourceRelPath(tt.args.source, tt.args.srcDir, tt.args.destDir)
 			assert.Equal(t, tt.want, got)
 		})
 	}
 }
+
+func Test_processBlocks_cycle(t *testing.T) {
+	g := platform.Graph{
+		{ID: "local.db_prefix", Requirements: []platform.BlockID{"local.db_suffix"}},
+		{ID: "local.db_suffix", Requirements: []platform.BlockID{"local.db_prefix"}},
+		{ID: "module.tr_component_postgres", Requirements: []platform.BlockID{"local.db_prefix"}},
+		{ID: "module.tr_component_redis"},
+	}
+
+	_, _, _, err := processBlocks(g, blocksToPull(g, "redis"), &tfconfig.Module{})
+	assert.NoError(t, err, "the cycle is not in the blocks of the dependencies")
+
+	_, _, _, err = processBlocks(g, blocksToPull(g, "postgres"), &tfconfig.Module{})
+	assert.ErrorIs(t, err, platform.ErrGraphCycle)
+	assert.ErrorContains(t, err, "local.db_prefix -> local.db_suffix -> local.db_prefix")
+}
+
+func Test_copyProfileConfigurationFile(t *testing.T) {
+	platformDir := t.TempDir()
+	files := map[string]string{
+		"base.tfvars": "# @title: Base Configuration\nregion = \"us-east-1\"\ninstance_class = \"db.t3.micro\"\n",
+		"prod.tfvars": "# @title: Production\n# @extends: base\ninstance_class = \"db.r5.large\"\nmulti_az = true\n",
+		"loop.tfvars": "# @extends: loop\n",
+	}
+	for name, content := range files {
+		assert.NoError(t, os.WriteFile(path.Join(platformDir, name), []byte(content), 0o644))
+	}
+
+	tests := []struct {
+		profile string
+		want    string
+		wantErr string
+	}{
+		{profile: "base", want: files["base.tfvars"]},
+		{profile: "prod", want: "# @title: Production\n# @extends: base\n\nregion         = \"us-east-1\"\ninstance_class = \"db.r5.large\"\nmulti_az       = true\n"},
+		{profile: "loop", wantErr: "profiles extend each other in a cycle: loop -> loop"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.profile, func(t *testing.T) {
+			destDir := t.TempDir()
+			err := copyProfileConfigurationFile(platformDir, tt.profile, destDir, newGenManifest())
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			content, err := os.ReadFile(path.Join(destDir, "tr_gen_profile.auto.tfvars"))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, string(content))
+		})
+	}
+}
+
+func Test_pullProfileVariables(t *testing.T) {
+	platformDir := t.TempDir()
+	files := map[string]string{
+		"main.tf":     "variable \"region\" {}\nvariable \"env_type\" {}\nvariable \"unused\" {}\n",
+		"base.tfvars": "region = \"us-east-1\"\n",
+		"dev.tfvars":  "# @extends: base\nenv_type = dev\nundeclared = 1\n",
+	}
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(path.Join(platformDir, name), []byte(content), 0o644))
+	}
+	tfModule, _ := tfconfig.LoadModule(platformDir, &tfconfig.ResolvedModulesSchema{})
+
+	// the values are not evaluated, hence 'dev' referring to an unknown variable is not an error.
+	pulled := map[platform.BlockID]struct{}{}
+	require.NoError(t, pullProfileVariables(tfModule, "dev", pulled))
+	assert.Equal(t, map[platform.BlockID]struct{}{"var.region": {}, "var.env_type": {}}, pulled)
+
+	err := pullProfileVariables(tfModule, "prod", pulled)
+	assert.ErrorContains(t, err, "could not retrieve configuration file for platform profile 'prod'")
+}
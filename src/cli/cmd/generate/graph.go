@@ -0,0 +1,53 @@
+// Copyright (c) Ollion
+// SPDX-License-Identifier: Apache-2.0
+
+package generate
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cldcvr/terrarium/src/cli/internal/constants"
+	"github.com/cldcvr/terrarium/src/pkg/metadata/app"
+	"github.com/cldcvr/terrarium/src/pkg/metadata/platform"
+	"github.com/rotisserie/eris"
+)
+
+// graphFileFormats maps the extension of the --graph-out file to the graph format.
+var graphFileFormats = map[string]platform.GraphFormat{
+	".dot":     platform.GraphFormatDOT,
+	".gv":      platform.GraphFormatDOT,
+	".mmd":     platform.GraphFormatMermaid,
+	".mermaid": platform.GraphFormatMermaid,
+}
+
+// writeGraph writes the graph of the platform blocks pulled for the apps to the filePath.
+// The graph format is determined by the file extension.
+func writeGraph(filePath string, pm *platform.PlatformMetadata, apps app.Apps) error {
+	ext := strings.ToLower(filepath.Ext(filePath))
+	format, ok := graphFileFormats[ext]
+	if !ok {
+		return eris.Errorf("unsupported graph file extension '%s'. must be one of: .dot, .gv (graphviz) or .mmd, .mermaid (mermaid)", ext)
+	}
+
+	g, err := pm.Graph.Subgraph(blocksToPull(pm.Graph, apps.GetUniqueDependencyTypes()...))
+	if err != nil {
+		return err
+	}
+
+	var sb strings.Builder
+	if err := g.Export(&sb, format); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(filePath), constants.ReadWriteExecutePermissions); err != nil {
+		return eris.Wrapf(err, "failed to create directory for %s", filePath)
+	}
+
+	if err := os.WriteFile(filePath, []byte(sb.String()), constants.ReadWritePermissions); err != nil {
+		return eris.Wrapf(err, "failed to write file: %s", filePath)
+	}
+
+	return nil
+}
@@ -0,0 +1,510 @@
+// Copyright (c) Ollion
+// SPDX-License-Identifier: Apache-2.0
+
+package generate
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+
+	"github.com/cldcvr/terraform-config-inspect/tfconfig"
+	"github.com/cldcvr/terrarium/src/pkg/metadata/app"
+	"github.com/cldcvr/terrarium/src/pkg/metadata/platform"
+	"github.com/cldcvr/terrarium/src/pkg/metadata/utils"
+	pkgutils "github.com/cldcvr/terrarium/src/pkg/utils"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/rotisserie/eris"
+	"github.com/zclconf/go-cty/cty"
+	"golang.org/x/exp/slices"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	layersFileName        = "tr_gen_layers.tf"
+	layerBackendFileName  = "tr_gen_layer.tfbackend"
+	layerRemoteStatePref  = "tr_layer_"
+	defaultLayerBackend   = "local"
+	defaultLayerStateFile = "terraform.tfstate"
+)
+
+var layerIDRegex = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_-]*$`)
+
+// layersConfig is the manifest of a layered platform composition. The layers are generated in the
+// given order, and each layer can use the components provisioned by the layers before it.
+type layersConfig struct {
+	// Backend is used to read the state of a layer in the layers after it. Defaults to the local backend.
+	Backend layerBackend `yaml:"backend"`
+	Layers  []*layer     `yaml:"layers"`
+
+	unit string // what a layer is called in the messages, defaults to "layer"
+}
+
+type layerBackend struct {
+	Type   string                 `yaml:"type"`
+	Config map[string]interface{} `yaml:"config"`
+}
+
+type layer struct {
+	ID       string   `yaml:"id"`
+	Platform string   `yaml:"platform"` // platform source, relative to the manifest file
+	Apps     []string `yaml:"apps"`     // app manifest paths, relative to the manifest file
+	Profile  string   `yaml:"profile"`
+
+	// BackendConfig is merged with the common backend config to read and write the state of this layer.
+	BackendConfig map[string]interface{} `yaml:"backend_config"`
+
+	src      *platformSource
+	module   *tfconfig.Module
+	pm       *platform.PlatformMetadata
+	appPaths []string
+	apps     app.Apps // apps declared in this layer
+
+	provisionApps app.Apps                       // app dependencies provisioned in this layer
+	usedLayers    map[*layer]map[string]struct{} // components used from each of the previous layers, by the apps or the platform code
+}
+
+// loadLayers reads the layers manifest, and loads the platform and apps of each layer.
+func loadLayers(filePath string) (*layersConfig, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, eris.Wrapf(err, "failed to read the layers manifest: %s", filePath)
+	}
+
+	lc := &layersConfig{}
+	if err := yaml.Unmarshal(content, lc); err != nil {
+		return nil, eris.Wrapf(err, "failed to parse the layers manifest: %s", filePath)
+	}
+
+	if len(lc.Layers) == 0 {
+		return nil, eris.Errorf("no layers defined in the layers manifest: %s", filePath)
+	}
+
+	if lc.Backend.Type == "" {
+		lc.Backend.Type = defaultLayerBackend
+	}
+
+	baseDir := filepath.Dir(filePath)
+	seenIDs := map[string]struct{}{}
+	for _, l := range lc.Layers {
+		if !layerIDRegex.MatchString(l.ID) {
+			return nil, eris.Errorf("invalid layer id '%s'. it must start with a letter and can only contain letters, digits, '_' and '-'", l.ID)
+		}
+
+		if _, ok := seenIDs[l.ID]; ok {
+			return nil, eris.Errorf("layer id '%s' is used more than once", l.ID)
+		}
+		seenIDs[l.ID] = struct{}{}
+
+		if err := l.load(baseDir); err != nil {
+			return nil, eris.Wrapf(err, "failed to load layer '%s'", l.ID)
+		}
+	}
+
+	return lc, nil
+}
+
+func (l *layer) load(baseDir string) error {
+	src, err := parsePlatformSource(l.Platform)
+	if err != nil {
+		return err
+	}
+
+	if src.Type != platformSourceGit && !filepath.IsAbs(src.Path) {
+		src.Path = filepath.Join(baseDir, src.Path)
+	}
+
+	platformDir, err := resolvePlatformDir(src)
+	if err != nil {
+		return err
+	}
+
+	l.src = src
+	l.module, _ = tfconfig.LoadModule(platformDir, &tfconfig.ResolvedModulesSchema{})
+	existingYaml, _ := os.ReadFile(path.Join(platformDir, defaultYAMLFileName))
+	l.pm, _ = platform.NewPlatformMetadata(l.module, existingYaml)
+
+	appPaths := make([]string, len(l.Apps))
+	for i, p := range l.Apps {
+		appPaths[i] = p
+		if !filepath.IsAbs(p) {
+			appPaths[i] = filepath.Join(baseDir, p)
+		}
+	}
+
+	l.appPaths = appPaths
+	if len(appPaths) > 0 {
+		l.apps, err = fetchApps(appPaths)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// resolve assigns each app dependency to the nearest layer, starting from the app's own layer
+// and going backwards, that implements the dependency component.
+func (lc *layersConfig) resolve(ignoreUnimplemented bool) error {
+	for i, l := range lc.Layers {
+		l.usedLayers = map[*layer]map[string]struct{}{}
+		if err := utils.ResolveImplementations(lc.envMetadata(i), l.apps); err != nil {
+			return eris.Wrapf(err, "invalid dependencies for layer '%s'", l.ID)
+		}
+	}
+
+	for i, l := range lc.Layers {
+		for _, a := range l.apps {
+			for _, dep := range a.GetDependencies() {
+				provider := lc.getProvider(i, dep.ComponentID())
+				if provider == nil {
+					if ignoreUnimplemented {
+						continue
+					}
+					return eris.Wrapf(utils.ErrComponentNotImplemented, "'%s.%s' of app '%s' in layer '%s'", dep.ID, dep.Use, a.ID, l.ID)
+				}
+
+				if provider != l {
+					l.addUsedComponent(provider, dep.ComponentID())
+				}
+
+				if !dep.NoProvision {
+					if err := provider.addDependency(a, dep); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+
+	// the platform code of a layer refers to the outputs of the components provisioned by the layers before it
+	// through the locals named after the outputs, which are read from the state of those layers.
+	for i, l := range lc.Layers {
+		for _, ref := range getUnresolvedReferences(l.module) {
+			if ref.Type() != "local" {
+				continue
+			}
+
+			if provider, compID := lc.getOutputProvider(i, ref.Name()); provider != nil {
+				l.addUsedComponent(provider, compID)
+			}
+		}
+	}
+
+	for _, l := range lc.Layers {
+		if err := utils.MatchAppAndPlatform(l.pm, l.provisionApps, false); err != nil {
+			return eris.Wrapf(err, "invalid dependencies for layer '%s'", l.ID)
+		}
+	}
+
+	return nil
+}
+
+// getProvider returns the nearest layer at or before the given index that implements the component.
+func (lc *layersConfig) getProvider(index int, compID string) *layer {
+	for i := index; i >= 0; i-- {
+		if lc.Layers[i].pm.Components.GetByID(compID) != nil {
+			return lc.Layers[i]
+		}
+	}
+
+	return nil
+}
+
+// getOutputProvider returns the nearest layer before the given index that implements a component with the
+// given terraform output name, along with the component ID.
+func (lc *layersConfig) getOutputProvider(index int, outputName string) (*layer, string) {
+	for i := index - 1; i >= 0; i-- {
+		for _, c := range lc.Layers[i].pm.Components {
+			if slices.Contains(getComponentOutputNames(lc.Layers[i].pm, map[string]struct{}{c.ID: {}}), outputName) {
+				return lc.Layers[i], c.ID
+			}
+		}
+	}
+
+	return nil, ""
+}
+
+// addUsedComponent records that the layer uses the component provisioned by the given previous layer.
+func (l *layer) addUsedComponent(provider *layer, compID string) {
+	if l.usedLayers[provider] == nil {
+		l.usedLayers[provider] = map[string]struct{}{}
+	}
+	l.usedLayers[provider][compID] = struct{}{}
+}
+
+// addDependency adds the app dependency to be provisioned in this layer.
+// Dependencies declared by apps in different layers are provisioned once if they are equivalent.
+func (l *layer) addDependency(a app.App, dep app.Dependency) error {
+	for _, existing := range l.provisionApps.GetDependenciesByType(dep.ComponentID()) {
+		if existing.ID == dep.ID {
+			if !existing.IsEquivalent(dep) {
+				return eris.Errorf("dependency '%s.%s' of app '%s' is declared differently by another app provisioned in layer '%s'", dep.ID, dep.Use, a.ID, l.ID)
+			}
+			return nil
+		}
+	}
+
+	for i := range l.provisionApps {
+		if l.provisionApps[i].ID == a.ID {
+			l.provisionApps[i].Dependencies = append(l.provisionApps[i].Dependencies, dep)
+			return nil
+		}
+	}
+
+	l.provisionApps = append(l.provisionApps, app.App{ID: a.ID, EnvPrefix: a.EnvPrefix, Dependencies: app.Dependencies{dep}})
+	return nil
+}
+
+// envMetadata returns the platform metadata with the components available to the apps in the layer
+// at the given index, i.e. the components of the layer and of the layers before it.
+func (lc *layersConfig) envMetadata(index int) *platform.PlatformMetadata {
+	pm := &platform.PlatformMetadata{}
+	for i := index; i >= 0; i-- {
+		for _, c := range lc.Layers[i].pm.Components {
+			if pm.Components.GetByID(c.ID) == nil {
+				pm.Components = append(pm.Components, c)
+			}
+		}
+	}
+
+	return pm
+}
+
+func (lc *layersConfig) unitName() string {
+	if lc.unit == "" {
+		return "layer"
+	}
+	return lc.unit
+}
+
+// layerDir returns the directory the code of the layer is generated in.
+func layerDir(outDir string, l *layer) string {
+	return path.Join(outDir, l.ID)
+}
+
+// generate generates the code of each layer in its own sub-directory of the outDir.
+func (lc *layersConfig) generate(out io.Writer, outDir string) error {
+	for i, l := range lc.Layers {
+		destDir := layerDir(outDir, l)
+		lock, err := newGenLock(destDir, l.src, l.module.Path, l.Profile, l.appPaths)
+		if err != nil {
+			return err
+		}
+
+		blockCount, err := writeGenerated(destDir, lock, func(manifest, prev *genManifest) (int, error) {
+			// the remote state is written with the layer code, so that the references to it are resolved when validating the code.
+			if err := lc.writeRemoteState(outDir, l, manifest); err != nil {
+				return 0, err
+			}
+
+			blockCount, err := writeTF(l.pm.Graph, destDir, l.provisionApps, l.module, l.Profile, manifest, prev)
+			if err != nil {
+				return blockCount, eris.Wrapf(err, "failed to write terraform code to dir: %s", destDir)
+			}
+
+			if err := lc.writeBackendConfig(destDir, l, manifest); err != nil {
+				return blockCount, err
+			}
+
+			if !flagSkipEnvFile {
+				err = writeAppsEnv(destDir, lc.envMetadata(i), l.apps, flagEnvFormats, flagSecretEnvFormats, manifest)
+			}
+
+			return blockCount, err
+		})
+		if err != nil {
+			return eris.Wrapf(err, "failed to generate %s '%s'", lc.unitName(), l.ID)
+		}
+
+		fmt.Fprintf(out, "Successfully pulled %d of %d terraform blocks for %s '%s' at: %s\n", blockCount, len(l.pm.Graph), lc.unitName(), l.ID, destDir)
+	}
+
+	return nil
+}
+
+// writeRemoteState writes a terraform_remote_state data source for each of the previous layers used by the layer,
+// along with a local and an output for each of the used component outputs. The locals are named after the outputs,
+// i.e. 'local.tr_component_<component>_<output>', for the platform code of the layer to refer to them, and
+// the outputs make the layer outputs include all the values required to render the app env files.
+func (lc *layersConfig) writeRemoteState(outDir string, l *layer, manifest *genManifest) error {
+	if len(l.usedLayers) == 0 {
+		return nil
+	}
+
+	f := hclwrite.NewEmptyFile()
+	for _, provider := range lc.Layers {
+		comps, ok := l.usedLayers[provider]
+		if !ok {
+			continue
+		}
+
+		config, err := lc.backendConfig(provider, l)
+		if err != nil {
+			return err
+		}
+
+		dataName := layerRemoteStatePref + provider.ID
+		if len(f.Body().Blocks()) > 0 {
+			f.Body().AppendNewline()
+		}
+		b := f.Body().AppendNewBlock("data", []string{"terraform_remote_state", dataName})
+		b.Body().SetAttributeValue("backend", cty.StringVal(lc.Backend.Type))
+		b.Body().SetAttributeValue("config", config)
+
+		outputNames := getComponentOutputNames(provider.pm, comps)
+		if len(outputNames) == 0 {
+			continue
+		}
+
+		f.Body().AppendNewline()
+		lb := f.Body().AppendNewBlock("locals", nil)
+		for _, outputName := range outputNames {
+			lb.Body().SetAttributeTraversal(outputName, hcl.Traversal{
+				hcl.TraverseRoot{Name: "data"},
+				hcl.TraverseAttr{Name: "terraform_remote_state"},
+				hcl.TraverseAttr{Name: dataName},
+				hcl.TraverseAttr{Name: "outputs"},
+				hcl.TraverseAttr{Name: outputName},
+			})
+		}
+
+		for _, outputName := range outputNames {
+			f.Body().AppendNewline()
+			ob := f.Body().AppendNewBlock("output", []string{outputName})
+			ob.Body().SetAttributeTraversal("value", hcl.Traversal{
+				hcl.TraverseRoot{Name: "local"},
+				hcl.TraverseAttr{Name: outputName},
+			})
+		}
+	}
+
+	if err := writeHCLFile(path.Join(layerDir(outDir, l), layersFileName), f); err != nil {
+		return err
+	}
+
+	manifest.addFile(layersFileName, "")
+	return nil
+}
+
+// writeBackendConfig writes the backend configuration of the layer, to be used with 'terraform init -backend-config'.
+func (lc *layersConfig) writeBackendConfig(destDir string, l *layer, manifest *genManifest) error {
+	if len(lc.Backend.Config) == 0 && len(l.BackendConfig) == 0 {
+		return nil
+	}
+
+	config, err := lc.backendConfig(l, nil)
+	if err != nil {
+		return err
+	}
+
+	f := hclwrite.NewEmptyFile()
+	attrs := config.AsValueMap()
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		f.Body().SetAttributeValue(k, attrs[k])
+	}
+
+	if err := writeHCLFile(path.Join(destDir, layerBackendFileName), f); err != nil {
+		return err
+	}
+
+	manifest.addFile(layerBackendFileName, "")
+	return nil
+}
+
+// backendConfig returns the backend configuration to read the state of the given layer.
+// For the default local backend, the path to the state file is set relative to the reader layer.
+func (lc *layersConfig) backendConfig(l *layer, reader *layer) (cty.Value, error) {
+	config := map[string]interface{}{}
+	for k, v := range lc.Backend.Config {
+		config[k] = v
+	}
+	for k, v := range l.BackendConfig {
+		config[k] = v
+	}
+
+	if lc.Backend.Type == defaultLayerBackend && reader != nil && config["path"] == nil {
+		config["path"] = path.Join("..", l.ID, defaultLayerStateFile)
+	}
+
+	if len(config) == 0 {
+		return cty.EmptyObjectVal, nil
+	}
+
+	val, err := pkgutils.ToCtyValue(config)
+	if err != nil {
+		return cty.NilVal, eris.Wrapf(err, "invalid backend config for layer '%s'", l.ID)
+	}
+
+	return val, nil
+}
+
+// getComponentOutputNames returns the sorted names of the terraform outputs of the given components.
+func getComponentOutputNames(pm *platform.PlatformMetadata, compIDs map[string]struct{}) []string {
+	names := []string{}
+	for compID := range compIDs {
+		comp := pm.Components.GetByID(compID)
+		if comp == nil || comp.Outputs == nil {
+			continue
+		}
+
+		for outputName := range comp.Outputs.Properties {
+			names = append(names, platform.ComponentPrefix+compID+"_"+outputName)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// runLayers generates the code for the layers manifest given to the --layers flag.
+func runLayers(out io.Writer) error {
+	lc, err := loadLayers(flagLayers)
+	if err != nil {
+		return err
+	}
+
+	if err := lc.resolve(flagIgnoreUnimplemented); err != nil {
+		return err
+	}
+
+	if flagCheckLock {
+		return lc.checkLocks(out, flagOutDir)
+	}
+
+	if flagDryRun {
+		return dryRun(out, func(outDir string) error {
+			return lc.generate(io.Discard, outDir)
+		})
+	}
+
+	return lc.generate(out, flagOutDir)
+}
+
+// checkLocks checks the lock file of each layer in the outDir.
+func (lc *layersConfig) checkLocks(out io.Writer, outDir string) error {
+	for _, l := range lc.Layers {
+		destDir := layerDir(outDir, l)
+		lock, err := newGenLock(destDir, l.src, l.module.Path, l.Profile, l.appPaths)
+		if err != nil {
+			return err
+		}
+
+		if err := lock.checkLock(destDir); err != nil {
+			return eris.Wrapf(err, "%s '%s'", lc.unitName(), l.ID)
+		}
+
+		fmt.Fprintf(out, "Lock file is up to date for %s '%s' at: %s\n", lc.unitName(), l.ID, destDir)
+	}
+
+	return nil
+}
@@ -0,0 +1,303 @@
+// Copyright (c) Ollion
+// SPDX-License-Identifier: Apache-2.0
+
+package generate
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/cldcvr/terrarium/src/cli/internal/build"
+	"github.com/cldcvr/terrarium/src/cli/internal/constants"
+	"github.com/cldcvr/terrarium/src/pkg/metadata/app"
+	"github.com/rotisserie/eris"
+)
+
+const (
+	lockFileName         = "terrarium.lock.json"
+	provenanceHeaderPref = "# Generated by terrarium "
+	templateFileSuffix   = ".mustache"
+)
+
+// provenanceFileExts are the extensions of the generated files that support '#' comments.
+// The app env templates are matched by the extension before the '.mustache' suffix.
+var provenanceFileExts = map[string]struct{}{
+	".tf":        {},
+	".tfvars":    {},
+	".tfbackend": {},
+	".env":       {},
+	".yaml":      {},
+}
+
+// genLock records the inputs the code in an output directory was generated from,
+// so that the generated code can be traced back to the platform and the app manifests.
+type genLock struct {
+	CLIVersion string       `json:"cli_version"`
+	Platform   lockPlatform `json:"platform"`
+	Profile    string       `json:"profile,omitempty"`
+	Apps       []lockApp    `json:"apps"`
+}
+
+type lockPlatform struct {
+	Source       string `json:"source"`                  // platform directory relative to the output directory, or the remote source
+	CommitSHA    string `json:"commit_sha,omitempty"`    // commit the git source is resolved to
+	MetadataHash string `json:"metadata_hash,omitempty"` // sha256 of the platform terrarium.yaml file
+}
+
+type lockApp struct {
+	ID   string `json:"id"`
+	Path string `json:"path"` // app manifest relative to the output directory
+	Hash string `json:"hash"` // sha256 of the app manifest
+}
+
+// newGenLock returns the lock for the code generated in the destDir from the given platform and app manifests.
+func newGenLock(destDir string, src *platformSource, platformDir, profile string, appPaths []string) (*genLock, error) {
+	lock := &genLock{
+		CLIVersion: build.Version,
+		Platform:   lockPlatform{CommitSHA: src.CommitSHA},
+		Profile:    profile,
+		Apps:       []lockApp{},
+	}
+
+	switch src.Type {
+	case platformSourceLocal:
+		lock.Platform.Source = relPath(destDir, src.Path)
+	case platformSourceArchive:
+		lock.Platform.Source = relPath(destDir, src.Path)
+		if src.RepoDirectory != "" {
+			lock.Platform.Source += sourceSubDirSep + src.RepoDirectory
+		}
+	case platformSourceGit:
+		lock.Platform.Source = src.RepoURL
+		if src.RepoDirectory != "" {
+			lock.Platform.Source += sourceSubDirSep + src.RepoDirectory
+		}
+	}
+
+	if hash, err := fileHash(filepath.Join(platformDir, defaultYAMLFileName)); err == nil {
+		lock.Platform.MetadataHash = hash
+	}
+
+	for _, appPath := range appPaths {
+		content, err := readAppDependency(appPath)
+		if err != nil {
+			return nil, err
+		}
+
+		appObj, err := app.NewApp(content)
+		if err != nil {
+			return nil, err
+		}
+
+		sum := sha256.Sum256(content)
+		lock.Apps = append(lock.Apps, lockApp{
+			ID:   appObj.ID,
+			Path: relPath(destDir, appPath),
+			Hash: hex.EncodeToString(sum[:]),
+		})
+	}
+
+	sort.Slice(lock.Apps, func(i, j int) bool {
+		return lock.Apps[i].ID < lock.Apps[j].ID
+	})
+
+	return lock, nil
+}
+
+// relPath returns the target path relative to the base directory, or the target as is if that is not possible.
+func relPath(baseDir, target string) string {
+	absBase, err := filepath.Abs(baseDir)
+	if err != nil {
+		return target
+	}
+
+	absTarget, err := filepath.Abs(target)
+	if err != nil {
+		return target
+	}
+
+	rel, err := filepath.Rel(absBase, absTarget)
+	if err != nil {
+		return target
+	}
+
+	return filepath.ToSlash(rel)
+}
+
+func (l *genLock) write(destDir string) error {
+	content, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return eris.Wrap(err, "failed to serialize lock file")
+	}
+
+	err = os.WriteFile(filepath.Join(destDir, lockFileName), append(content, '\n'), constants.ReadWritePermissions)
+	if err != nil {
+		return eris.Wrap(err, "failed to write lock file")
+	}
+
+	return nil
+}
+
+func readGenLock(destDir string) (*genLock, error) {
+	lockFile := filepath.Join(destDir, lockFileName)
+	content, err := os.ReadFile(lockFile)
+	if err != nil {
+		return nil, eris.Wrapf(err, "failed to read lock file: %s", lockFile)
+	}
+
+	l := &genLock{}
+	if err := json.Unmarshal(content, l); err != nil {
+		return nil, eris.Wrapf(err, "failed to parse lock file: %s", lockFile)
+	}
+
+	return l, nil
+}
+
+// diff returns the differences in the inputs recorded in the existing lock and this lock.
+// A difference in the CLI version is only logged, as it does not change the inputs.
+func (l *genLock) diff(existing *genLock) []string {
+	diffs := []string{}
+	if existing.CLIVersion != l.CLIVersion {
+		log.Warn("the lock file was written by another terrarium version", "lock", existing.CLIVersion, "current", l.CLIVersion)
+	}
+
+	if existing.Platform != l.Platform {
+		diffs = append(diffs, fmt.Sprintf("platform: %s -> %s", existing.Platform, l.Platform))
+	}
+
+	if existing.Profile != l.Profile {
+		diffs = append(diffs, fmt.Sprintf("profile: '%s' -> '%s'", existing.Profile, l.Profile))
+	}
+
+	existingApps := map[string]lockApp{}
+	for _, a := range existing.Apps {
+		existingApps[a.ID] = a
+	}
+
+	for _, a := range l.Apps {
+		prev, ok := existingApps[a.ID]
+		delete(existingApps, a.ID)
+		if !ok {
+			diffs = append(diffs, fmt.Sprintf("app '%s': added", a.ID))
+		} else if !reflect.DeepEqual(prev, a) {
+			diffs = append(diffs, fmt.Sprintf("app '%s': %s -> %s", a.ID, prev, a))
+		}
+	}
+
+	removed := make([]string, 0, len(existingApps))
+	for id := range existingApps {
+		removed = append(removed, fmt.Sprintf("app '%s': removed", id))
+	}
+	sort.Strings(removed)
+
+	return append(diffs, removed...)
+}
+
+// checkLock returns an error if the inputs differ from the ones recorded in the lock file in the destDir.
+func (l *genLock) checkLock(destDir string) error {
+	existing, err := readGenLock(destDir)
+	if err != nil {
+		return err
+	}
+
+	if diffs := l.diff(existing); len(diffs) > 0 {
+		return eris.Errorf("inputs differ from the lock file '%s':\n  %s", filepath.Join(destDir, lockFileName), strings.Join(diffs, "\n  "))
+	}
+
+	return nil
+}
+
+func (p lockPlatform) String() string {
+	s := p.Source
+	if p.CommitSHA != "" {
+		s += "@" + p.CommitSHA
+	}
+	if p.MetadataHash != "" {
+		s += " (metadata " + shortHash(p.MetadataHash) + ")"
+	}
+	return s
+}
+
+func (a lockApp) String() string {
+	return fmt.Sprintf("%s (%s)", a.Path, shortHash(a.Hash))
+}
+
+func shortHash(hash string) string {
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	return hash
+}
+
+// header returns the provenance comment added at the top of each generated file.
+func (l *genLock) header() string {
+	sb := strings.Builder{}
+	sb.WriteString(provenanceHeaderPref + l.CLIVersion + " from the platform " + l.Platform.Source)
+	if l.Platform.CommitSHA != "" {
+		sb.WriteString(" at commit " + l.Platform.CommitSHA)
+	}
+	if l.Profile != "" {
+		sb.WriteString(" with the '" + l.Profile + "' profile")
+	}
+	sb.WriteString(".\n# The inputs are recorded in " + lockFileName + ".\n\n")
+	return sb.String()
+}
+
+// addProvenanceHeaders adds the provenance header to each generated file that supports comments,
+// replacing the header added by the previous run.
+func (l *genLock) addProvenanceHeaders(destDir string, manifest *genManifest) error {
+	header := l.header()
+	for relFile := range manifest.Files {
+		if _, ok := provenanceFileExts[filepath.Ext(strings.TrimSuffix(relFile, templateFileSuffix))]; !ok {
+			continue
+		}
+
+		filePath := filepath.Join(destDir, relFile)
+		content, err := os.ReadFile(filePath)
+		if err != nil {
+			return eris.Wrapf(err, "failed to read file: %s", filePath)
+		}
+
+		content = append([]byte(header), stripProvenanceHeader(content)...)
+		if err := os.WriteFile(filePath, content, constants.ReadWritePermissions); err != nil {
+			return eris.Wrapf(err, "failed to write file: %s", filePath)
+		}
+	}
+
+	return nil
+}
+
+// stripProvenanceHeader removes the provenance header, i.e. the leading comment lines
+// starting with the provenance prefix and the empty line after them, from the content.
+func stripProvenanceHeader(content []byte) []byte {
+	if !strings.HasPrefix(string(content), provenanceHeaderPref) {
+		return content
+	}
+
+	offset := 0
+	scanner := bufio.NewScanner(strings.NewReader(string(content)))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "#") {
+			if strings.TrimSpace(line) == "" {
+				offset += len(line) + 1
+			}
+			break
+		}
+		offset += len(line) + 1
+	}
+
+	if offset > len(content) {
+		return nil
+	}
+	return content[offset:]
+}
@@ -0,0 +1,89 @@
+// Copyright (c) Ollion
+// SPDX-License-Identifier: Apache-2.0
+
+package generate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_stripProvenanceHeader(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name:    "no header",
+			content: "# doc comment\nmodule \"a\" {}\n",
+			want:    "# doc comment\nmodule \"a\" {}\n",
+		},
+		{
+			name:    "header",
+			content: "# Generated by terrarium DEV from the platform ../platform.\n# The inputs are recorded in terrarium.lock.json.\n\n# doc comment\nmodule \"a\" {}\n",
+			want:    "# doc comment\nmodule \"a\" {}\n",
+		},
+		{
+			name:    "header only",
+			content: "# Generated by terrarium DEV from the platform ../platform.\n# The inputs are recorded in terrarium.lock.json.\n\n",
+			want:    "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, string(stripProvenanceHeader([]byte(tt.content))))
+		})
+	}
+}
+
+func Test_genLock_diff(t *testing.T) {
+	existing := &genLock{
+		CLIVersion: "v0.1.0",
+		Platform:   lockPlatform{Source: "https://example.com/platform.git", CommitSHA: "aaaa"},
+		Profile:    "dev",
+		Apps: []lockApp{
+			{ID: "api", Path: "../apps/api", Hash: "1111"},
+			{ID: "web", Path: "../apps/web", Hash: "2222"},
+		},
+	}
+
+	tests := []struct {
+		name string
+		lock genLock
+		want []string
+	}{
+		{
+			name: "same inputs with another cli version",
+			lock: genLock{CLIVersion: "v0.2.0", Platform: existing.Platform, Profile: "dev", Apps: existing.Apps},
+			want: []string{},
+		},
+		{
+			name: "changed inputs",
+			lock: genLock{
+				CLIVersion: "v0.1.0",
+				Platform:   lockPlatform{Source: "https://example.com/platform.git", CommitSHA: "bbbb"},
+				Profile:    "prod",
+				Apps: []lockApp{
+					{ID: "api", Path: "../apps/api", Hash: "3333"},
+					{ID: "worker", Path: "../apps/worker", Hash: "4444"},
+				},
+			},
+			want: []string{
+				"platform: https://example.com/platform.git@aaaa -> https://example.com/platform.git@bbbb",
+				"profile: 'dev' -> 'prod'",
+				"app 'api': ../apps/api (1111) -> ../apps/api (3333)",
+				"app 'worker': added",
+				"app 'web': removed",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.lock.diff(existing))
+		})
+	}
+}
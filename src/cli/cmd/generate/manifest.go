@@ -0,0 +1,153 @@
+// Copyright (c) Ollion
+// SPDX-License-Identifier: Apache-2.0
+
+package generate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/cldcvr/terrarium/src/cli/internal/constants"
+	"github.com/cldcvr/terrarium/src/pkg/metadata/platform"
+	"github.com/rotisserie/eris"
+	"golang.org/x/exp/slices"
+)
+
+const (
+	manifestFileName = "tr_gen_manifest.json"
+)
+
+// genManifest records every file written to the output directory by a generate run,
+// so that the next run can tell its own output apart from anything else in the directory.
+type genManifest struct {
+	Files map[string]*genManifestFile `json:"files"` // keyed by path relative to the output dir
+}
+
+// genManifestFile is a single generated file. Files with no blocks are owned by generate entirely.
+type genManifestFile struct {
+	SourceHash string             `json:"source_hash,omitempty"` // sha256 of the platform file the content was copied from
+	Blocks     []platform.BlockID `json:"blocks,omitempty"`      // blocks copied from the platform file
+}
+
+func newGenManifest() *genManifest {
+	return &genManifest{Files: map[string]*genManifestFile{}}
+}
+
+// readGenManifest loads the manifest from the previous run in destDir.
+// An empty manifest is returned if the directory was never generated.
+func readGenManifest(destDir string) (*genManifest, error) {
+	m := newGenManifest()
+
+	content, err := os.ReadFile(filepath.Join(destDir, manifestFileName))
+	if os.IsNotExist(err) {
+		return m, nil
+	} else if err != nil {
+		return nil, eris.Wrapf(err, "failed to read generation manifest")
+	}
+
+	if err := json.Unmarshal(content, m); err != nil {
+		return nil, eris.Wrapf(err, "failed to parse generation manifest '%s'", manifestFileName)
+	}
+
+	if m.Files == nil {
+		m.Files = map[string]*genManifestFile{}
+	}
+
+	return m, nil
+}
+
+func (m *genManifest) write(destDir string) error {
+	content, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return eris.Wrap(err, "failed to serialize generation manifest")
+	}
+
+	err = os.WriteFile(filepath.Join(destDir, manifestFileName), append(content, '\n'), constants.ReadWritePermissions)
+	if err != nil {
+		return eris.Wrap(err, "failed to write generation manifest")
+	}
+
+	return nil
+}
+
+// addFile registers a file that is generated as a whole.
+func (m *genManifest) addFile(relFile string, srcFilePath string) {
+	f := m.getOrAdd(relFile)
+	if srcFilePath != "" {
+		f.SourceHash, _ = fileHash(srcFilePath)
+	}
+}
+
+// addBlocks registers the blocks copied into the given file.
+func (m *genManifest) addBlocks(relFile string, srcFilePath string, bIDs ...platform.BlockID) {
+	f := m.getOrAdd(relFile)
+	if f.SourceHash == "" {
+		f.SourceHash, _ = fileHash(srcFilePath)
+	}
+	f.Blocks = append(f.Blocks, bIDs...)
+	sort.Slice(f.Blocks, func(i, j int) bool {
+		return f.Blocks[i] < f.Blocks[j]
+	})
+}
+
+func (m *genManifest) getOrAdd(relFile string) *genManifestFile {
+	f, ok := m.Files[relFile]
+	if !ok {
+		f = &genManifestFile{}
+		m.Files[relFile] = f
+	}
+	return f
+}
+
+// owns returns true if the given block was generated in the given file.
+func (m *genManifest) owns(relFile string, bID platform.BlockID) bool {
+	f, ok := m.Files[relFile]
+	return ok && slices.Contains(f.Blocks, bID)
+}
+
+// prune removes the output of the previous run that is not part of this run.
+// Files generated entirely are removed, and blocks that are no longer pulled are
+// removed from their file, removing the file if nothing else is left in it.
+func (m *genManifest) prune(destDir string, prev *genManifest) (removed []string, err error) {
+	for relFile, prevFile := range prev.Files {
+		if _, ok := m.Files[relFile]; ok {
+			continue
+		}
+
+		destFile := filepath.Join(destDir, relFile)
+		if len(prevFile.Blocks) > 0 {
+			isRemoved, err := removeOwnedBlocks(destFile, func(id platform.BlockID) bool {
+				return prev.owns(relFile, id)
+			})
+			if err != nil {
+				return removed, eris.Wrapf(err, "failed to remove stale blocks from file: %s", relFile)
+			}
+			if isRemoved {
+				removed = append(removed, relFile)
+			}
+			continue
+		}
+
+		if err := os.Remove(destFile); err != nil && !os.IsNotExist(err) {
+			return removed, eris.Wrapf(err, "failed to remove stale file: %s", relFile)
+		}
+		removed = append(removed, relFile)
+	}
+
+	sort.Strings(removed)
+	return removed, nil
+}
+
+func fileHash(filePath string) (string, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:]), nil
+}
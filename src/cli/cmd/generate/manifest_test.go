@@ -0,0 +1,78 @@
+// Copyright (c) Ollion
+// SPDX-License-Identifier: Apache-2.0
+
+package generate
+
+import (
+	"os"
+	"path"
+	"sort"
+	"testing"
+
+	"github.com/cldcvr/terraform-config-inspect/tfconfig"
+	"github.com/cldcvr/terrarium/src/pkg/metadata/app"
+	"github.com/cldcvr/terrarium/src/pkg/metadata/platform"
+	"github.com/cldcvr/terrarium/src/pkg/metadata/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_genManifest_regenerate(t *testing.T) {
+	destDir := t.TempDir()
+	m, _ := tfconfig.LoadModule("../../../../examples/platform", &tfconfig.ResolvedModulesSchema{})
+	pm, err := platform.NewPlatformMetadata(m, nil)
+	require.NoError(t, err)
+
+	generate := func(deps ...string) []string {
+		a := app.App{ID: "app1"}
+		for _, d := range deps {
+			a.Dependencies = append(a.Dependencies, app.Dependency{Use: d})
+		}
+		apps := app.Apps{a}
+		apps.SetDefaults()
+		require.NoError(t, utils.MatchAppAndPlatform(pm, apps, false))
+
+		prev, err := readGenManifest(destDir)
+		require.NoError(t, err)
+
+		lock, err := newGenLock(destDir, &platformSource{Type: platformSourceLocal, Path: m.Path}, m.Path, "", nil)
+		require.NoError(t, err)
+
+		// the files are written, pruned and validated the same way as by the generate command.
+		_, err = writeGenerated(destDir, lock, func(manifest, prev *genManifest) (int, error) {
+			return writeTF(pm.Graph, destDir, apps, m, "", manifest, prev)
+		})
+		require.NoError(t, err)
+
+		manifest, err := readGenManifest(destDir)
+		require.NoError(t, err)
+
+		removed := []string{}
+		for relFile := range prev.Files {
+			if _, ok := manifest.Files[relFile]; !ok {
+				removed = append(removed, relFile)
+			}
+		}
+		sort.Strings(removed)
+		return removed
+	}
+
+	removed := generate("postgres", "redis")
+	assert.Empty(t, removed)
+	assertFilesExists(t, destDir, []string{"component_postgres.tf", "component_redis.tf", "tr_gen_manifest.json"}, nil)
+
+	removed = generate("redis")
+	assert.Equal(t, []string{"component_postgres.tf", "variables.tf"}, removed)
+	assertFilesExists(t, destDir, []string{"component_redis.tf", "outputs.tf", "vpc.tf", lockFileName}, []string{"component_postgres.tf", "variables.tf"})
+
+	outputs, err := os.ReadFile(path.Join(destDir, "outputs.tf"))
+	require.NoError(t, err)
+	assert.Contains(t, string(outputs), "tr_component_redis_host")
+	assert.NotContains(t, string(outputs), "tr_component_postgres_host")
+
+	manifest, err := readGenManifest(destDir)
+	require.NoError(t, err)
+	assert.Contains(t, manifest.Files, "component_redis.tf")
+	assert.NotContains(t, manifest.Files, "component_postgres.tf")
+	assert.NotEmpty(t, manifest.Files["component_redis.tf"].SourceHash)
+}
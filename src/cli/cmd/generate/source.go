@@ -0,0 +1,356 @@
+// Copyright (c) Ollion
+// SPDX-License-Identifier: Apache-2.0
+
+package generate
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/cldcvr/terrarium/src/cli/internal/config"
+	"github.com/cldcvr/terrarium/src/cli/internal/constants"
+	"github.com/cldcvr/terrarium/src/pkg/utils"
+	"github.com/rotisserie/eris"
+)
+
+type platformSourceType int
+
+const (
+	platformSourceLocal platformSourceType = iota
+	platformSourceGit
+	platformSourceArchive
+)
+
+const (
+	gitSourcePrefix    = "git::"
+	sourceRefQueryKey  = "ref"
+	sourceSubDirSep    = "//"
+	cacheDirGit        = "git"
+	cacheDirArchive    = "archive"
+	cacheDirTempPrefix = ".tmp_"
+)
+
+var (
+	gitURLPrefixes    = []string{"https://", "http://", "ssh://", "file://", "git@"}
+	archiveSuffixes   = []string{".tar.gz", ".tgz", ".tar"}
+	gitCommitSHARegex = regexp.MustCompile(`^[0-9a-f]{40}$`)
+)
+
+// platformSource is a reference to a platform template.
+// It can be a local directory, a directory in a git repository, or a directory in a local tarball.
+//
+// Git sources are written as `[git::]<repo-url>[//<repo-dir>][?ref=<branch|tag|commit>]`,
+// and tarball sources as `<file>.tar.gz[//<dir>]`, similar to the terraform module sources.
+type platformSource struct {
+	Type          platformSourceType
+	Path          string // path to the local directory or the tarball
+	RepoURL       string
+	RepoDirectory string // directory in the repository or the tarball
+	Ref           string
+	CommitSHA     string // commit the git source is resolved to
+}
+
+// parsePlatformSource parses the platform source given to the --platform-dir flag.
+func parsePlatformSource(src string) (*platformSource, error) {
+	isGit := strings.HasPrefix(src, gitSourcePrefix)
+	src = strings.TrimPrefix(src, gitSourcePrefix)
+	for _, prefix := range gitURLPrefixes {
+		isGit = isGit || strings.HasPrefix(src, prefix)
+	}
+
+	if isGit {
+		ref := ""
+		if i := strings.LastIndex(src, "?"); i >= 0 {
+			query, err := url.ParseQuery(src[i+1:])
+			if err != nil {
+				return nil, eris.Wrapf(err, "invalid platform source: %s", src)
+			}
+			ref = query.Get(sourceRefQueryKey)
+			src = src[:i]
+		}
+
+		repoURL, repoDir := splitSourceSubDir(src)
+		return &platformSource{Type: platformSourceGit, RepoURL: repoURL, RepoDirectory: repoDir, Ref: ref}, nil
+	}
+
+	archivePath, archiveDir := splitSourceSubDir(src)
+	for _, suffix := range archiveSuffixes {
+		if strings.HasSuffix(archivePath, suffix) {
+			return &platformSource{Type: platformSourceArchive, Path: archivePath, RepoDirectory: archiveDir}, nil
+		}
+	}
+
+	return &platformSource{Type: platformSourceLocal, Path: src}, nil
+}
+
+// splitSourceSubDir splits the source at the `//` separator, ignoring the one after the URL scheme.
+func splitSourceSubDir(src string) (string, string) {
+	offset := 0
+	if i := strings.Index(src, "://"); i >= 0 {
+		offset = i + len("://")
+	}
+
+	if i := strings.Index(src[offset:], sourceSubDirSep); i >= 0 {
+		return src[:offset+i], strings.Trim(src[offset+i+len(sourceSubDirSep):], "/")
+	}
+
+	return src, ""
+}
+
+// resolvePlatformDir returns the local directory containing the platform template.
+// Git and tarball sources are extracted in the platform cache directory, so that they
+// are downloaded only once for each commit or tarball content.
+func resolvePlatformDir(src *platformSource) (string, error) {
+	var (
+		baseDir string
+		err     error
+	)
+
+	switch src.Type {
+	case platformSourceLocal:
+		return src.Path, nil
+	case platformSourceGit:
+		baseDir, err = checkoutGitSource(src)
+	case platformSourceArchive:
+		baseDir, err = extractArchiveSource(src)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	platformDir := filepath.Join(baseDir, filepath.FromSlash(src.RepoDirectory))
+	if info, err := os.Stat(platformDir); err != nil || !info.IsDir() {
+		return "", eris.Errorf("platform directory '%s' not found in: %s", src.RepoDirectory, baseDir)
+	}
+
+	return platformDir, nil
+}
+
+func checkoutGitSource(src *platformSource) (string, error) {
+	var err error
+	src.CommitSHA, err = resolveGitCommitSHA(src.RepoURL, src.Ref)
+	if err != nil {
+		return "", err
+	}
+
+	return populateCacheDir(filepath.Join(cacheDirGit, hashString(src.RepoURL), src.CommitSHA), func(dir string) error {
+		log.Info("fetching platform", "repo", src.RepoURL, "commit", src.CommitSHA)
+		if _, err := runGit(dir, "init", "-q"); err != nil {
+			return err
+		}
+
+		// fetching a single commit is not allowed by all git servers, in which case the branches and tags are fetched.
+		if _, err := runGit(dir, "fetch", "-q", "--depth", "1", src.RepoURL, src.CommitSHA); err != nil {
+			if _, err := runGit(dir, "fetch", "-q", "--tags", src.RepoURL, "+refs/heads/*:refs/remotes/origin/*"); err != nil {
+				return err
+			}
+
+			if _, err := runGit(dir, "cat-file", "-e", src.CommitSHA+"^{commit}"); err != nil {
+				return eris.Errorf("commit '%s' is not reachable from any branch or tag in repository '%s'", src.CommitSHA, src.RepoURL)
+			}
+		}
+
+		if _, err := runGit(dir, "checkout", "-q", "--detach", src.CommitSHA); err != nil {
+			return err
+		}
+
+		return os.RemoveAll(filepath.Join(dir, ".git"))
+	})
+}
+
+// resolveGitCommitSHA returns the commit SHA the ref points to in the remote repository.
+// A short ref must name exactly one branch or tag, annotated tags resolve to the commit they point to.
+func resolveGitCommitSHA(repoURL, ref string) (string, error) {
+	if gitCommitSHARegex.MatchString(ref) {
+		return ref, nil
+	}
+
+	if ref == "" {
+		ref = "HEAD"
+	}
+
+	// the patterns match the trailing path components, hence the refs are filtered by their exact name below.
+	out, err := runGit("", "ls-remote", repoURL, ref, ref+"^{}")
+	if err != nil {
+		return "", err
+	}
+
+	refSHAs := map[string]string{}
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 {
+			refSHAs[fields[1]] = fields[0]
+		}
+	}
+
+	candidates := []string{ref}
+	if ref != "HEAD" && !strings.HasPrefix(ref, "refs/") {
+		candidates = []string{"refs/heads/" + ref, "refs/tags/" + ref}
+	}
+
+	matched := []string{}
+	commitSHA := ""
+	for _, name := range candidates {
+		sha, found := refSHAs[name+"^{}"]
+		if !found {
+			sha, found = refSHAs[name]
+		}
+
+		if found {
+			matched = append(matched, name)
+			commitSHA = sha
+		}
+	}
+
+	switch len(matched) {
+	case 0:
+		return "", eris.Errorf("could not find ref '%s' in repository '%s'", ref, repoURL)
+	case 1:
+		return commitSHA, nil
+	default:
+		return "", eris.Errorf("ref '%s' is ambiguous in repository '%s', it matches: %s", ref, repoURL, strings.Join(matched, ", "))
+	}
+}
+
+func runGit(dir string, args ...string) (string, error) {
+	c := exec.Command("git", args...)
+	c.Dir = dir
+	out, err := c.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return "", eris.Errorf("git %s failed: %s", args[0], strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", eris.Wrapf(err, "failed to run git %s", args[0])
+	}
+
+	return string(out), nil
+}
+
+func extractArchiveSource(src *platformSource) (string, error) {
+	content, err := os.ReadFile(src.Path)
+	if err != nil {
+		return "", eris.Wrapf(err, "failed to read file: %s", src.Path)
+	}
+
+	sum := sha256.Sum256(content)
+	return populateCacheDir(filepath.Join(cacheDirArchive, hex.EncodeToString(sum[:])), func(dir string) error {
+		log.Info("extracting platform", "file", src.Path)
+		return extractTar(src.Path, dir)
+	})
+}
+
+// extractTar extracts the directories and regular files in the tarball to the destDir.
+func extractTar(filePath, destDir string) error {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return eris.Wrapf(err, "failed to open file: %s", filePath)
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if !strings.HasSuffix(filePath, ".tar") {
+		gzReader, err := gzip.NewReader(f)
+		if err != nil {
+			return eris.Wrapf(err, "failed to read gzip file: %s", filePath)
+		}
+		defer gzReader.Close()
+		r = gzReader
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return eris.Wrapf(err, "failed to read tar file: %s", filePath)
+		}
+
+		name := filepath.Clean(filepath.FromSlash(header.Name))
+		if filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+			return eris.Errorf("invalid file path '%s' in tar file: %s", header.Name, filePath)
+		}
+
+		target := filepath.Join(destDir, name)
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(target, constants.ReadWriteExecutePermissions)
+		case tar.TypeReg:
+			err = writeTarFile(tr, target, header.FileInfo().Mode().Perm())
+		default:
+			continue
+		}
+		if err != nil {
+			return eris.Wrapf(err, "failed to extract '%s' from tar file: %s", header.Name, filePath)
+		}
+	}
+}
+
+func writeTarFile(r io.Reader, target string, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(target), constants.ReadWriteExecutePermissions); err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, r)
+	return err
+}
+
+// populateCacheDir returns the directory at the relative path in the platform cache directory.
+// If the directory does not exist, it is created using the populate func.
+func populateCacheDir(relPath string, populate func(dir string) error) (string, error) {
+	cacheDir, err := utils.ResolveHomeAbs(config.PlatformCacheDir())
+	if err != nil {
+		return "", err
+	}
+
+	dir := filepath.Join(cacheDir, relPath)
+	if _, err := os.Stat(dir); err == nil {
+		log.Debug("using cached platform", "dir", dir)
+		return dir, nil
+	}
+
+	parentDir := filepath.Dir(dir)
+	if err := os.MkdirAll(parentDir, constants.ReadWriteExecutePermissions); err != nil {
+		return "", eris.Wrapf(err, "failed to create directory for %s", parentDir)
+	}
+
+	// populate a temporary directory first, so that an interrupted download is not cached.
+	tmpDir, err := os.MkdirTemp(parentDir, cacheDirTempPrefix)
+	if err != nil {
+		return "", eris.Wrap(err, "failed to create platform cache directory")
+	}
+	defer os.RemoveAll(tmpDir)
+
+	if err := populate(tmpDir); err != nil {
+		return "", err
+	}
+
+	if err := os.Rename(tmpDir, dir); err != nil {
+		return "", eris.Wrapf(err, "failed to create platform cache directory: %s", dir)
+	}
+
+	return dir, nil
+}
+
+func hashString(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:8])
+}
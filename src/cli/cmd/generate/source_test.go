@@ -0,0 +1,218 @@
+// Copyright (c) Ollion
+// SPDX-License-Identifier: Apache-2.0
+
+package generate
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cldcvr/terrarium/src/cli/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parsePlatformSource(t *testing.T) {
+	tests := []struct {
+		src  string
+		want platformSource
+	}{
+		{
+			src:  "../../examples/platform",
+			want: platformSource{Type: platformSourceLocal, Path: "../../examples/platform"},
+		},
+		{
+			src:  "https://github.com/cldcvr/terrarium",
+			want: platformSource{Type: platformSourceGit, RepoURL: "https://github.com/cldcvr/terrarium"},
+		},
+		{
+			src:  "https://github.com/cldcvr/terrarium//examples/platform?ref=v0.1.0",
+			want: platformSource{Type: platformSourceGit, RepoURL: "https://github.com/cldcvr/terrarium", RepoDirectory: "examples/platform", Ref: "v0.1.0"},
+		},
+		{
+			src:  "git::git@github.com:cldcvr/terrarium.git//examples/platform/?ref=main",
+			want: platformSource{Type: platformSourceGit, RepoURL: "git@github.com:cldcvr/terrarium.git", RepoDirectory: "examples/platform", Ref: "main"},
+		},
+		{
+			src:  "./platform.tar.gz//examples/platform",
+			want: platformSource{Type: platformSourceArchive, Path: "./platform.tar.gz", RepoDirectory: "examples/platform"},
+		},
+		{
+			src:  "platform.tgz",
+			want: platformSource{Type: platformSourceArchive, Path: "platform.tgz"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			got, err := parsePlatformSource(tt.src)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, *got)
+		})
+	}
+}
+
+func Test_resolvePlatformDir(t *testing.T) {
+	config.LoadDefaults()
+	t.Setenv("TR_PLATFORM_CACHE_DIR", t.TempDir())
+
+	srcFiles := map[string]string{
+		"platforms/main/main.tf":  `module "tr_component_postgres" { source = "../../modules/db" }`,
+		"modules/db/main.tf":      `variable "name" {}`,
+		"platforms/other/main.tf": ``,
+	}
+
+	t.Run("git", func(t *testing.T) {
+		repoDir := t.TempDir()
+		writeFiles(t, repoDir, srcFiles)
+		mustRunGit(t, repoDir, "init", "-q")
+		mustRunGit(t, repoDir, "add", "-A")
+		mustRunGit(t, repoDir, "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "init")
+		mustRunGit(t, repoDir, "-c", "user.name=test", "-c", "user.email=test@example.com", "tag", "-a", "v1", "-m", "v1")
+		commitSHA := strings.TrimSpace(mustRunGit(t, repoDir, "rev-parse", "HEAD"))
+
+		src, err := parsePlatformSource("git::file://" + repoDir + "//platforms/main?ref=v1")
+		require.NoError(t, err)
+		dir, err := resolvePlatformDir(src)
+		require.NoError(t, err)
+		assert.Equal(t, commitSHA, src.CommitSHA)
+		assert.FileExists(t, filepath.Join(dir, "main.tf"))
+		assert.FileExists(t, filepath.Join(dir, "../../modules/db/main.tf"))
+		assert.NoDirExists(t, filepath.Join(dir, "../../.git"))
+
+		// the commit is served from the cache once fetched
+		require.NoError(t, os.RemoveAll(repoDir))
+		src, err = parsePlatformSource("git::file://" + repoDir + "//platforms/main?ref=" + commitSHA)
+		require.NoError(t, err)
+		cachedDir, err := resolvePlatformDir(src)
+		require.NoError(t, err)
+		assert.Equal(t, dir, cachedDir)
+	})
+
+	t.Run("git ref", func(t *testing.T) {
+		repoDir := t.TempDir()
+		writeFiles(t, repoDir, srcFiles)
+		mustRunGit(t, repoDir, "init", "-q", "-b", "main")
+		mustRunGit(t, repoDir, "add", "-A")
+		mustRunGit(t, repoDir, "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "init")
+		mainSHA := strings.TrimSpace(mustRunGit(t, repoDir, "rev-parse", "HEAD"))
+		mustRunGit(t, repoDir, "checkout", "-q", "-b", "feature/main")
+		mustRunGit(t, repoDir, "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "--allow-empty", "-m", "feature")
+		mustRunGit(t, repoDir, "-c", "user.name=test", "-c", "user.email=test@example.com", "tag", "-a", "v1", "-m", "v1")
+		featureSHA := strings.TrimSpace(mustRunGit(t, repoDir, "rev-parse", "HEAD"))
+		mustRunGit(t, repoDir, "branch", "release")
+		mustRunGit(t, repoDir, "tag", "release", mainSHA)
+
+		for ref, want := range map[string]string{
+			"main":              mainSHA,
+			"feature/main":      featureSHA,
+			"v1":                featureSHA,
+			"refs/heads/main":   mainSHA,
+			"refs/tags/release": mainSHA,
+		} {
+			commitSHA, err := resolveGitCommitSHA("file://"+repoDir, ref)
+			require.NoError(t, err, ref)
+			assert.Equal(t, want, commitSHA, ref)
+		}
+
+		_, err := resolveGitCommitSHA("file://"+repoDir, "release")
+		assert.ErrorContains(t, err, "ref 'release' is ambiguous in repository 'file://"+repoDir+"', it matches: refs/heads/release, refs/tags/release")
+
+		_, err = resolveGitCommitSHA("file://"+repoDir, "feature")
+		assert.ErrorContains(t, err, "could not find ref 'feature' in repository")
+	})
+
+	t.Run("git commit not advertised by the server", func(t *testing.T) {
+		// with the protocol v0, the servers only allow fetching the commits at the tip of a branch or tag.
+		t.Setenv("GIT_CONFIG_COUNT", "1")
+		t.Setenv("GIT_CONFIG_KEY_0", "protocol.version")
+		t.Setenv("GIT_CONFIG_VALUE_0", "0")
+
+		repoDir := t.TempDir()
+		writeFiles(t, repoDir, srcFiles)
+		mustRunGit(t, repoDir, "init", "-q")
+		mustRunGit(t, repoDir, "checkout", "-q", "-b", "feature")
+		mustRunGit(t, repoDir, "add", "-A")
+		mustRunGit(t, repoDir, "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "init")
+		commitSHA := strings.TrimSpace(mustRunGit(t, repoDir, "rev-parse", "HEAD"))
+		mustRunGit(t, repoDir, "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "--allow-empty", "-m", "next")
+
+		src, err := parsePlatformSource("git::file://" + repoDir + "//platforms/main?ref=" + commitSHA)
+		require.NoError(t, err)
+		dir, err := resolvePlatformDir(src)
+		require.NoError(t, err)
+		assert.FileExists(t, filepath.Join(dir, "main.tf"))
+
+		missingSHA := strings.Repeat("0", 40)
+		src, err = parsePlatformSource("git::file://" + repoDir + "//platforms/main?ref=" + missingSHA)
+		require.NoError(t, err)
+		_, err = resolvePlatformDir(src)
+		assert.ErrorContains(t, err, "commit '"+missingSHA+"' is not reachable from any branch or tag in repository")
+	})
+
+	t.Run("archive", func(t *testing.T) {
+		archiveFile := filepath.Join(t.TempDir(), "platform.tar.gz")
+		writeTarGz(t, archiveFile, srcFiles)
+
+		src, err := parsePlatformSource(archiveFile + "//platforms/main")
+		require.NoError(t, err)
+		dir, err := resolvePlatformDir(src)
+		require.NoError(t, err)
+		assert.FileExists(t, filepath.Join(dir, "main.tf"))
+		assert.FileExists(t, filepath.Join(dir, "../../modules/db/main.tf"))
+
+		src, err = parsePlatformSource(archiveFile + "//platforms/missing")
+		require.NoError(t, err)
+		_, err = resolvePlatformDir(src)
+		assert.ErrorContains(t, err, "platform directory 'platforms/missing' not found in:")
+	})
+
+	t.Run("archive with invalid path", func(t *testing.T) {
+		archiveFile := filepath.Join(t.TempDir(), "platform.tar.gz")
+		writeTarGz(t, archiveFile, map[string]string{"../main.tf": ""})
+
+		src, err := parsePlatformSource(archiveFile)
+		require.NoError(t, err)
+		_, err = resolvePlatformDir(src)
+		assert.ErrorContains(t, err, "invalid file path '../main.tf' in tar file:")
+	})
+}
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		filePath := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(filePath), 0755))
+		require.NoError(t, os.WriteFile(filePath, []byte(content), 0644))
+	}
+}
+
+func writeTarGz(t *testing.T, filePath string, files map[string]string) {
+	t.Helper()
+	f, err := os.Create(filePath)
+	require.NoError(t, err)
+	defer f.Close()
+
+	gw := gzip.NewWriter(f)
+	defer gw.Close()
+
+	tw := tar.NewWriter(gw)
+	defer tw.Close()
+
+	for name, content := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+}
+
+func mustRunGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	out, err := runGit(dir, args...)
+	require.NoError(t, err)
+	return out
+}
@@ -0,0 +1,159 @@
+// Copyright (c) Ollion
+// SPDX-License-Identifier: Apache-2.0
+
+package generate
+
+import (
+	"io"
+
+	"github.com/cldcvr/terraform-config-inspect/tfconfig"
+	"github.com/cldcvr/terrarium/src/pkg/metadata/app"
+	"github.com/cldcvr/terrarium/src/pkg/metadata/platform"
+	"github.com/rotisserie/eris"
+)
+
+const (
+	splitSharedRootID = "shared"
+	splitUnit         = "root"
+)
+
+// splitApps returns the root modules to generate each app in, as layers of the same platform.
+// The dependencies that are provisioned by one app and used by another app with 'no_provision',
+// are provisioned in a shared root module generated before the apps.
+// Each app root module provisions the rest of its dependencies and reads the outputs
+// of the shared components from the state of the shared root module.
+func splitApps(src *platformSource, pm *platform.PlatformMetadata, m *tfconfig.Module, apps app.Apps, appPaths []string, profile string) (*layersConfig, error) {
+	sharedDeps := getSharedDependencies(apps)
+
+	shared := &layer{ID: splitSharedRootID, Profile: profile, src: src, module: m, pm: pm, usedLayers: map[*layer]map[string]struct{}{}}
+	lc := &layersConfig{Backend: layerBackend{Type: defaultLayerBackend}, unit: splitUnit}
+	if len(sharedDeps) > 0 {
+		lc.Layers = append(lc.Layers, shared)
+	}
+
+	paths := make(map[string]string, len(apps))
+	for i, a := range apps {
+		paths[a.ID] = appPaths[i]
+	}
+
+	// the apps are generated after the apps they use as dependency.
+	sorted, err := apps.SortByDependencies()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, a := range sorted {
+		if a.ID == splitSharedRootID {
+			return nil, eris.Errorf("app id '%s' is reserved for the shared root module when generating each app separately", a.ID)
+		}
+
+		l := &layer{
+			ID:         a.ID,
+			Profile:    profile,
+			src:        src,
+			module:     m,
+			pm:         pm,
+			appPaths:   []string{paths[a.ID]},
+			apps:       app.Apps{a},
+			usedLayers: map[*layer]map[string]struct{}{},
+		}
+
+		// the outputs of a component are read from a single root module, hence the other dependencies
+		// of a component the app uses from the shared root module are provisioned there as well.
+		sharedComps := map[string]struct{}{}
+		for _, dep := range a.GetDependencies() {
+			if _, ok := sharedDeps[dep.Use+"."+dep.ID]; ok {
+				sharedComps[dep.ComponentID()] = struct{}{}
+			}
+		}
+
+		addedToShared := false
+		for _, dep := range a.GetDependencies() {
+			if pm.Components.GetByID(dep.ComponentID()) == nil {
+				continue // not implemented, which is only allowed with --ignore-unimplemented
+			}
+
+			provider := l
+			if _, ok := sharedComps[dep.ComponentID()]; ok {
+				provider = shared
+				if l.usedLayers[shared] == nil {
+					l.usedLayers[shared] = map[string]struct{}{}
+				}
+				l.usedLayers[shared][dep.ComponentID()] = struct{}{}
+			}
+
+			if dep.NoProvision {
+				continue
+			}
+
+			if err := provider.addDependency(a, dep); err != nil {
+				return nil, err
+			}
+
+			if provider == shared && !addedToShared {
+				shared.appPaths = append(shared.appPaths, paths[a.ID])
+				addedToShared = true
+			}
+		}
+
+		lc.Layers = append(lc.Layers, l)
+	}
+
+	return lc, nil
+}
+
+// getSharedDependencies returns the '<use>.<id>' of the dependencies that are provisioned by one app
+// and used by another app with 'no_provision'.
+func getSharedDependencies(apps app.Apps) map[string]struct{} {
+	provisioned := map[string]string{} // '<use>.<id>' of the provisioned dependencies to the app provisioning it
+	for _, a := range apps {
+		for _, dep := range a.GetDependencies() {
+			if !dep.NoProvision {
+				provisioned[dep.Use+"."+dep.ID] = a.ID
+			}
+		}
+	}
+
+	shared := map[string]struct{}{}
+	for _, a := range apps {
+		for _, dep := range a.GetDependencies() {
+			if owner, ok := provisioned[dep.Use+"."+dep.ID]; ok && dep.NoProvision && owner != a.ID {
+				shared[dep.Use+"."+dep.ID] = struct{}{}
+			}
+		}
+	}
+
+	return shared
+}
+
+// runSplitApps generates each app in its own root module in each target directory,
+// or checks the lock files of the root modules with the --check-lock flag.
+func runSplitApps(out io.Writer, targets []genTarget, src *platformSource, pm *platform.PlatformMetadata, apps app.Apps, m *tfconfig.Module) error {
+	run := func(out io.Writer, outDir string) error {
+		for _, t := range targets {
+			lc, err := splitApps(src, pm, m, apps, flagApps, t.profile)
+			if err != nil {
+				return err
+			}
+
+			if flagCheckLock {
+				err = lc.checkLocks(out, t.destDir(outDir))
+			} else {
+				err = lc.generate(out, t.destDir(outDir))
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	if flagDryRun {
+		return dryRun(out, func(outDir string) error {
+			return run(io.Discard, outDir)
+		})
+	}
+
+	return run(out, flagOutDir)
+}
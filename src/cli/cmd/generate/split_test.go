@@ -0,0 +1,66 @@
+// Copyright (c) Ollion
+// SPDX-License-Identifier: Apache-2.0
+
+package generate
+
+import (
+	"testing"
+
+	"github.com/cldcvr/terrarium/src/pkg/metadata/app"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_getSharedDependencies(t *testing.T) {
+	tests := []struct {
+		name string
+		apps app.Apps
+		want map[string]struct{}
+	}{
+		{
+			name: "no shared dependencies",
+			apps: app.Apps{
+				{ID: "api", Dependencies: app.Dependencies{{ID: "db", Use: "postgres"}}},
+				{ID: "worker", Dependencies: app.Dependencies{{ID: "jobs", Use: "redis"}}},
+			},
+			want: map[string]struct{}{},
+		},
+		{
+			name: "dependency used by another app",
+			apps: app.Apps{
+				{ID: "api", Dependencies: app.Dependencies{{ID: "db", Use: "postgres"}, {ID: "cache", Use: "redis"}}},
+				{ID: "worker", Dependencies: app.Dependencies{{ID: "db", Use: "postgres", NoProvision: true}}},
+			},
+			want: map[string]struct{}{"postgres.db": {}},
+		},
+		{
+			name: "private dependency of a shared component",
+			apps: app.Apps{
+				{ID: "api", Dependencies: app.Dependencies{{ID: "db", Use: "postgres"}}},
+				{ID: "worker", Dependencies: app.Dependencies{{ID: "db", Use: "postgres", NoProvision: true}}},
+				{ID: "billing", Dependencies: app.Dependencies{{ID: "ledger", Use: "postgres"}}},
+			},
+			want: map[string]struct{}{"postgres.db": {}},
+		},
+		{
+			name: "compute used by another app",
+			apps: app.Apps{
+				{ID: "be", Compute: app.Dependency{ID: "be", Use: "server_web"}},
+				{ID: "fe", Dependencies: app.Dependencies{{ID: "be", Use: "server_web", NoProvision: true}}},
+			},
+			want: map[string]struct{}{"server_web.be": {}},
+		},
+		{
+			name: "dependency provisioned elsewhere",
+			apps: app.Apps{
+				{ID: "worker", Dependencies: app.Dependencies{{ID: "db", Use: "postgres", NoProvision: true}}},
+			},
+			want: map[string]struct{}{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, getSharedDependencies(tt.apps))
+		})
+	}
+}
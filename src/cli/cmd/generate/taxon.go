@@ -0,0 +1,97 @@
+// Copyright (c) Ollion
+// SPDX-License-Identifier: Apache-2.0
+
+package generate
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/cldcvr/terrarium/src/cli/internal/config"
+	"github.com/cldcvr/terrarium/src/pkg/metadata/app"
+	"github.com/cldcvr/terrarium/src/pkg/metadata/dependency"
+	"github.com/cldcvr/terrarium/src/pkg/metadata/platform"
+	"github.com/cldcvr/terrarium/src/pkg/metadata/taxonomy"
+	"github.com/rotisserie/eris"
+)
+
+// isTaxonSwitch returns true if the local variable name is a taxon switch, i.e. "tr_taxon_<level>_enabled".
+func isTaxonSwitch(name string) bool {
+	return strings.HasPrefix(name, platform.TaxonPrefix) && strings.HasSuffix(name, platform.SwitchSuffix) &&
+		len(name) > len(platform.TaxonPrefix)+len(platform.SwitchSuffix)
+}
+
+// getUsedTaxons returns the set of taxonomy levels of the dependency interfaces used by the apps.
+// For example, an app using an interface with the taxonomy "storage/database/rdbms" enables
+// the "storage", "database" and "rdbms" taxon switches.
+// The taxonomy is read from the dependency interfaces set with the --dependency-interfaces flag,
+// or from the farm database by default. Returns nil if the locals have no taxon switch.
+func getUsedTaxons(apps app.Apps, locals map[string]interface{}) (map[string]struct{}, error) {
+	switches := []string{}
+	for k := range locals {
+		if isTaxonSwitch(k) {
+			switches = append(switches, k)
+		}
+	}
+	if len(switches) == 0 {
+		return nil, nil
+	}
+
+	interfaces, source, err := loadTaxonomyInterfaces()
+	if err != nil {
+		sort.Strings(switches)
+		return nil, eris.Wrapf(err, "failed to load the taxonomy of the app dependencies to set the taxon switches [%s]. use --dependency-interfaces flag to set the dependency interfaces yaml file, or a directory containing them", strings.Join(switches, ", "))
+	}
+
+	taxons := map[string]struct{}{}
+	for _, depType := range apps.GetUniqueDependencyTypes() {
+		depInterface := interfaces.GetByID(platform.GetInterfaceID(depType))
+		if depInterface == nil {
+			log.Warn("dependency interface is not defined, its taxons are not enabled", "interface", depType, "source", source)
+			continue
+		}
+
+		for _, level := range depInterface.Taxonomy.Split() {
+			taxons[level] = struct{}{}
+		}
+	}
+
+	return taxons, nil
+}
+
+// loadTaxonomyInterfaces returns the dependency interfaces from the YAML files set with the --dependency-interfaces flag,
+// or from the farm database, along with where they are loaded from.
+func loadTaxonomyInterfaces() (dependency.Interfaces, string, error) {
+	if flagDependencyInterfaces != "" {
+		interfaces, err := dependency.LoadInterfaces(flagDependencyInterfaces)
+		return interfaces, flagDependencyInterfaces, err
+	}
+
+	const source = "farm database"
+
+	g, err := config.DBConnect()
+	if err != nil {
+		return nil, source, err
+	}
+
+	dbDeps, err := g.QueryDependencies()
+	if err != nil {
+		return nil, source, eris.Wrap(err, "failed to query the dependency interfaces")
+	}
+
+	if len(dbDeps) == 0 {
+		return nil, source, eris.New("the farm database has no dependency interfaces")
+	}
+
+	interfaces := make(dependency.Interfaces, 0, len(dbDeps))
+	for _, d := range dbDeps {
+		depInterface := dependency.Interface{ID: d.InterfaceID}
+		if d.Taxonomy != nil {
+			depInterface.Taxonomy = taxonomy.NewTaxonomy(d.Taxonomy.ToLevels()...)
+		}
+		interfaces = append(interfaces, depInterface)
+	}
+
+	return interfaces, source, nil
+}
@@ -0,0 +1,19 @@
+locals {
+  tr_component_network = {
+    default = {
+      cidr = "10.0.0.0/16"
+    }
+  }
+}
+
+module "tr_component_network" {
+  source = "./modules/network"
+
+  for_each = local.tr_component_network
+
+  cidr = each.value.cidr
+}
+
+output "tr_component_network_id" {
+  value = { for k, v in module.tr_component_network : k => v.id }
+}
@@ -0,0 +1,20 @@
+locals {
+  tr_component_postgres = {
+    default = {
+      version = "11"
+    }
+  }
+}
+
+module "tr_component_postgres" {
+  source = "./modules/postgres"
+
+  for_each = local.tr_component_postgres
+
+  engine_version = each.value.version
+  network_id     = local.tr_component_network_id["core"]
+}
+
+output "tr_component_postgres_host" {
+  value = { for k, v in module.tr_component_postgres : k => v.host }
+}
@@ -0,0 +1,8 @@
+module "tr_component_redis" {
+  source = "./modules/redis"
+
+  for_each = local.tr_component_redis
+
+  name = each.key
+  size = var.a_size
+}
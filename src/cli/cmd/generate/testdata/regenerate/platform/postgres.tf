@@ -0,0 +1,7 @@
+module "tr_component_postgres" {
+  source = "./modules/postgres"
+
+  for_each = local.tr_component_postgres
+
+  name = each.key
+}
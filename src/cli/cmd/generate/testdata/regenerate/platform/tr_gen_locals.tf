@@ -0,0 +1,9 @@
+locals {
+  tr_component_redis = {
+    default = {}
+  }
+
+  tr_component_postgres = {
+    default = {}
+  }
+}
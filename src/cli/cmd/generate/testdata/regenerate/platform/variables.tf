@@ -0,0 +1,4 @@
+variable "a_size" {
+  type    = number
+  default = 1
+}
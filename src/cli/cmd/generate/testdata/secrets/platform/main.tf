@@ -0,0 +1,27 @@
+locals {
+  tr_component_postgres = {
+    default = {
+      db_name = "app"
+    }
+  }
+}
+
+module "tr_component_postgres" {
+  source = "./modules/postgres"
+
+  for_each = local.tr_component_postgres
+
+  name    = each.key
+  db_name = each.value.db_name
+}
+
+output "tr_component_postgres_host" {
+  description = "The host address of the database server."
+  value       = { for k, v in module.tr_component_postgres : k => v.host }
+}
+
+output "tr_component_postgres_password" {
+  description = "The password for accessing the database."
+  value       = { for k, v in module.tr_component_postgres : k => v.password }
+  sensitive   = true
+}
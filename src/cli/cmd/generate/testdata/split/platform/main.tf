@@ -0,0 +1,36 @@
+locals {
+  tr_component_postgres = {
+    default = {
+      db_name = "app"
+    }
+  }
+
+  tr_component_redis = {
+    default = {}
+  }
+}
+
+module "tr_component_postgres" {
+  source = "./modules/postgres"
+
+  for_each = local.tr_component_postgres
+
+  name    = each.key
+  db_name = each.value.db_name
+}
+
+module "tr_component_redis" {
+  source = "./modules/redis"
+
+  for_each = local.tr_component_redis
+
+  name = each.key
+}
+
+output "tr_component_postgres_host" {
+  value = { for k, v in module.tr_component_postgres : k => v.host }
+}
+
+output "tr_component_redis_host" {
+  value = { for k, v in module.tr_component_redis : k => v.host }
+}
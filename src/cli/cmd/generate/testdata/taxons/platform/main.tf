@@ -0,0 +1,29 @@
+module "tr_component_postgres" {
+  source = "./modules/postgres"
+
+  for_each = local.tr_component_postgres
+
+  name      = each.key
+  db_name   = each.value.db_name
+  subnet_id = module.network.database_subnet_id
+}
+
+module "tr_component_redis" {
+  source = "./modules/redis"
+
+  for_each = local.tr_component_redis
+
+  name      = each.key
+  subnet_id = module.network.cache_subnet_id
+}
+
+module "network" {
+  source = "./modules/network"
+
+  database_enabled = local.tr_taxon_database_enabled
+  cache_enabled    = local.tr_taxon_cache_enabled
+}
+
+output "tr_component_postgres_host" {
+  value = { for k, v in module.tr_component_postgres : k => v.host }
+}
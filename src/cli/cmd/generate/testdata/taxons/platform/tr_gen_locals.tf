@@ -0,0 +1,15 @@
+locals {
+  tr_component_postgres = {
+    default = {
+      db_name = "app"
+    }
+  }
+  tr_component_redis = {
+    default = {}
+  }
+
+  tr_component_postgres_enabled = length(local.tr_component_postgres) > 0
+  tr_component_redis_enabled    = length(local.tr_component_redis) > 0
+  tr_taxon_database_enabled     = anytrue([local.tr_component_postgres_enabled])
+  tr_taxon_cache_enabled        = anytrue([local.tr_component_redis_enabled])
+}
@@ -0,0 +1,8 @@
+module "tr_component_postgres" {
+  source = "./modules/postgres"
+
+  for_each = local.tr_component_postgres
+
+  name       = each.key
+  subnet_ids = local.subnet_ids
+}
@@ -0,0 +1,8 @@
+locals {
+  tr_component_postgres = {
+    default = {}
+  }
+
+  # only the component inputs and switches are generated, so this local is not copied.
+  subnet_ids = ["subnet-a", "subnet-b"]
+}
@@ -0,0 +1,45 @@
+locals {
+  tr_component_postgres__v11 = {
+    default = {
+      storage = 20
+    }
+  }
+
+  tr_component_postgres__v15 = {
+    default = {
+      storage = 20
+    }
+  }
+}
+
+# @title: PostgreSQL 11
+# @versions: >= 11, < 12
+module "tr_component_postgres__v11" {
+  source = "./modules/postgres-legacy"
+
+  for_each = local.tr_component_postgres__v11
+
+  name              = each.key
+  engine_version    = "11"
+  allocated_storage = each.value.storage
+}
+
+# @title: PostgreSQL 15
+# @versions: >= 12
+module "tr_component_postgres__v15" {
+  source = "./modules/postgres"
+
+  for_each = local.tr_component_postgres__v15
+
+  name              = each.key
+  engine_version    = "15"
+  allocated_storage = each.value.storage
+}
+
+output "tr_component_postgres__v11_host" {
+  value = { for k, v in module.tr_component_postgres__v11 : k => v.host }
+}
+
+output "tr_component_postgres__v15_host" {
+  value = { for k, v in module.tr_component_postgres__v15 : k => v.host }
+}
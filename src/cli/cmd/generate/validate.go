@@ -0,0 +1,205 @@
+// Copyright (c) Ollion
+// SPDX-License-Identifier: Apache-2.0
+
+package generate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/cldcvr/terraform-config-inspect/tfconfig"
+	"github.com/cldcvr/terrarium/src/pkg/metadata/platform"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/rotisserie/eris"
+)
+
+// validateGenerated loads the terraform module generated in the destDir and returns an error listing the
+// references to locals, variables and modules that are not declared in it, the blocks declared more than once,
+// and the variables set by the profile configuration that are not declared in it.
+func validateGenerated(destDir, profileName string) error {
+	// the load diagnostics are not checked, as the platform code is loaded the same way
+	// and the syntax errors are reported when parsing the files for duplicate blocks.
+	m, _ := tfconfig.LoadModule(destDir, &tfconfig.ResolvedModulesSchema{})
+
+	problems := findUnresolvedReferences(m)
+
+	duplicates, err := findDuplicateBlocks(destDir)
+	if err != nil {
+		return err
+	}
+	problems = append(problems, duplicates...)
+
+	if profileName != "" {
+		undeclared, err := findUndeclaredProfileVariables(destDir, m)
+		if err != nil {
+			return err
+		}
+		problems = append(problems, undeclared...)
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return eris.Errorf("generated terraform code in '%s' is invalid:\n  %s", destDir, strings.Join(problems, "\n  "))
+	}
+
+	return nil
+}
+
+// findUnresolvedReferences returns a problem for each reference to a local, variable or module
+// that is not declared in the module.
+func findUnresolvedReferences(m *tfconfig.Module) []string {
+	problems := []string{}
+	for _, ref := range getUnresolvedReferences(m) {
+		file, line := ref.Pos()
+		problems = append(problems, fmt.Sprintf("%s:%d: reference to undeclared %s.%s", relPath(m.Path, file), line, ref.Type(), ref.Name()))
+	}
+
+	return problems
+}
+
+// getUnresolvedReferences returns the references to locals, variables and modules that are not declared in the module.
+func getUnresolvedReferences(m *tfconfig.Module) []tfconfig.AttributeReference {
+	blocks := []platform.ParsedBlock{}
+	for _, b := range m.Locals {
+		blocks = append(blocks, b)
+	}
+	for _, b := range m.ModuleCalls {
+		blocks = append(blocks, b)
+	}
+	for _, b := range m.ManagedResources {
+		blocks = append(blocks, b)
+	}
+	for _, b := range m.DataResources {
+		blocks = append(blocks, b)
+	}
+	for _, b := range m.Outputs {
+		blocks = append(blocks, b)
+	}
+
+	refs := []tfconfig.AttributeReference{}
+	for _, b := range blocks {
+		dg, ok := b.(platform.BlockDependencyGetter)
+		if !ok {
+			continue
+		}
+
+		for _, ref := range dg.GetDependencies() {
+			var found bool
+			switch ref.Type() {
+			case "local":
+				_, found = m.Locals[ref.Name()]
+			case "var":
+				_, found = m.Variables[ref.Name()]
+			case "module":
+				_, found = m.ModuleCalls[ref.Name()]
+			default:
+				continue
+			}
+
+			if !found {
+				refs = append(refs, ref)
+			}
+		}
+	}
+
+	return refs
+}
+
+// findDuplicateBlocks returns a problem for each local, variable, output, module, resource or data block
+// that is declared more than once in the terraform files in the dir.
+func findDuplicateBlocks(dir string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil {
+		return nil, err
+	}
+
+	declared := map[platform.BlockID]hcl.Range{}
+	problems := []string{}
+	add := func(id platform.BlockID, r hcl.Range) {
+		if prev, ok := declared[id]; ok {
+			problems = append(problems, fmt.Sprintf("%s:%d: duplicate %s, previously declared at %s:%d", relPath(dir, r.Filename), r.Start.Line, id, relPath(dir, prev.Filename), prev.Start.Line))
+			return
+		}
+		declared[id] = r
+	}
+
+	parser := hclparse.NewParser()
+	for _, file := range files {
+		f, diags := parser.ParseHCLFile(file)
+		if diags.HasErrors() {
+			return nil, eris.Wrapf(diags, "failed to parse file: %s", file)
+		}
+
+		body, ok := f.Body.(*hclsyntax.Body)
+		if !ok {
+			continue
+		}
+
+		for _, b := range body.Blocks {
+			if b.Type == hclBlockLocals {
+				for name, attr := range b.Body.Attributes {
+					add(platform.NewBlockID(platform.BlockType_Local, name), attr.NameRange)
+				}
+				continue
+			}
+
+			// providers are not included since a provider can be declared more than once with an alias.
+			if hclBlockTypes[b.Type] == platform.BlockType_Provider {
+				continue
+			}
+
+			if id, ok := getBlockIDFromLabels(b.Type, b.Labels); ok {
+				add(id, b.DefRange())
+			}
+		}
+	}
+
+	return problems, nil
+}
+
+// findUndeclaredProfileVariables returns a problem for each variable set in the profile configuration
+// copied to the dir that is not declared in the module.
+func findUndeclaredProfileVariables(dir string, m *tfconfig.Module) ([]string, error) {
+	profileFile, err := getProfileVariableInputDestFile(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := os.Stat(profileFile); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	attrs, err := readProfileAttributes(profileFile)
+	if err != nil {
+		return nil, err
+	}
+
+	problems := []string{}
+	for name, attr := range attrs {
+		if _, ok := m.Variables[name]; !ok {
+			problems = append(problems, fmt.Sprintf("%s:%d: value for undeclared variable '%s' set by the profile", relPath(dir, profileFile), attr.NameRange.Start.Line, name))
+		}
+	}
+
+	return problems, nil
+}
+
+// readProfileAttributes returns the variables set in the terraform input file without evaluating their values.
+func readProfileAttributes(profileFile string) (hcl.Attributes, error) {
+	f, diags := hclparse.NewParser().ParseHCLFile(profileFile)
+	if diags.HasErrors() {
+		return nil, eris.Wrapf(diags, "failed to parse file: %s", profileFile)
+	}
+
+	attrs, diags := f.Body.JustAttributes()
+	if diags.HasErrors() {
+		return nil, eris.Wrapf(diags, "failed to parse file: %s", profileFile)
+	}
+
+	return attrs, nil
+}
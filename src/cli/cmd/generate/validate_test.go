@@ -0,0 +1,79 @@
+// Copyright (c) Ollion
+// SPDX-License-Identifier: Apache-2.0
+
+package generate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_validateGenerated(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		profile string
+		wantErr string
+	}{
+		{
+			name: "valid",
+			files: map[string]string{
+				"main.tf":          "variable \"name\" {}\n\nmodule \"db\" {\n  source = \"./db\"\n  name   = var.name\n}\n\noutput \"host\" {\n  value = module.db.host\n}\n",
+				"tr_gen_locals.tf": "locals {\n  tr_component_db = {}\n}\n",
+			},
+		},
+		{
+			name: "unresolved references",
+			files: map[string]string{
+				"main.tf": "module \"db\" {\n  source = \"./db\"\n  name   = var.name\n  tags   = local.tags\n}\n\noutput \"host\" {\n  value = module.cache.host\n}\n",
+			},
+			wantErr: "is invalid:\n  main.tf:3: reference to undeclared var.name\n  main.tf:4: reference to undeclared local.tags\n  main.tf:8: reference to undeclared module.cache",
+		},
+		{
+			name: "duplicate blocks",
+			files: map[string]string{
+				"main.tf":          "locals {\n  tags = {}\n}\n\nmodule \"db\" {\n  source = \"./db\"\n}\n",
+				"tr_gen_locals.tf": "locals {\n  tags = {}\n}\n\nmodule \"db\" {\n  source = \"./db\"\n}\n",
+			},
+			wantErr: "is invalid:\n  tr_gen_locals.tf:2: duplicate local.tags, previously declared at main.tf:2\n  tr_gen_locals.tf:5: duplicate module.db, previously declared at main.tf:5",
+		},
+		{
+			name: "profile variables",
+			files: map[string]string{
+				"main.tf":                    "variable \"db_class\" {}\n",
+				"tr_gen_profile.auto.tfvars": "db_class = \"small\"\n",
+			},
+			profile: "dev",
+		},
+		{
+			name: "undeclared profile variables",
+			files: map[string]string{
+				"main.tf":                    "variable \"db_class\" {}\n",
+				"tr_gen_profile.auto.tfvars": "db_class = \"small\"\ncache_class = \"small\"\n",
+			},
+			profile: "dev",
+			wantErr: "is invalid:\n  tr_gen_profile.auto.tfvars:2: value for undeclared variable 'cache_class' set by the profile",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+			}
+
+			err := validateGenerated(dir, tt.profile)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
@@ -16,6 +16,7 @@ import (
 	"github.com/cldcvr/terrarium/src/cli/internal/config"
 	"github.com/cldcvr/terrarium/src/pkg/db"
 	"github.com/cldcvr/terrarium/src/pkg/git"
+	"github.com/cldcvr/terrarium/src/pkg/metadata/platform"
 	"github.com/google/go-github/github"
 	"github.com/google/uuid"
 	"github.com/rotisserie/eris"
@@ -300,7 +301,7 @@ func findTerrariumYaml(ctx context.Context, gl []*github.RepositoryContent, owne
 
 func compareYAMLWithSQLData(g db.DB, c Component, queryResult []db.DependencyResult, u uuid.UUID) error {
 	for _, r := range queryResult {
-		if c.ID == r.InterfaceID {
+		if platform.GetInterfaceID(c.ID) == r.InterfaceID {
 			_, err := g.CreatePlatformComponents(&db.PlatformComponent{
 				PlatformID:   u,
 				DependencyID: r.DependencyID,
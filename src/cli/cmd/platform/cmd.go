@@ -4,7 +4,10 @@
 package platform
 
 import (
+	"github.com/cldcvr/terrarium/src/cli/cmd/platform/diff"
+	"github.com/cldcvr/terrarium/src/cli/cmd/platform/graph"
 	"github.com/cldcvr/terrarium/src/cli/cmd/platform/lint"
+	"github.com/cldcvr/terrarium/src/cli/cmd/platform/schema"
 	"github.com/spf13/cobra"
 )
 
@@ -19,6 +22,9 @@ func NewCmd() *cobra.Command {
 	}
 
 	cmd.AddCommand(lint.NewCmd())
+	cmd.AddCommand(graph.NewCmd())
+	cmd.AddCommand(schema.NewCmd())
+	cmd.AddCommand(diff.NewCmd())
 
 	return cmd
 }
@@ -0,0 +1,169 @@
+// Copyright (c) Ollion
+// SPDX-License-Identifier: Apache-2.0
+
+package diff
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/cldcvr/terrarium/src/cli/internal/utils"
+	"github.com/cldcvr/terrarium/src/pkg/metadata/platform"
+	"github.com/rotisserie/eris"
+	"github.com/spf13/cobra"
+)
+
+const defaultYAMLFileName = "terrarium.yaml"
+
+var (
+	cmd *cobra.Command
+
+	flagDir          string
+	flagOutputFormat string
+)
+
+// report is the machine-readable result of the diff, written with '-o json'.
+type report struct {
+	Breaking bool             `json:"breaking"`
+	Changes  platform.Changes `json:"changes"`
+}
+
+func NewCmd() *cobra.Command {
+	cmd = &cobra.Command{
+		Use:   "diff <old> [<new>]",
+		Short: "Compare two revisions of the platform metadata",
+		Long: `Compare two revisions of the platform metadata (terrarium.yaml) and report the added and removed components, the removed or retyped inputs, the changed defaults, and the removed outputs and profiles.
+Each revision is either a path to a metadata file or to a platform directory, or a git ref of the platform directory given to the --dir flag. The new revision defaults to the metadata file in the platform directory.
+The command fails when any of the changes is breaking, i.e. may break the app manifests working with the old revision.`,
+		Example: `  terrarium platform diff v1.2.0
+  terrarium platform diff origin/main HEAD -d examples/platform -o json
+  terrarium platform diff old/terrarium.yaml new/terrarium.yaml`,
+		Args: cobra.RangeArgs(1, 2),
+		RunE: cmdRunE,
+	}
+
+	cmd.Flags().StringVarP(&flagDir, "dir", "d", ".", "path to the platform directory, used to read the metadata at the given git refs")
+	cmd.Flags().StringVarP(&flagOutputFormat, "output", "o", "table", "Output format (json or table)")
+
+	return cmd
+}
+
+func cmdRunE(cmd *cobra.Command, args []string) error {
+	if flagOutputFormat != "json" && flagOutputFormat != "table" {
+		return eris.Errorf("unsupported output format '%s'. must be one of: json, table", flagOutputFormat)
+	}
+
+	newSrc := flagDir
+	if len(args) > 1 {
+		newSrc = args[1]
+	}
+
+	oldPM, err := loadMetadata(args[0])
+	if err != nil {
+		return err
+	}
+
+	newPM, err := loadMetadata(newSrc)
+	if err != nil {
+		return err
+	}
+
+	changes := oldPM.Diff(newPM)
+	breaking := changes.Breaking()
+
+	if flagOutputFormat == "json" {
+		err = writeJSON(cmd.OutOrStdout(), changes)
+	} else {
+		writeTable(cmd.OutOrStdout(), changes)
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(breaking) > 0 {
+		return eris.Errorf("found %d breaking changes between '%s' and '%s'", len(breaking), args[0], newSrc)
+	}
+
+	return nil
+}
+
+// loadMetadata reads the platform metadata from the given file, from the platform directory,
+// or from the platform directory at the given git ref.
+func loadMetadata(src string) (*platform.PlatformMetadata, error) {
+	var content []byte
+	if info, err := os.Stat(src); err == nil {
+		filePath := src
+		if info.IsDir() {
+			filePath = filepath.Join(src, defaultYAMLFileName)
+		}
+
+		content, err = os.ReadFile(filePath)
+		if err != nil {
+			return nil, eris.Wrapf(err, "failed to read the platform metadata file '%s'", filePath)
+		}
+	} else {
+		content, err = readGitFile(flagDir, src, defaultYAMLFileName)
+		if err != nil {
+			return nil, eris.Wrapf(err, "failed to read the platform metadata at '%s'", src)
+		}
+	}
+
+	pm := &platform.PlatformMetadata{}
+	if err := pm.FromFileBytes(content); err != nil {
+		return nil, eris.Wrapf(err, "failed to parse the platform metadata at '%s'", src)
+	}
+
+	return pm, nil
+}
+
+// readGitFile returns the content of the file in the directory at the given git ref.
+func readGitFile(dir, ref, fileName string) ([]byte, error) {
+	c := exec.Command("git", "show", ref+":./"+fileName)
+	c.Dir = dir
+	out, err := c.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return nil, eris.Errorf("git show failed: %s", strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, eris.Wrap(err, "failed to run git show")
+	}
+
+	return out, nil
+}
+
+func writeJSON(out io.Writer, changes platform.Changes) error {
+	b, err := json.MarshalIndent(report{Breaking: len(changes.Breaking()) > 0, Changes: changes}, "", "  ")
+	if err != nil {
+		return eris.Wrap(err, "error formatting output to json")
+	}
+
+	fmt.Fprintf(out, "%s\n", b)
+	return nil
+}
+
+func writeTable(out io.Writer, changes platform.Changes) {
+	if len(changes) == 0 {
+		fmt.Fprintln(out, "No changes found.")
+		return
+	}
+
+	table := utils.OutFormatForList(out)
+	table.SetHeader([]string{"#", "Breaking", "Kind", "Description"})
+	for i, c := range changes {
+		breaking := "no"
+		if c.Breaking {
+			breaking = "yes"
+		}
+		table.Append([]string{fmt.Sprintf("%d", i+1), breaking, string(c.Kind), c.String()})
+	}
+	table.Render()
+
+	fmt.Fprintf(out, "\n%d changes, %d breaking\n", len(changes), len(changes.Breaking()))
+}
@@ -0,0 +1,122 @@
+// Copyright (c) Ollion
+// SPDX-License-Identifier: Apache-2.0
+
+package diff
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/cldcvr/terrarium/src/pkg/metadata/platform"
+	"github.com/cldcvr/terrarium/src/pkg/testutils/clitesting"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCmd(t *testing.T) {
+	// platform repository with the old metadata committed, and the new metadata in the working tree
+	repoDir := t.TempDir()
+	copyFile(t, "testdata/old.yaml", filepath.Join(repoDir, "terrarium.yaml"))
+	mustRunGit(t, repoDir, "init", "-q")
+	mustRunGit(t, repoDir, "add", "-A")
+	mustRunGit(t, repoDir, "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "init")
+	copyFile(t, "testdata/breaking.yaml", filepath.Join(repoDir, "terrarium.yaml"))
+
+	clitest := clitesting.CLITest{
+		CmdToTest: NewCmd,
+	}
+
+	clitest.RunTests(t, []clitesting.CLITestCase{
+		{
+			Name:           "render help",
+			Args:           []string{"-h"},
+			ValidateOutput: clitesting.ValidateOutputContains("Compare two revisions of the platform metadata (terrarium.yaml)"),
+		},
+		{
+			Name: "non-breaking changes",
+			Args: []string{"testdata/old.yaml", "testdata/new.yaml"},
+			ValidateOutput: func(ctx context.Context, t *testing.T, cmdOpts clitesting.CmdOpts, output []byte) bool {
+				pass := assert.Contains(t, string(output), "component 'mysql' is added")
+				pass = assert.Contains(t, string(output), "output 'username' is added to") && pass
+				pass = assert.Contains(t, string(output), "3 changes, 0 breaking\n") && pass
+				return pass
+			},
+		},
+		{
+			Name:           "no changes",
+			Args:           []string{"testdata/old.yaml", "testdata/old.yaml"},
+			ValidateOutput: clitesting.ValidateOutputMatch("No changes found.\n"),
+		},
+		{
+			Name: "json",
+			Args: []string{"testdata/old.yaml", "testdata/new.yaml", "-o", "json"},
+			ValidateOutput: clitesting.ValidateOutputJson(`{
+				"breaking": false,
+				"changes": [
+					{"kind": "component_added", "component": "mysql", "breaking": false},
+					{"kind": "input_default_changed", "component": "postgres", "name": "version", "old": 11, "new": 15, "breaking": false},
+					{"kind": "output_added", "component": "postgres", "name": "username", "breaking": false}
+				]
+			}`),
+		},
+		{
+			Name:     "breaking changes",
+			Args:     []string{"testdata/old.yaml", "testdata/breaking.yaml", "-o", "json"},
+			WantErr:  true,
+			ExpError: "found 5 breaking changes between 'testdata/old.yaml' and 'testdata/breaking.yaml'",
+			ValidateOutput: func(ctx context.Context, t *testing.T, cmdOpts clitesting.CmdOpts, output []byte) bool {
+				var got struct {
+					Breaking bool             `json:"breaking"`
+					Changes  platform.Changes `json:"changes"`
+				}
+				pass := assert.NoError(t, json.NewDecoder(bytes.NewReader(output)).Decode(&got))
+				pass = assert.True(t, got.Breaking) && pass
+				pass = assert.Len(t, got.Changes.Breaking(), 5) && pass
+				return pass
+			},
+		},
+		{
+			Name:     "git ref",
+			Args:     []string{"HEAD", "-d", repoDir},
+			WantErr:  true,
+			ExpError: "found 5 breaking changes between 'HEAD' and '" + repoDir + "'",
+			ValidateOutput: func(ctx context.Context, t *testing.T, cmdOpts clitesting.CmdOpts, output []byte) bool {
+				pass := assert.Contains(t, string(output), "output_removed")
+				pass = assert.Contains(t, string(output), "6 changes, 5 breaking\n") && pass
+				return pass
+			},
+		},
+		{
+			Name:     "unknown git ref",
+			Args:     []string{"v0.0.1", "-d", repoDir},
+			WantErr:  true,
+			ExpError: "failed to read the platform metadata at 'v0.0.1': git show failed",
+		},
+		{
+			Name:     "invalid output format",
+			Args:     []string{"testdata/old.yaml", "-o", "xml"},
+			WantErr:  true,
+			ExpError: "unsupported output format 'xml'. must be one of: json, table",
+		},
+	})
+}
+
+func copyFile(t *testing.T, src, dst string) {
+	t.Helper()
+	content, err := os.ReadFile(src)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(dst, content, 0o644))
+}
+
+func mustRunGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	c := exec.Command("git", args...)
+	c.Dir = dir
+	out, err := c.CombinedOutput()
+	require.NoError(t, err, string(out))
+}
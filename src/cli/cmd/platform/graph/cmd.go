@@ -0,0 +1,87 @@
+// Copyright (c) Ollion
+// SPDX-License-Identifier: Apache-2.0
+
+package graph
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/cldcvr/terraform-config-inspect/tfconfig"
+	"github.com/cldcvr/terrarium/src/cli/internal/constants"
+	"github.com/cldcvr/terrarium/src/pkg/metadata/platform"
+	"github.com/rotisserie/eris"
+	"github.com/spf13/cobra"
+)
+
+const stdoutFileName = "-"
+
+var (
+	cmd *cobra.Command
+
+	flagDir        string
+	flagFormat     string
+	flagOutFile    string
+	flagRequiredBy string
+)
+
+func NewCmd() *cobra.Command {
+	cmd = &cobra.Command{
+		Use:   "graph",
+		Short: "Export the platform block graph",
+		Long:  "Export the graph of the terraform blocks in the platform template, and the blocks each of them requires, as Graphviz DOT or Mermaid.",
+		RunE:  cmdRunE,
+	}
+
+	cmd.Flags().StringVarP(&flagDir, "dir", "d", ".", "path to the platform directory")
+	cmd.Flags().StringVarP(&flagFormat, "format", "f", string(platform.GraphFormatDOT), fmt.Sprintf("format of the graph. supported formats: %s", strings.Join(platform.GraphFormats(), ", ")))
+	cmd.Flags().StringVarP(&flagOutFile, "output", "o", stdoutFileName, "path to the file to write the graph to. writes to stdout when set to '-'")
+	cmd.Flags().StringVar(&flagRequiredBy, "required-by", "", "list the blocks that require the given block (i.e. 'var.region') directly or through other blocks, instead of exporting the graph")
+
+	return cmd
+}
+
+func cmdRunE(cmd *cobra.Command, args []string) error {
+	if _, err := os.Stat(flagDir); os.IsNotExist(err) {
+		return eris.Wrapf(err, "could not open given directory '%s'", flagDir)
+	}
+
+	m, _ := tfconfig.LoadModule(flagDir, &tfconfig.ResolvedModulesSchema{})
+	g := platform.NewGraph(m)
+
+	if flagRequiredBy != "" {
+		return listRequiredBy(cmd.OutOrStdout(), g, platform.BlockID(flagRequiredBy))
+	}
+
+	if flagOutFile == stdoutFileName {
+		return g.Export(cmd.OutOrStdout(), platform.GraphFormat(flagFormat))
+	}
+
+	var sb strings.Builder
+	if err := g.Export(&sb, platform.GraphFormat(flagFormat)); err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(flagOutFile, []byte(sb.String()), constants.ReadWritePermissions); err != nil {
+		return eris.Wrapf(err, "failed to write file: %s", flagOutFile)
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "Successfully exported the graph of %d terraform blocks to: %s\n", len(g), flagOutFile)
+	return nil
+}
+
+// listRequiredBy writes the IDs of the blocks that require the given block, one per line.
+func listRequiredBy(out io.Writer, g platform.Graph, bID platform.BlockID) error {
+	gi := g.Index()
+	if gi.GetByID(bID) == nil {
+		return eris.Errorf("block '%s' is not required by any component in the platform", bID)
+	}
+
+	for _, reqByID := range gi.AllRequiredBy(bID) {
+		fmt.Fprintln(out, reqByID)
+	}
+
+	return nil
+}
@@ -0,0 +1,84 @@
+// Copyright (c) Ollion
+// SPDX-License-Identifier: Apache-2.0
+
+package graph
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cldcvr/terrarium/src/pkg/testutils/clitesting"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCmd(t *testing.T) {
+	outFile := filepath.Join(t.TempDir(), "graph.mmd")
+
+	clitest := clitesting.CLITest{
+		CmdToTest: NewCmd,
+	}
+
+	clitest.RunTests(t, []clitesting.CLITestCase{
+		{
+			Name:           "render help",
+			Args:           []string{"-h"},
+			ValidateOutput: clitesting.ValidateOutputContains("Export the graph of the terraform blocks in the platform template"),
+		},
+		{
+			Name: "dot",
+			Args: []string{"-d", "testdata/platform"},
+			ValidateOutput: clitesting.ValidateOutputMatch(`digraph platform {
+  rankdir=LR;
+  node [fontname="Helvetica"];
+  "local.tr_component_postgres" [shape=note, style=filled, fillcolor="#fdbf6f"];
+  "module.tr_component_postgres" [shape=box, style="filled,bold", fillcolor="#a6cee3"];
+  "output.tr_component_postgres_host" [shape=ellipse, style=filled, fillcolor="#fb9a99"];
+  "var.db_instance_class" [shape=parallelogram, style=filled, fillcolor="#b2df8a"];
+  "module.tr_component_postgres" -> "local.tr_component_postgres";
+  "module.tr_component_postgres" -> "var.db_instance_class";
+  "output.tr_component_postgres_host" -> "module.tr_component_postgres";
+}
+`),
+		},
+		{
+			Name: "mermaid to file",
+			Args: []string{"-d", "testdata/platform", "-f", "mermaid", "-o", outFile},
+			ValidateOutput: func(ctx context.Context, t *testing.T, cmdOpts clitesting.CmdOpts, output []byte) bool {
+				pass := assert.Equal(t, "Successfully exported the graph of 4 terraform blocks to: "+outFile+"\n", string(output))
+				content, err := os.ReadFile(outFile)
+				pass = assert.NoError(t, err) && pass
+				pass = assert.Contains(t, string(content), "flowchart LR\n") && pass
+				pass = assert.Contains(t, string(content), "  n1[\"module.tr_component_postgres\"]:::component\n  n2[\"output.tr_component_postgres_host\"]:::output\n") && pass
+				pass = assert.Contains(t, string(content), "  n2 --> n1\n") && pass
+				return pass
+			},
+		},
+		{
+			Name: "required by",
+			Args: []string{"-d", "testdata/platform", "--required-by", "var.db_instance_class"},
+			ValidateOutput: clitesting.ValidateOutputMatch(`module.tr_component_postgres
+output.tr_component_postgres_host
+`),
+		},
+		{
+			Name:     "required by unknown block",
+			Args:     []string{"-d", "testdata/platform", "--required-by", "var.region"},
+			WantErr:  true,
+			ExpError: "block 'var.region' is not required by any component in the platform",
+		},
+		{
+			Name:     "invalid format",
+			Args:     []string{"-d", "testdata/platform", "-f", "svg"},
+			WantErr:  true,
+			ExpError: "unsupported graph format 'svg'. must be one of: dot, mermaid",
+		},
+		{
+			Name:     "invalid directory",
+			Args:     []string{"-d", "testdata/invalid-path"},
+			WantErr:  true,
+			ExpError: "could not open given directory",
+		},
+	})
+}
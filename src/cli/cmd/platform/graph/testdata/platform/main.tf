@@ -0,0 +1,25 @@
+locals {
+  tr_component_postgres = {
+    default = {
+      version = 11
+    }
+  }
+}
+
+variable "db_instance_class" {
+  type    = string
+  default = "db.t3.micro"
+}
+
+module "tr_component_postgres" {
+  source = "./modules/postgres"
+
+  for_each = local.tr_component_postgres
+
+  name           = each.key
+  instance_class = var.db_instance_class
+}
+
+output "tr_component_postgres_host" {
+  value = { for k, v in module.tr_component_postgres : k => v.host }
+}
@@ -14,7 +14,9 @@ import (
 var (
 	cmd *cobra.Command
 
-	flagDir string
+	flagDir                      string
+	flagDependencyInterfaces     string
+	flagFarmDependencyInterfaces bool
 )
 
 func NewCmd() *cobra.Command {
@@ -26,6 +28,9 @@ func NewCmd() *cobra.Command {
 	}
 
 	cmd.Flags().StringVarP(&flagDir, "dir", "d", ".", "Path to platform directory to validate.")
+	cmd.Flags().StringVar(&flagDependencyInterfaces, "dependency-interfaces", "", "path to a dependency interfaces yaml file, or a directory containing them, to validate the platform components against. reports the components implementing unknown interfaces, and the missing inputs and outputs and the input type mismatches")
+	cmd.Flags().BoolVar(&flagFarmDependencyInterfaces, "farm-dependency-interfaces", false, "validate the platform components against the dependency interfaces in the farm database, instead of a dependency interfaces yaml file")
+	cmd.MarkFlagsMutuallyExclusive("dependency-interfaces", "farm-dependency-interfaces")
 
 	return cmd
 }
@@ -34,7 +39,12 @@ func cmdRunE(cmd *cobra.Command, args []string) error {
 	if err := checkDirExists(flagDir); err != nil {
 		return err
 	}
-	err := lintPlatform(flagDir)
+	interfaces, err := loadInterfaces(flagDependencyInterfaces, flagFarmDependencyInterfaces)
+	if err != nil {
+		return err
+	}
+
+	err = lintPlatform(flagDir, interfaces)
 	if err != nil {
 		return err
 	}
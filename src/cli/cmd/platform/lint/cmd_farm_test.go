@@ -0,0 +1,54 @@
+// Copyright (c) Ollion
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build mock
+// +build mock
+
+package lint
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/cldcvr/terrarium/src/cli/internal/config"
+	"github.com/cldcvr/terrarium/src/pkg/db"
+	"github.com/cldcvr/terrarium/src/pkg/db/mocks"
+	"github.com/cldcvr/terrarium/src/pkg/jsonschema"
+	"github.com/cldcvr/terrarium/src/pkg/testutils/clitesting"
+)
+
+func TestCmd_farmDependencyInterfaces(t *testing.T) {
+	config.LoadDefaults()
+	mockDB := &mocks.DB{}
+	mockDB.On("QueryDependencies").Return(nil, fmt.Errorf("mock error")).Once()
+	mockDB.On("QueryDependencies").Return(db.Dependencies{
+		{
+			InterfaceID: "postgres",
+			Attributes: db.DependencyAttributes{
+				{Name: "db_name", Schema: &jsonschema.Node{Type: "string"}},
+				{Name: "host", Computed: true, Schema: &jsonschema.Node{Type: "string"}},
+				{Name: "username", Computed: true, Schema: &jsonschema.Node{Type: "string"}},
+			},
+		},
+	}, nil)
+	config.SetDBMocks(mockDB)
+
+	clitest := clitesting.CLITest{
+		CmdToTest: NewCmd,
+	}
+
+	clitest.RunTests(t, []clitesting.CLITestCase{
+		{
+			Name:     "db failure",
+			Args:     []string{"-d", "testdata/valid-terraform-1", "--farm-dependency-interfaces"},
+			WantErr:  true,
+			ExpError: "failed to query the dependency interfaces",
+		},
+		{
+			Name:     "not conforming to dependency interfaces",
+			Args:     []string{"-d", "testdata/valid-terraform-1", "--farm-dependency-interfaces"},
+			WantErr:  true,
+			ExpError: "component 'postgres': output 'username' of the dependency interface is missing",
+		},
+	})
+}
@@ -31,5 +31,25 @@ func TestCmd(t *testing.T) {
 			Args:           []string{"-d", "testdata/valid-terraform-1"},
 			ValidateOutput: clitesting.ValidateOutputMatch("Platform parse and lint completed\n"),
 		},
+		{
+			Name:           "conforming to dependency interfaces",
+			Args:           []string{"-d", "testdata/valid-terraform-1", "--dependency-interfaces", "testdata/dependency-interfaces/conforming.yaml"},
+			ValidateOutput: clitesting.ValidateOutputMatch("Platform parse and lint completed\n"),
+		},
+		{
+			Name:    "not conforming to dependency interfaces",
+			Args:    []string{"-d", "testdata/valid-terraform-1", "--dependency-interfaces", "testdata/dependency-interfaces/nonconforming.yaml"},
+			WantErr: true,
+			ExpError: `platform lint: platform components do not conform to their dependency interfaces:
+  component 'postgres': input 'ssl_mode' of the dependency interface is not accepted
+  component 'postgres': input 'version' is of type 'string' instead of 'number' declared by the dependency interface
+  component 'postgres': output 'username' of the dependency interface is missing`,
+		},
+		{
+			Name:     "invalid dependency interfaces path",
+			Args:     []string{"-d", "testdata/valid-terraform-1", "--dependency-interfaces", "testdata/dependency-interfaces/missing.yaml"},
+			WantErr:  true,
+			ExpError: "failed to load dependency interfaces from: testdata/dependency-interfaces/missing.yaml",
+		},
 	})
 }
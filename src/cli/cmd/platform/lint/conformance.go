@@ -0,0 +1,127 @@
+// Copyright (c) Ollion
+// SPDX-License-Identifier: Apache-2.0
+
+package lint
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cldcvr/terrarium/src/cli/internal/config"
+	"github.com/cldcvr/terrarium/src/pkg/jsonschema"
+	"github.com/cldcvr/terrarium/src/pkg/metadata/dependency"
+	"github.com/cldcvr/terrarium/src/pkg/metadata/platform"
+	"github.com/rotisserie/eris"
+	"golang.org/x/exp/maps"
+)
+
+// loadInterfaces returns the dependency interfaces to check the platform components against,
+// from the YAML files at the given path or from the farm database. Returns nil if neither is set.
+func loadInterfaces(path string, fromDB bool) (dependency.Interfaces, error) {
+	if path != "" {
+		return dependency.LoadInterfaces(path)
+	}
+
+	if !fromDB {
+		return nil, nil
+	}
+
+	g, err := config.DBConnect()
+	if err != nil {
+		return nil, err
+	}
+
+	dbDeps, err := g.QueryDependencies()
+	if err != nil {
+		return nil, eris.Wrap(err, "failed to query the dependency interfaces")
+	}
+
+	interfaces := make(dependency.Interfaces, 0, len(dbDeps))
+	for _, d := range dbDeps {
+		interfaces = append(interfaces, dependency.Interface{
+			ID:          d.InterfaceID,
+			Title:       d.Title,
+			Description: d.Description,
+			Inputs:      d.GetInputs(),
+			Outputs:     d.GetOutputs(),
+		})
+	}
+
+	return interfaces, nil
+}
+
+// validateConformance checks that each platform component implements a dependency interface,
+// accepts the inputs declared by the interface with the same types, and produces the outputs declared by it.
+func validateConformance(components platform.Components, interfaces dependency.Interfaces) error {
+	msgs := []string{}
+	for _, cmp := range components {
+		iface := interfaces.GetByID(cmp.InterfaceID())
+		if iface == nil {
+			msgs = append(msgs, fmt.Sprintf("component '%s' implements the unknown dependency interface '%s'", cmp.ID, cmp.InterfaceID()))
+			continue
+		}
+
+		for _, msg := range checkInputsConformance(iface.Inputs, cmp.Inputs, "") {
+			msgs = append(msgs, fmt.Sprintf("component '%s': %s", cmp.ID, msg))
+		}
+
+		for _, name := range sortedPropertyNames(iface.Outputs) {
+			if cmp.Outputs == nil || cmp.Outputs.Properties[name] == nil {
+				msgs = append(msgs, fmt.Sprintf("component '%s': output '%s' of the dependency interface is missing", cmp.ID, name))
+			}
+		}
+	}
+
+	if len(msgs) > 0 {
+		return eris.Errorf("platform components do not conform to their dependency interfaces:\n  %s", strings.Join(msgs, "\n  "))
+	}
+
+	return nil
+}
+
+// checkInputsConformance returns the inputs of the interface schema that are not accepted by the component schema,
+// or are accepted with a different type. The types are compared for the nested properties and items as well.
+func checkInputsConformance(ifaceNode, cmpNode *jsonschema.Node, path string) []string {
+	msgs := []string{}
+	if ifaceNode == nil {
+		return msgs
+	}
+	if cmpNode == nil {
+		cmpNode = &jsonschema.Node{}
+	}
+
+	if path != "" && !jsonschema.IsTypeCompatible(ifaceNode.Type, cmpNode.Type) {
+		return append(msgs, fmt.Sprintf("input '%s' is of type '%s' instead of '%s' declared by the dependency interface", path, cmpNode.Type, ifaceNode.Type))
+	}
+
+	if ifaceNode.Items != nil && cmpNode.Items != nil {
+		msgs = append(msgs, checkInputsConformance(ifaceNode.Items, cmpNode.Items, path+"[*]")...)
+	}
+
+	for _, name := range sortedPropertyNames(ifaceNode) {
+		propPath := name
+		if path != "" {
+			propPath = path + "." + name
+		}
+
+		if cmpNode.Properties[name] == nil {
+			msgs = append(msgs, fmt.Sprintf("input '%s' of the dependency interface is not accepted", propPath))
+			continue
+		}
+
+		msgs = append(msgs, checkInputsConformance(ifaceNode.Properties[name], cmpNode.Properties[name], propPath)...)
+	}
+
+	return msgs
+}
+
+func sortedPropertyNames(node *jsonschema.Node) []string {
+	if node == nil {
+		return nil
+	}
+
+	names := maps.Keys(node.Properties)
+	sort.Strings(names)
+	return names
+}
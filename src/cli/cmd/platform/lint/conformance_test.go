@@ -0,0 +1,129 @@
+// Copyright (c) Ollion
+// SPDX-License-Identifier: Apache-2.0
+
+package lint
+
+import (
+	"testing"
+
+	"github.com/cldcvr/terrarium/src/pkg/jsonschema"
+	"github.com/cldcvr/terrarium/src/pkg/metadata/dependency"
+	"github.com/cldcvr/terrarium/src/pkg/metadata/platform"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_validateConformance(t *testing.T) {
+	interfaces := dependency.Interfaces{
+		{
+			ID: "postgres",
+			Inputs: &jsonschema.Node{
+				Type: "object",
+				Properties: map[string]*jsonschema.Node{
+					"db_name": {Type: "string"},
+					"storage": {Type: "integer"},
+					"tags": {
+						Type:  "array",
+						Items: &jsonschema.Node{Type: "object", Properties: map[string]*jsonschema.Node{"key": {Type: "string"}}},
+					},
+				},
+			},
+			Outputs: &jsonschema.Node{
+				Type: "object",
+				Properties: map[string]*jsonschema.Node{
+					"host": {Type: "string"},
+					"port": {Type: "number"},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		components platform.Components
+		wantErrs   []string
+	}{
+		{
+			name: "conforming",
+			components: platform.Components{
+				{
+					ID: "postgres__v11",
+					Inputs: &jsonschema.Node{
+						Type: "object",
+						Properties: map[string]*jsonschema.Node{
+							"db_name": {Type: "string"},
+							"storage": {Type: "number"},
+							"tags": {
+								Type:  "array",
+								Items: &jsonschema.Node{Type: "object", Properties: map[string]*jsonschema.Node{"key": {Type: "string"}, "value": {Type: "string"}}},
+							},
+							"multi_az": {Type: "boolean"},
+						},
+					},
+					Outputs: &jsonschema.Node{
+						Type: "object",
+						Properties: map[string]*jsonschema.Node{
+							"host":       {},
+							"port":       {},
+							"read_hosts": {Type: "array"},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "not conforming",
+			components: platform.Components{
+				{
+					ID: "postgres",
+					Inputs: &jsonschema.Node{
+						Type: "object",
+						Properties: map[string]*jsonschema.Node{
+							"storage": {Type: "string"},
+							"tags": {
+								Type:  "array",
+								Items: &jsonschema.Node{Type: "object", Properties: map[string]*jsonschema.Node{"key": {Type: "number"}}},
+							},
+						},
+					},
+					Outputs: &jsonschema.Node{
+						Type:       "object",
+						Properties: map[string]*jsonschema.Node{"host": {}},
+					},
+				},
+				{
+					ID: "redis",
+				},
+			},
+			wantErrs: []string{
+				"component 'postgres': input 'db_name' of the dependency interface is not accepted",
+				"component 'postgres': input 'storage' is of type 'string' instead of 'integer' declared by the dependency interface",
+				"component 'postgres': input 'tags[*].key' is of type 'number' instead of 'string' declared by the dependency interface",
+				"component 'postgres': output 'port' of the dependency interface is missing",
+				"component 'redis' implements the unknown dependency interface 'redis'",
+			},
+		},
+		{
+			name:       "component without inputs and outputs",
+			components: platform.Components{{ID: "postgres"}},
+			wantErrs: []string{
+				"component 'postgres': input 'db_name' of the dependency interface is not accepted",
+				"component 'postgres': output 'host' of the dependency interface is missing",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateConformance(tt.components, interfaces)
+			if len(tt.wantErrs) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.ErrorContains(t, err, "platform components do not conform to their dependency interfaces:")
+			for _, wantErr := range tt.wantErrs {
+				assert.ErrorContains(t, err, wantErr)
+			}
+		})
+	}
+}
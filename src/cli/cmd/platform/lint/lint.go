@@ -7,29 +7,41 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/charmbracelet/log"
 	"github.com/cldcvr/terraform-config-inspect/tfconfig"
 	"github.com/cldcvr/terrarium/src/cli/internal/constants"
+	"github.com/cldcvr/terrarium/src/pkg/jsonschema"
+	"github.com/cldcvr/terrarium/src/pkg/metadata/dependency"
 	"github.com/cldcvr/terrarium/src/pkg/metadata/platform"
 	"github.com/cldcvr/terrarium/src/pkg/tf/parser"
 	"github.com/hashicorp/hcl/v2"
 	"github.com/rotisserie/eris"
+	"golang.org/x/exp/maps"
 	"golang.org/x/exp/slices"
 	"gopkg.in/yaml.v3"
 )
 
 const terrariumComponentModulePrefix = "tr_component_"
 const terrariumComponentModuleEnabledSuffix = "_enabled"
-const terrariumTaxonEnabledPrefix = "tr_taxon_"
-const terrariumTaxonEnabledSuffix = "_enabled"
 
-func lintPlatform(dir string) error {
+func lintPlatform(dir string, interfaces dependency.Interfaces) error {
 	log.Info("Linting terrarium platform template...")
 
 	log.Infof("Loading Terraform modules to lint from '%s'...", dir)
-	module, _ := tfconfig.LoadModule(dir, &tfconfig.ResolvedModulesSchema{})
+	// the module calls are loaded along with the platform when the modules are installed (i.e. 'terraform init'),
+	// for the types of the component outputs to be inferred from the outputs of the called modules.
+	resolvedModules := &tfconfig.ResolvedModulesSchema{}
+	schemaFile := filepath.Join(dir, constants.ModuleSchemaFilePath)
+	if _, err := os.Stat(schemaFile); err == nil {
+		if err := resolvedModules.UnmarshalFromFile(schemaFile); err != nil {
+			log.Warn("failed to read the installed modules, the module calls are not loaded", "path", schemaFile, "error", err)
+		}
+	}
+
+	module, _ := tfconfig.LoadModule(dir, resolvedModules)
 
 	log.Info("Validating Terraform modules...")
 	if err := validatePlatformTerraform(module); err != nil {
@@ -41,6 +53,21 @@ func lintPlatform(dir string) error {
 		log.Infof("Following platform issues were found: %v", err)
 		return eris.Wrap(err, "platform lint")
 	}
+
+	if err := validatePlatformProfiles(module); err != nil {
+		log.Infof("Following profile issues were found: %v", err)
+		return eris.Wrap(err, "platform lint")
+	}
+
+	if interfaces != nil {
+		log.Info("Validating platform components against the dependency interfaces...")
+		components := platform.Components{}
+		components.Parse(module)
+		if err := validateConformance(components, interfaces); err != nil {
+			log.Infof("Following conformance issues were found: %v", err)
+			return eris.Wrap(err, "platform lint")
+		}
+	}
 	log.Info("Platform is valid.")
 
 	metadataFile := filepath.Join(dir, "terrarium.yaml")
@@ -88,7 +115,7 @@ func validatePlatformTerraform(module *tfconfig.Module) error {
 		}
 
 		// Assert taxon switch variables are boolean.
-		if strings.HasPrefix(name, terrariumTaxonEnabledPrefix) && strings.HasSuffix(name, terrariumTaxonEnabledSuffix) {
+		if strings.HasPrefix(name, platform.TaxonPrefix) && strings.HasSuffix(name, platform.SwitchSuffix) {
 			if !parser.IsBool(expr.Expression) {
 				return eris.Errorf("terraform variable '%s' %s must evaluate to a boolean", name, fmtExpressionPosition(expr.Expression))
 			}
@@ -117,6 +144,11 @@ func validatePlatformTerraform(module *tfconfig.Module) error {
 		}
 	}
 
+	// Ensure the blocks do not require each other in a cycle, i.e. locals referring to each other.
+	if _, err := platform.NewGraph(module).Index().TopologicalSort(); err != nil {
+		return eris.Wrap(err, "terraform blocks require each other")
+	}
+
 	return nil
 }
 
@@ -125,12 +157,55 @@ func validatePlatformMetadata(module *tfconfig.Module) error {
 	components := platform.Components{}
 	components.Parse(module)
 	for _, cmp := range components {
+		if errs := cmp.DocErrors(); len(errs) > 0 {
+			msgs := make([]string, 0, len(errs))
+			for _, err := range errs {
+				msgs = append(msgs, err.Error())
+			}
+			sort.Strings(msgs)
+			return eris.Errorf("platform component '%s' has invalid doc tags:\n  %s", cmp.ID, strings.Join(msgs, "\n  "))
+		}
+
 		for name, value := range cmp.Inputs.Properties {
 			if len(value.Enum) > 0 && !slices.Contains(value.Enum, value.Default) {
 				return eris.Errorf("platform component '%s' has default value (%s) for input '%s' that is not one of the enumerated allowed values: %s", cmp.ID, value.Default, name, fmtItems(value.Enum))
 			}
 		}
+
+		// Ensure the default values satisfy the input types and the constraints set by the doc tags, i.e. pattern, minimum, etc.
+		if err := validateDefaultValues(cmp.Inputs, ""); err != nil {
+			return eris.Wrapf(err, "platform component '%s' has default values that do not satisfy its input schema", cmp.ID)
+		}
+	}
+	return nil
+}
+
+// validateDefaultValues validates the default value of the schema and of each of its properties and items
+// against their own schema. The inputs without a default value are skipped, i.e. the required inputs.
+func validateDefaultValues(node *jsonschema.Node, path string) error {
+	if node.Default != nil {
+		if err := node.Validate(node.Default); err != nil {
+			return eris.Wrapf(err, "input '%s'", path)
+		}
+	}
+
+	names := maps.Keys(node.Properties)
+	sort.Strings(names)
+	for _, name := range names {
+		propPath := name
+		if path != "" {
+			propPath = path + "." + name
+		}
+
+		if err := validateDefaultValues(node.Properties[name], propPath); err != nil {
+			return err
+		}
 	}
+
+	if node.Items != nil {
+		return validateDefaultValues(node.Items, path+"[*]")
+	}
+
 	return nil
 }
 
@@ -4,12 +4,15 @@
 package lint
 
 import (
+	"os"
 	"testing"
 
 	"github.com/cldcvr/terraform-config-inspect/tfconfig"
+	"github.com/cldcvr/terrarium/src/pkg/metadata/platform"
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hclsyntax"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/zclconf/go-cty/cty"
 )
 
@@ -29,6 +32,55 @@ func Test_lintPlatform(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "invalid platform - invalid output type",
+			args: args{
+				dir: "testdata/invalid-output-type",
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid platform - default does not match the pattern",
+			args: args{
+				dir: "testdata/invalid-input-defaults",
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid platform - default does not match the input type",
+			args: args{
+				dir: "testdata/invalid-input-type",
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid platform - input doc tags",
+			args: args{
+				dir: "testdata/valid-input-tags",
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid platform - invalid input doc tag",
+			args: args{
+				dir: "testdata/invalid-input-tags",
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid platform - blocks require each other in a cycle",
+			args: args{
+				dir: "testdata/invalid-graph-cycle",
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid platform - invalid configuration profiles",
+			args: args{
+				dir: "testdata/invalid-profiles",
+			},
+			wantErr: true,
+		},
 		{
 			name: "valid platform",
 			args: args{
@@ -36,10 +88,17 @@ func Test_lintPlatform(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "valid platform - profile extending another profile",
+			args: args{
+				dir: "testdata/valid-profiles",
+			},
+			wantErr: false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := lintPlatform(tt.args.dir)
+			err := lintPlatform(tt.args.dir, nil)
 			if tt.wantErr {
 				assert.Error(t, err)
 			} else {
@@ -49,6 +108,25 @@ func Test_lintPlatform(t *testing.T) {
 	}
 }
 
+func Test_lintPlatform_inputTags(t *testing.T) {
+	require.NoError(t, lintPlatform("testdata/valid-input-tags", nil))
+
+	content, err := os.ReadFile("testdata/valid-input-tags/terrarium.yaml")
+	require.NoError(t, err)
+
+	pm := platform.PlatformMetadata{}
+	require.NoError(t, pm.FromFileBytes(content))
+
+	inputs := pm.Components.GetByID("postgres").Inputs
+	assert.Equal(t, []string{"subnet_id"}, inputs.Required)
+	assert.Equal(t, int32(0), *inputs.Properties["db_name"].MinLength, "zero bound is kept")
+	assert.Equal(t, int32(63), *inputs.Properties["db_name"].MaxLength)
+	assert.Equal(t, 0.0, *inputs.Properties["cpu_share"].ExclusiveMinimum, "zero bound is kept")
+	assert.Equal(t, 0.5, *inputs.Properties["cpu_share"].Maximum, "fractional bound is kept")
+	assert.Nil(t, inputs.Properties["cpu_share"].Minimum)
+	assert.Equal(t, 20.0, *inputs.Properties["storage"].Minimum)
+}
+
 func Test_validatePlatformTerraform(t *testing.T) {
 	mockStringExpr := hcl.StaticExpr(cty.StringVal("Human"), hcl.Range{
 		Filename: "quality_kroon_generate.twds",
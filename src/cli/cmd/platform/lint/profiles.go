@@ -0,0 +1,111 @@
+// Copyright (c) Ollion
+// SPDX-License-Identifier: Apache-2.0
+
+package lint
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cldcvr/terraform-config-inspect/tfconfig"
+	"github.com/cldcvr/terrarium/src/pkg/metadata/platform"
+	"github.com/cldcvr/terrarium/src/pkg/tf/parser"
+	"github.com/rotisserie/eris"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+	"golang.org/x/exp/maps"
+)
+
+// validatePlatformProfiles checks that each configuration profile, merged with the profiles it extends,
+// only sets the variables declared by the platform with values of their type. The profiles that no other profile
+// extends must set every required variable, while the base profiles extended by others may leave them to their children.
+func validatePlatformProfiles(module *tfconfig.Module) error {
+	profiles := platform.Profiles{}
+	profiles.Parse(module)
+
+	extended := map[string]struct{}{}
+	for _, p := range profiles {
+		if p.Extends != "" {
+			extended[p.Extends] = struct{}{}
+		}
+	}
+
+	requiredNames := []string{}
+	for name, v := range module.Variables {
+		if v.Required {
+			requiredNames = append(requiredNames, name)
+		}
+	}
+	sort.Strings(requiredNames)
+
+	msgs := []string{}
+	for _, p := range profiles {
+		lineage, err := profiles.GetLineage(p.ID)
+		if err != nil {
+			msgs = append(msgs, err.Error())
+			continue
+		}
+
+		files := make([]string, 0, len(lineage))
+		for _, id := range lineage {
+			files = append(files, platform.ProfileFilePath(module.Path, id))
+		}
+
+		values, err := platform.LoadProfileValues(files...)
+		if err != nil {
+			msgs = append(msgs, fmt.Sprintf("profile '%s': %s", p.ID, err))
+			continue
+		}
+
+		names := maps.Keys(values)
+		sort.Strings(names)
+		for _, name := range names {
+			v, ok := module.Variables[name]
+			if !ok {
+				msgs = append(msgs, fmt.Sprintf("profile '%s' sets the undeclared variable '%s'", p.ID, name))
+				continue
+			}
+
+			if err := checkVariableValue(v.Type, values[name]); err != nil {
+				msgs = append(msgs, fmt.Sprintf("profile '%s' sets variable '%s' to a value that does not match its type '%s': %s", p.ID, name, strings.Join(strings.Fields(v.Type), " "), err))
+			}
+		}
+
+		if _, ok := extended[p.ID]; ok {
+			continue
+		}
+
+		for _, name := range requiredNames {
+			if _, ok := values[name]; !ok {
+				msgs = append(msgs, fmt.Sprintf("profile '%s' does not set the required variable '%s'", p.ID, name))
+			}
+		}
+	}
+
+	if len(msgs) > 0 {
+		return eris.Errorf("platform profiles are invalid:\n  %s", strings.Join(msgs, "\n  "))
+	}
+
+	return nil
+}
+
+// checkVariableValue returns an error if the value can not be converted to the variable type, the same way terraform converts it.
+// The variables declared without a type accept any value.
+func checkVariableValue(typeStr string, val cty.Value) error {
+	if typeStr == "" {
+		return nil
+	}
+
+	ty, defaults, err := parser.ParseType(typeStr)
+	if err != nil {
+		return err
+	}
+
+	if defaults != nil {
+		val = defaults.Apply(val)
+	}
+
+	_, err = convert.Convert(val, ty)
+	return err
+}
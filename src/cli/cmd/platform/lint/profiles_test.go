@@ -0,0 +1,65 @@
+// Copyright (c) Ollion
+// SPDX-License-Identifier: Apache-2.0
+
+package lint
+
+import (
+	"testing"
+
+	"github.com/cldcvr/terraform-config-inspect/tfconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_validatePlatformProfiles(t *testing.T) {
+	tests := []struct {
+		name        string
+		dir         string
+		wantErrs    []string
+		notWantErrs []string
+	}{
+		{
+			name: "valid profiles",
+			dir:  "testdata/valid-profiles",
+		},
+		{
+			name: "platform without profiles",
+			dir:  "testdata/invalid-graph-cycle",
+		},
+		{
+			name: "invalid profiles",
+			dir:  "testdata/invalid-profiles",
+			wantErrs: []string{
+				"profile 'dev' does not set the required variable 'environment'",
+				"profile 'prod' sets the undeclared variable 'db_port'",
+				"profile 'prod' sets variable 'db_storage' to a value that does not match its type 'object({ size = number multi_az = optional(bool, false) })': a number is required",
+				"profile 'staging' extends the undefined profile 'qa'",
+			},
+			// base is extended by dev and prod, hence the required variables are left to them.
+			notWantErrs: []string{
+				"profile 'base' does not set the required variable 'environment'",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module, diags := tfconfig.LoadModule(tt.dir, &tfconfig.ResolvedModulesSchema{})
+			require.False(t, diags.HasErrors(), diags.Error())
+
+			err := validatePlatformProfiles(module)
+			if len(tt.wantErrs) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.ErrorContains(t, err, "platform profiles are invalid:")
+			for _, wantErr := range tt.wantErrs {
+				assert.ErrorContains(t, err, wantErr)
+			}
+			for _, notWantErr := range tt.notWantErrs {
+				assert.NotContains(t, err.Error(), notWantErr)
+			}
+		})
+	}
+}
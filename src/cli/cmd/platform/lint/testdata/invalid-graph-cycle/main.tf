@@ -0,0 +1,21 @@
+locals {
+  tr_component_postgres = {
+    default = {
+      db_name = "default"
+    }
+  }
+
+  db_prefix = "${local.db_suffix}-db" # <------ ERROR: locals refer to each other
+  db_suffix = "${local.db_prefix}-suffix"
+}
+
+module "tr_component_postgres" {
+  source = "terraform-aws-modules/rds/aws"
+
+  for_each   = local.tr_component_postgres
+  identifier = "${local.db_prefix}-${each.key}"
+}
+
+output "tr_component_postgres_host" {
+  value = { for k, v in module.tr_component_postgres : k => v.db_instance_address }
+}
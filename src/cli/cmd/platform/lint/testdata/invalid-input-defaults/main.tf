@@ -0,0 +1,14 @@
+locals {
+  tr_component_postgres = {
+    default = {
+      # @pattern: ^[a-z][a-z0-9_]*$
+      db_name = "Default-DB" # <------ ERROR: default value does not match the pattern
+    }
+  }
+}
+
+module "tr_component_postgres" {
+  source = "terraform-aws-modules/rds/aws"
+
+  for_each = local.tr_component_postgres
+}
@@ -0,0 +1,14 @@
+locals {
+  tr_component_postgres = {
+    default = {
+      # @minimum: twenty
+      storage = 20 # <------ ERROR: minimum is not a number
+    }
+  }
+}
+
+module "tr_component_postgres" {
+  source = "terraform-aws-modules/rds/aws"
+
+  for_each = local.tr_component_postgres
+}
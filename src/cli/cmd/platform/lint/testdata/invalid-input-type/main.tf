@@ -0,0 +1,22 @@
+variable "postgres_inputs" {
+  type = object({
+    db_name = string
+    port    = optional(number, 5432)
+  })
+}
+
+locals {
+  # @type: var.postgres_inputs
+  tr_component_postgres = {
+    default = {
+      db_name = "default"
+      port    = "postgres" # <------ ERROR: default value is not a number
+    }
+  }
+}
+
+module "tr_component_postgres" {
+  source = "terraform-aws-modules/rds/aws"
+
+  for_each = local.tr_component_postgres
+}
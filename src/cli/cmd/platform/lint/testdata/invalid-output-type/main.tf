@@ -0,0 +1,16 @@
+locals {
+  tr_component_postgres = {
+    default = {}
+  }
+}
+
+module "tr_component_postgres" {
+  source = "terraform-aws-modules/rds/aws"
+
+  for_each = local.tr_component_postgres
+}
+
+# @type: list(strng)
+output "tr_component_postgres_host" {
+  value = { for k, v in module.tr_component_postgres : k => v.db_instance_address }
+}
@@ -0,0 +1,18 @@
+variable "environment" {
+  type = string
+}
+
+variable "db_instance_class" {
+  type    = string
+  default = "db.t3.micro"
+}
+
+variable "db_storage" {
+  type = object({
+    size     = number
+    multi_az = optional(bool, false)
+  })
+  default = {
+    size = 20
+  }
+}
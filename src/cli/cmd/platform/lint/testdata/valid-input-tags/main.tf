@@ -0,0 +1,30 @@
+locals {
+  tr_component_postgres = {
+    default = {
+      # @title: Database Name
+      # @pattern: ^[a-z][a-z0-9_]*$
+      # @minLength: 0
+      # @maxLength: 63
+      # @examples: orders, users
+      db_name = "default_db"
+      # @title: Storage Size
+      # @description: Allocated storage in gigabytes
+      # @minimum: 20
+      # @maximum: 1024
+      storage = 20
+      # @exclusiveMinimum: 0
+      # @maximum: 0.5
+      cpu_share = 0.25
+      # @required: true
+      subnet_id = "subnet-default"
+      # @deprecated: true
+      multi_az = false
+    }
+  }
+}
+
+module "tr_component_postgres" {
+  source = "terraform-aws-modules/rds/aws"
+
+  for_each = local.tr_component_postgres
+}
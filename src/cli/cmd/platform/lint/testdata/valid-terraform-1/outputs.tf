@@ -9,3 +9,8 @@ output "tr_component_postgres_host" {
 output "tr_component_postgres_port" {
   value = { for k, v in module.tr_component_postgres : k => v.db_instance_port }
 }
+
+# @type: list(string)
+output "tr_component_postgres_read_hosts" {
+  value = { for k, v in module.tr_component_postgres : k => v.db_instance_read_hosts }
+}
@@ -0,0 +1,79 @@
+// Copyright (c) Ollion
+// SPDX-License-Identifier: Apache-2.0
+
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/cldcvr/terraform-config-inspect/tfconfig"
+	"github.com/cldcvr/terrarium/src/cli/internal/constants"
+	"github.com/cldcvr/terrarium/src/pkg/metadata/platform"
+	"github.com/rotisserie/eris"
+	"github.com/spf13/cobra"
+)
+
+const (
+	stdoutFileName      = "-"
+	defaultYAMLFileName = "terrarium.yaml"
+)
+
+var (
+	cmd *cobra.Command
+
+	flagDir     string
+	flagOutFile string
+)
+
+func NewCmd() *cobra.Command {
+	cmd = &cobra.Command{
+		Use:   "schema",
+		Short: "Export the JSON schema of the app manifests",
+		Long: `Export the JSON schema of the app manifests (terrarium.yaml) that can be generated with the platform.
+The dependencies are limited to the ones implemented by the platform, and their inputs and outputs are validated against the implementing components.
+Editors supporting the yaml-language-server use the schema for completion and validation, i.e. by adding the comment
+'# yaml-language-server: $schema=<path-to-schema>' at the top of the app manifest.`,
+		RunE: cmdRunE,
+	}
+
+	cmd.Flags().StringVarP(&flagDir, "dir", "d", ".", "path to the platform directory")
+	cmd.Flags().StringVarP(&flagOutFile, "output", "o", stdoutFileName, "path to the file to write the schema to. writes to stdout when set to '-'")
+
+	return cmd
+}
+
+func cmdRunE(cmd *cobra.Command, args []string) error {
+	if _, err := os.Stat(flagDir); os.IsNotExist(err) {
+		return eris.Wrapf(err, "could not open given directory '%s'", flagDir)
+	}
+
+	m, _ := tfconfig.LoadModule(flagDir, &tfconfig.ResolvedModulesSchema{})
+
+	existingYaml, _ := os.ReadFile(filepath.Join(flagDir, defaultYAMLFileName))
+
+	pm, err := platform.NewPlatformMetadata(m, existingYaml)
+	if err != nil {
+		return eris.Wrap(err, "error parsing platform metadata")
+	}
+
+	content, err := json.MarshalIndent(pm.AppSchema(), "", "  ")
+	if err != nil {
+		return eris.Wrap(err, "failed to serialize the app manifest schema")
+	}
+	content = append(content, '\n')
+
+	if flagOutFile == stdoutFileName {
+		_, err := cmd.OutOrStdout().Write(content)
+		return err
+	}
+
+	if err := os.WriteFile(flagOutFile, content, constants.ReadWritePermissions); err != nil {
+		return eris.Wrapf(err, "failed to write file: %s", flagOutFile)
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "Successfully exported the app manifest schema of %d components to: %s\n", len(pm.Components), flagOutFile)
+	return nil
+}
@@ -0,0 +1,75 @@
+// Copyright (c) Ollion
+// SPDX-License-Identifier: Apache-2.0
+
+package schema
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cldcvr/terrarium/src/pkg/jsonschema"
+	"github.com/cldcvr/terrarium/src/pkg/metadata/platform"
+	"github.com/cldcvr/terrarium/src/pkg/testutils/clitesting"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCmd(t *testing.T) {
+	outFile := filepath.Join(t.TempDir(), "app.schema.json")
+
+	validateSchema := func(t *testing.T, content []byte) bool {
+		var schema jsonschema.Node
+		if !assert.NoError(t, json.Unmarshal(content, &schema)) {
+			return false
+		}
+
+		use := schema.Properties["dependencies"].Items.Properties["use"]
+		pass := assert.Equal(t, platform.AppSchemaDraft, schema.Schema)
+		pass = assert.Equal(t, []interface{}{"postgres"}, use.AnyOf[0].Enum) && pass
+		pass = assert.NoError(t, schema.Validate(map[string]interface{}{
+			"id":           "banking_app",
+			"dependencies": []interface{}{map[string]interface{}{"use": "postgres", "outputs": map[string]interface{}{"PG_HOST": "{{host}}"}}},
+		})) && pass
+		pass = assert.ErrorContains(t, schema.Validate(map[string]interface{}{
+			"id":           "banking_app",
+			"dependencies": []interface{}{map[string]interface{}{"use": "postgres", "outputs": map[string]interface{}{"PG_PORT": "{{port}}"}}},
+		}), "dependencies.0.outputs.PG_PORT: Does not match pattern") && pass
+		return pass
+	}
+
+	clitest := clitesting.CLITest{
+		CmdToTest: NewCmd,
+	}
+
+	clitest.RunTests(t, []clitesting.CLITestCase{
+		{
+			Name:           "render help",
+			Args:           []string{"-h"},
+			ValidateOutput: clitesting.ValidateOutputContains("Export the JSON schema of the app manifests (terrarium.yaml) that can be generated with the platform"),
+		},
+		{
+			Name: "stdout",
+			Args: []string{"-d", "testdata/platform"},
+			ValidateOutput: func(ctx context.Context, t *testing.T, cmdOpts clitesting.CmdOpts, output []byte) bool {
+				return validateSchema(t, output)
+			},
+		},
+		{
+			Name: "to file",
+			Args: []string{"-d", "testdata/platform", "-o", outFile},
+			ValidateOutput: func(ctx context.Context, t *testing.T, cmdOpts clitesting.CmdOpts, output []byte) bool {
+				pass := assert.Equal(t, "Successfully exported the app manifest schema of 1 components to: "+outFile+"\n", string(output))
+				content, err := os.ReadFile(outFile)
+				return assert.NoError(t, err) && validateSchema(t, content) && pass
+			},
+		},
+		{
+			Name:     "invalid directory",
+			Args:     []string{"-d", "testdata/invalid-path"},
+			WantErr:  true,
+			ExpError: "could not open given directory",
+		},
+	})
+}
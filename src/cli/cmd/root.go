@@ -8,6 +8,7 @@ import (
 	"os"
 
 	"github.com/charmbracelet/log"
+	"github.com/cldcvr/terrarium/src/cli/cmd/env"
 	"github.com/cldcvr/terrarium/src/cli/cmd/farm"
 	"github.com/cldcvr/terrarium/src/cli/cmd/generate"
 	"github.com/cldcvr/terrarium/src/cli/cmd/harvest"
@@ -43,6 +44,7 @@ func newCmd() *cobra.Command {
 	rootCmd.AddCommand(harvest.NewCmd())
 	rootCmd.AddCommand(platform.NewCmd())
 	rootCmd.AddCommand(generate.NewCmd())
+	rootCmd.AddCommand(env.NewCmd())
 	rootCmd.AddCommand(version.NewCmd())
 	rootCmd.AddCommand(query.NewCmd())
 	rootCmd.AddCommand(farm.NewCmd())
@@ -0,0 +1,11 @@
+// Copyright (c) Ollion
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import "github.com/cldcvr/terrarium/src/pkg/confighelper"
+
+// PlatformCacheDir directory to cache the remote platform templates in
+func PlatformCacheDir() string {
+	return confighelper.MustGetString("platform.cache_dir")
+}
@@ -8,6 +8,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
 	"strings"
 
 	"github.com/cldcvr/terrarium/src/pkg/pb/terrariumpb"
@@ -16,29 +17,41 @@ import (
 	"google.golang.org/protobuf/types/known/structpb"
 )
 
+// Node is a JSON schema.
+// The numeric constraints are pointers, so that a zero bound is kept and told apart from an unset one,
+// and the bounds on numbers are floats, so that fractional bounds are kept.
 type Node struct {
-	Title            string           `yaml:"title,omitempty" json:"title,omitempty"`
-	Description      string           `yaml:"description,omitempty" json:"description,omitempty"`
-	Type             string           `yaml:"type,omitempty" json:"type,omitempty"` // string, number, integer, boolean, object, array, null
-	Default          interface{}      `yaml:"default,omitempty" json:"default,omitempty"`
-	Examples         []interface{}    `yaml:"examples,omitempty" json:"examples,omitempty"`
-	Enum             []interface{}    `yaml:"enum,omitempty" json:"enum,omitempty"`
-	MinLength        int32            `yaml:"minLength,omitempty" json:"minLength,omitempty"`
-	MaxLength        int32            `yaml:"maxLength,omitempty" json:"maxLength,omitempty"`
-	Pattern          string           `yaml:"pattern,omitempty" json:"pattern,omitempty"`
-	Format           string           `yaml:"format,omitempty" json:"format,omitempty"`
-	Minimum          int32            `yaml:"minimum,omitempty" json:"minimum,omitempty"`
-	Maximum          int32            `yaml:"maximum,omitempty" json:"maximum,omitempty"`
-	ExclusiveMinimum int32            `yaml:"exclusiveMinimum,omitempty" json:"exclusiveMinimum,omitempty"`
-	ExclusiveMaximum int32            `yaml:"exclusiveMaximum,omitempty" json:"exclusiveMaximum,omitempty"`
-	MultipleOf       int32            `yaml:"multipleOf,omitempty" json:"multipleOf,omitempty"`
-	Items            *Node            `yaml:"items,omitempty" json:"items,omitempty"`
-	AdditionalItems  bool             `yaml:"additionalItems,omitempty" json:"additionalItems,omitempty"`
-	MinItems         int32            `yaml:"minItems,omitempty" json:"minItems,omitempty"`
-	MaxItems         int32            `yaml:"maxItems,omitempty" json:"maxItems,omitempty"`
-	UniqueItems      bool             `yaml:"uniqueItems,omitempty" json:"uniqueItems,omitempty"`
-	Properties       map[string]*Node `yaml:"properties,omitempty" json:"properties,omitempty"`
-	Required         []string         `yaml:"required,omitempty" json:"required,omitempty"`
+	Schema               string           `yaml:"$schema,omitempty" json:"$schema,omitempty"` // URI of the JSON schema draft, set on the root node of a standalone schema
+	Title                string           `yaml:"title,omitempty" json:"title,omitempty"`
+	Description          string           `yaml:"description,omitempty" json:"description,omitempty"`
+	Type                 string           `yaml:"type,omitempty" json:"type,omitempty"` // string, number, integer, boolean, object, array, null
+	Default              interface{}      `yaml:"default,omitempty" json:"default,omitempty"`
+	Examples             []interface{}    `yaml:"examples,omitempty" json:"examples,omitempty"`
+	Enum                 []interface{}    `yaml:"enum,omitempty" json:"enum,omitempty"`
+	MinLength            *int32           `yaml:"minLength,omitempty" json:"minLength,omitempty"`
+	MaxLength            *int32           `yaml:"maxLength,omitempty" json:"maxLength,omitempty"`
+	Pattern              string           `yaml:"pattern,omitempty" json:"pattern,omitempty"`
+	Format               string           `yaml:"format,omitempty" json:"format,omitempty"`
+	Minimum              *float64         `yaml:"minimum,omitempty" json:"minimum,omitempty"`
+	Maximum              *float64         `yaml:"maximum,omitempty" json:"maximum,omitempty"`
+	ExclusiveMinimum     *float64         `yaml:"exclusiveMinimum,omitempty" json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum     *float64         `yaml:"exclusiveMaximum,omitempty" json:"exclusiveMaximum,omitempty"`
+	MultipleOf           *float64         `yaml:"multipleOf,omitempty" json:"multipleOf,omitempty"`
+	Items                *Node            `yaml:"items,omitempty" json:"items,omitempty"`
+	AdditionalItems      bool             `yaml:"additionalItems,omitempty" json:"additionalItems,omitempty"`
+	MinItems             *int32           `yaml:"minItems,omitempty" json:"minItems,omitempty"`
+	MaxItems             *int32           `yaml:"maxItems,omitempty" json:"maxItems,omitempty"`
+	UniqueItems          bool             `yaml:"uniqueItems,omitempty" json:"uniqueItems,omitempty"`
+	Properties           map[string]*Node `yaml:"properties,omitempty" json:"properties,omitempty"`
+	Required             []string         `yaml:"required,omitempty" json:"required,omitempty"`
+	AdditionalProperties *Node            `yaml:"additionalProperties,omitempty" json:"additionalProperties,omitempty"` // Schema of the values of the properties not listed in Properties
+	Const                interface{}      `yaml:"const,omitempty" json:"const,omitempty"`
+	AnyOf                []*Node          `yaml:"anyOf,omitempty" json:"anyOf,omitempty"`
+	AllOf                []*Node          `yaml:"allOf,omitempty" json:"allOf,omitempty"`
+	If                   *Node            `yaml:"if,omitempty" json:"if,omitempty"`
+	Then                 *Node            `yaml:"then,omitempty" json:"then,omitempty"`
+	Sensitive            bool             `yaml:"sensitive,omitempty" json:"sensitive,omitempty"`   // Set for values that must not be exposed, such as passwords (not a standard JSON schema keyword)
+	Deprecated           bool             `yaml:"deprecated,omitempty" json:"deprecated,omitempty"` // Set for values that are kept for compatibility and should no longer be used
 
 	compiled *gojsonschema.Schema
 }
@@ -97,6 +110,19 @@ func (n *Node) ApplyDefaultsToArr(inp []interface{}) {
 	}
 }
 
+// IsTypeCompatible returns true if the values of the given type are accepted by a node of the accepting type.
+// An unset type matches any type, and the 'number' type accepts integers.
+func IsTypeCompatible(valueType, acceptingType string) bool {
+	switch {
+	case valueType == "" || acceptingType == "" || valueType == acceptingType:
+		return true
+	case valueType == "integer" && acceptingType == "number":
+		return true
+	}
+
+	return false
+}
+
 func (n *Node) compileIfNot() error {
 	if n.compiled == nil {
 		return n.Compile()
@@ -159,6 +185,8 @@ func (n *Node) ToProto() *terrariumpb.JSONSchema {
 		}
 	}
 
+	n.constraintsToProto(protoSchema)
+
 	if n.Default != nil {
 		switch defaultValue := n.Default.(type) {
 		case float64:
@@ -186,3 +214,53 @@ func (n *Node) ToProto() *terrariumpb.JSONSchema {
 	}
 	return protoSchema
 }
+
+// constraintsToProto sets the constraints of the node on the protoSchema.
+// The proto follows draft 4 of the JSON schema, hence an exclusive bound is set as the bound with the exclusive flag,
+// and the bounds on numbers are integers, hence the fractional bounds and multiples are not set.
+func (n *Node) constraintsToProto(protoSchema *terrariumpb.JSONSchema) {
+	protoSchema.Pattern = n.Pattern
+	protoSchema.Format = n.Format
+	protoSchema.MinLength = int32Value(n.MinLength)
+	protoSchema.MaxLength = int32Value(n.MaxLength)
+	protoSchema.MinItems = int32Value(n.MinItems)
+	protoSchema.MaxItems = int32Value(n.MaxItems)
+	protoSchema.UniqueItems = n.UniqueItems
+	protoSchema.AdditionalItems = n.AdditionalItems
+	protoSchema.Required = n.Required
+	protoSchema.Items = n.Items.ToProto()
+
+	if v, ok := wholeInt32(n.Minimum); ok {
+		protoSchema.Minimum = v
+	}
+	// the exclusive bound is only set if it is tighter than the inclusive one.
+	if v, ok := wholeInt32(n.ExclusiveMinimum); ok && (n.Minimum == nil || *n.ExclusiveMinimum >= *n.Minimum) {
+		protoSchema.Minimum, protoSchema.ExclusiveMinimum = v, true
+	}
+
+	if v, ok := wholeInt32(n.Maximum); ok {
+		protoSchema.Maximum = v
+	}
+	if v, ok := wholeInt32(n.ExclusiveMaximum); ok && (n.Maximum == nil || *n.ExclusiveMaximum <= *n.Maximum) {
+		protoSchema.Maximum, protoSchema.ExclusiveMaximum = v, true
+	}
+
+	if v, ok := wholeInt32(n.MultipleOf); ok {
+		protoSchema.MultipleOf = v
+	}
+}
+
+func int32Value(v *int32) int32 {
+	if v == nil {
+		return 0
+	}
+	return *v
+}
+
+// wholeInt32 returns the value as int32, or false if it is not set or not a whole number in the int32 range.
+func wholeInt32(v *float64) (int32, bool) {
+	if v == nil || *v != math.Trunc(*v) || *v < math.MinInt32 || *v > math.MaxInt32 {
+		return 0, false
+	}
+	return int32(*v), true
+}
@@ -370,6 +370,68 @@ func TestNode_ToProto(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "JSONSchema with constraints",
+			jsn: &Node{
+				Type: "object",
+				Properties: map[string]*Node{
+					"name": {
+						Type:      "string",
+						Pattern:   "^[a-z]+$",
+						MinLength: int32Ptr(0),
+						MaxLength: int32Ptr(63),
+					},
+					"size": {
+						Type:             "integer",
+						Minimum:          float64Ptr(1),
+						ExclusiveMinimum: float64Ptr(2),
+						ExclusiveMaximum: float64Ptr(100),
+						MultipleOf:       float64Ptr(2),
+					},
+					"share": {
+						Type:       "number",
+						Minimum:    float64Ptr(0),
+						Maximum:    float64Ptr(0.5),
+						MultipleOf: float64Ptr(0.1),
+					},
+					"tags": {
+						Type:     "array",
+						Items:    &Node{Type: "string", MaxLength: int32Ptr(8)},
+						MinItems: int32Ptr(1),
+						MaxItems: int32Ptr(5),
+					},
+				},
+				Required: []string{"name"},
+			},
+			validator: func(t *testing.T, result *terrariumpb.JSONSchema) {
+				require.NotNil(t, result)
+				assert.Equal(t, []string{"name"}, result.Required)
+
+				name := result.Properties["name"]
+				assert.Equal(t, "^[a-z]+$", name.Pattern)
+				assert.Equal(t, int32(0), name.MinLength)
+				assert.Equal(t, int32(63), name.MaxLength)
+
+				size := result.Properties["size"]
+				assert.Equal(t, int32(2), size.Minimum, "the tighter exclusive bound is set")
+				assert.True(t, size.ExclusiveMinimum)
+				assert.Equal(t, int32(100), size.Maximum)
+				assert.True(t, size.ExclusiveMaximum)
+				assert.Equal(t, int32(2), size.MultipleOf)
+
+				share := result.Properties["share"]
+				assert.Equal(t, int32(0), share.Minimum)
+				assert.Equal(t, int32(0), share.Maximum, "fractional bound is not set")
+				assert.Equal(t, int32(0), share.MultipleOf, "fractional multiple is not set")
+				assert.False(t, share.ExclusiveMaximum)
+
+				tags := result.Properties["tags"]
+				assert.Equal(t, int32(1), tags.MinItems)
+				assert.Equal(t, int32(5), tags.MaxItems)
+				require.NotNil(t, tags.Items)
+				assert.Equal(t, int32(8), tags.Items.MaxLength)
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -379,3 +441,11 @@ func TestNode_ToProto(t *testing.T) {
 		})
 	}
 }
+
+func int32Ptr(v int32) *int32 {
+	return &v
+}
+
+func float64Ptr(v float64) *float64 {
+	return &v
+}
@@ -172,6 +172,25 @@ type Dependency struct {
 	// If true, this dependency is shared and its inputs are set in another app
 	// and its outputs are made available here.
 	NoProvision bool `yaml:"no_provision"`
+
+	// App is the ID of another app whose compute is used as this dependency, e.g. a backend used by a frontend.
+	// The dependency ID and Use default to the app ID and its compute interface,
+	// and the dependency is not provisioned in this app. The ID can not be set to another value.
+	App string `yaml:"app,omitempty"`
+
+	// Implementation is the ID of the platform component selected to provision the dependency,
+	// i.e. "postgres__v11" for "postgres@11". It is set while matching the app with the platform,
+	// and is empty when the dependency is provisioned by the component with the same ID as Use.
+	Implementation string `yaml:"-"`
+}
+
+// ComponentID returns the ID of the platform component that provisions the dependency.
+func (e Dependency) ComponentID() string {
+	if e.Implementation != "" {
+		return e.Implementation
+	}
+
+	return e.Use
 }
 
 func (e Dependency) ProtoValue() (*terrariumpb.AppDependency, error) {
@@ -204,6 +223,7 @@ func (e *Dependency) ScanProto(m *terrariumpb.AppDependency) {
 func (e Dependency) IsEquivalent(other Dependency) bool {
 	return e.ID == other.ID &&
 		e.Use == other.Use &&
+		e.ComponentID() == other.ComponentID() &&
 		e.NoProvision == other.NoProvision &&
 		isEquivalent(e.Inputs, other.Inputs)
 }
@@ -7,11 +7,16 @@ import (
 	"strings"
 
 	"github.com/rotisserie/eris"
+	"golang.org/x/exp/slices"
 )
 
 // Validate ensures that each application and its dependencies have unique IDs within the scope of all applications.
 // It also checks that shared dependencies are provisioned.
 func (apps Apps) Validate() error {
+	if err := apps.validateAppDependencies(); err != nil {
+		return err
+	}
+
 	seenAppIDs := make(map[string]struct{})   // Tracks observed application IDs for uniqueness.
 	seenDepIDs := make(map[string]struct{})   // Tracks observed dependency IDs for uniqueness.
 	sharedDepIDs := make(map[string]struct{}) // Tracks IDs of dependencies marked as shared.
@@ -30,6 +35,42 @@ func (apps Apps) Validate() error {
 		}
 	}
 
+	_, err := apps.SortByDependencies()
+	return err
+}
+
+// validateAppDependencies ensures that the apps used as dependencies exist and have a compute.
+func (apps Apps) validateAppDependencies() error {
+	for _, a := range apps {
+		for _, dep := range a.Dependencies {
+			if dep.App == "" {
+				continue
+			}
+
+			if dep.App == a.ID {
+				return eris.Errorf("dependency '%s' of app '%s' can not refer to the app itself", dep.ID, a.ID)
+			}
+
+			other := apps.GetAppByID(dep.App)
+			if other == nil {
+				return eris.Errorf("dependency '%s' of app '%s' refers to the app '%s' that is not found", dep.ID, a.ID, dep.App)
+			}
+
+			if other.Compute.Use == "" {
+				return eris.Errorf("dependency '%s' of app '%s' refers to the app '%s' that does not have a compute", dep.ID, a.ID, dep.App)
+			}
+
+			if dep.Use != other.Compute.Use {
+				return eris.Errorf("dependency '%s' of app '%s' uses '%s', but the compute of the app '%s' is '%s'", dep.ID, a.ID, dep.Use, dep.App, other.Compute.Use)
+			}
+
+			// the outputs of the dependency are looked up by the ID of the compute it refers to.
+			if dep.ID != other.Compute.ID {
+				return eris.Errorf("dependency '%s' of app '%s' refers to the app '%s', hence its id must be '%s' or omitted", dep.ID, a.ID, dep.App, other.Compute.ID)
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -91,10 +132,24 @@ func (dep *Dependency) validate(seenDepIDs, sharedDepIDs map[string]struct{}) er
 }
 
 // Sets the default values for the optional fields in the Apps and its Dependencies.
+// The dependencies on other apps use the compute interface of the app they refer to.
 func (apps *Apps) SetDefaults() {
 	for i := range *apps {
 		(*apps)[i].SetDefaults()
 	}
+
+	for _, a := range *apps {
+		for i := range a.Dependencies {
+			dep := &a.Dependencies[i]
+			if dep.App == "" || dep.Use != "" {
+				continue
+			}
+
+			if other := apps.GetAppByID(dep.App); other != nil {
+				dep.Use = other.Compute.Use
+			}
+		}
+	}
 }
 
 // Sets the default values for the optional fields in the App and its Dependencies.
@@ -124,6 +179,13 @@ func (dep *Dependency) SetDefaults() {
 		dep.Use, dep.Inputs["version"] = split[0], split[1]
 	}
 
+	if dep.App != "" {
+		dep.NoProvision = true
+		if dep.ID == "" {
+			dep.ID = dep.App
+		}
+	}
+
 	if dep.ID == "" {
 		dep.ID = dep.Use
 	}
@@ -177,12 +239,12 @@ func (apps Apps) GetDependenciesByAppID(appID string) Dependencies {
 	return nil
 }
 
-// GetDependenciesByType returns all dependencies of a given type across all apps.
+// GetDependenciesByType returns all dependencies provisioned by the given platform component across all apps.
 func (apps Apps) GetDependenciesByType(depType string) Dependencies {
 	var deps Dependencies
 	for _, app := range apps {
 		for _, dep := range app.GetDependencies() {
-			if dep.Use == depType && !dep.NoProvision {
+			if dep.ComponentID() == depType && !dep.NoProvision {
 				deps = append(deps, dep)
 			}
 		}
@@ -191,12 +253,12 @@ func (apps Apps) GetDependenciesByType(depType string) Dependencies {
 	return deps
 }
 
-// GetUniqueDependencyTypes returns a list of unique dependency types across all apps.
+// GetUniqueDependencyTypes returns a list of the unique platform components used by the dependencies across all apps.
 func (apps Apps) GetUniqueDependencyTypes() []string {
 	seenTypes := make(map[string]struct{})
 	for _, app := range apps {
 		for _, dep := range app.GetDependencies() {
-			seenTypes[dep.Use] = struct{}{}
+			seenTypes[dep.ComponentID()] = struct{}{}
 		}
 	}
 
@@ -217,3 +279,66 @@ func (allDeps Dependencies) GetInputs() map[string]interface{} {
 
 	return result
 }
+
+// GetAppDependencyIDs returns the IDs of the other apps whose compute is used by the app, in the given apps.
+// A dependency refers to another app either with the `app` field, or by using the compute
+// of the app with the app ID as the dependency ID and `no_provision` set.
+func (apps Apps) GetAppDependencyIDs(a App) []string {
+	ids := []string{}
+	for _, dep := range a.Dependencies {
+		id := dep.App
+		if id == "" && dep.NoProvision {
+			if other := apps.GetAppByID(dep.ID); other != nil && other.Compute.Use == dep.Use {
+				id = other.ID
+			}
+		}
+
+		if id != "" && id != a.ID && !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids
+}
+
+// SortByDependencies returns the apps ordered such that each app comes after the apps it uses as dependency,
+// keeping the given order otherwise. Returns an error listing the chain of apps if they depend on each other in a cycle.
+func (apps Apps) SortByDependencies() (Apps, error) {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+
+	state := map[string]int{}
+	sorted := make(Apps, 0, len(apps))
+	var visit func(a App, chain []string) error
+	visit = func(a App, chain []string) error {
+		switch state[a.ID] {
+		case visited:
+			return nil
+		case visiting:
+			return eris.Errorf("apps depend on each other in a cycle: %s", strings.Join(append(chain, a.ID), " -> "))
+		}
+
+		state[a.ID] = visiting
+		for _, id := range apps.GetAppDependencyIDs(a) {
+			if other := apps.GetAppByID(id); other != nil {
+				if err := visit(*other, append(chain, a.ID)); err != nil {
+					return err
+				}
+			}
+		}
+		state[a.ID] = visited
+
+		sorted = append(sorted, a)
+		return nil
+	}
+
+	for _, a := range apps {
+		if err := visit(a, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	return sorted, nil
+}
@@ -81,6 +81,43 @@ func TestSetDefaults(t *testing.T) {
 	assert.Equal(t, apps[0].Dependencies[0].Inputs["version"], "11")
 }
 
+func TestSetDefaults_AppDependency(t *testing.T) {
+	apps := getAppsTest()
+	apps[1].Dependencies = append(apps[1].Dependencies, Dependency{App: "testapp1"})
+
+	apps.SetDefaults()
+	assert.Equal(t, Dependency{
+		ID:          "testapp1",
+		Use:         depWeb,
+		EnvPrefix:   "TESTAPP1",
+		Inputs:      map[string]interface{}{},
+		NoProvision: true,
+		App:         "testapp1",
+	}, apps[1].Dependencies[3])
+	assert.NoError(t, apps.Validate())
+}
+
+func TestSortByDependencies(t *testing.T) {
+	apps := Apps{
+		{ID: "fe", Dependencies: Dependencies{{ID: "be", Use: depWeb, App: "be", NoProvision: true}}},
+		{ID: "admin", Dependencies: Dependencies{{ID: "fe", Use: depWeb, NoProvision: true}}},
+		{ID: "be", Compute: Dependency{ID: "be", Use: depWeb}},
+		{ID: "worker"},
+	}
+	apps[0].Compute = Dependency{ID: "fe", Use: depWeb}
+
+	sorted, err := apps.SortByDependencies()
+	assert.NoError(t, err)
+
+	ids := []string{}
+	for _, a := range sorted {
+		ids = append(ids, a.ID)
+	}
+	assert.Equal(t, []string{"be", "fe", "admin", "worker"}, ids)
+	assert.Equal(t, []string{"be"}, apps.GetAppDependencyIDs(apps[0]))
+	assert.Equal(t, []string{"fe"}, apps.GetAppDependencyIDs(apps[1]))
+}
+
 func TestAppsValidate(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -138,6 +175,70 @@ func TestAppsValidate(t *testing.T) {
 			}(),
 			expectError: "shared dependency not provisioned: testdep3",
 		},
+		{
+			name: "App Dependency",
+			apps: func() Apps {
+				apps := getAppsTest()
+				apps[1].Dependencies = append(apps[1].Dependencies, Dependency{ID: "testapp1", Use: depWeb, App: "testapp1", NoProvision: true})
+				return apps
+			}(),
+		},
+		{
+			name: "App Dependency Not Found",
+			apps: func() Apps {
+				apps := getAppsTest()
+				apps[1].Dependencies = append(apps[1].Dependencies, Dependency{ID: "testapp3", Use: depWeb, App: "testapp3", NoProvision: true})
+				return apps
+			}(),
+			expectError: "dependency 'testapp3' of app 'testapp2' refers to the app 'testapp3' that is not found",
+		},
+		{
+			name: "App Dependency On Itself",
+			apps: func() Apps {
+				apps := getAppsTest()
+				apps[1].Dependencies = append(apps[1].Dependencies, Dependency{ID: "self", Use: depWeb, App: "testapp2", NoProvision: true})
+				return apps
+			}(),
+			expectError: "dependency 'self' of app 'testapp2' can not refer to the app itself",
+		},
+		{
+			name: "App Dependency Without Compute",
+			apps: func() Apps {
+				apps := getAppsTest()
+				apps[0].Compute = Dependency{}
+				apps[1].Dependencies = append(apps[1].Dependencies, Dependency{ID: "testapp1", Use: depWeb, App: "testapp1", NoProvision: true})
+				return apps
+			}(),
+			expectError: "dependency 'testapp1' of app 'testapp2' refers to the app 'testapp1' that does not have a compute",
+		},
+		{
+			name: "App Dependency With Another Interface",
+			apps: func() Apps {
+				apps := getAppsTest()
+				apps[1].Dependencies = append(apps[1].Dependencies, Dependency{ID: "testapp1", Use: depRedis, App: "testapp1", NoProvision: true})
+				return apps
+			}(),
+			expectError: "dependency 'testapp1' of app 'testapp2' uses 'redis', but the compute of the app 'testapp1' is 'compute_web'",
+		},
+		{
+			name: "App Dependency With Another ID",
+			apps: func() Apps {
+				apps := getAppsTest()
+				apps[1].Dependencies = append(apps[1].Dependencies, Dependency{ID: "backend", Use: depWeb, App: "testapp1", NoProvision: true})
+				return apps
+			}(),
+			expectError: "dependency 'backend' of app 'testapp2' refers to the app 'testapp1', hence its id must be 'testapp1' or omitted",
+		},
+		{
+			name: "App Dependency Cycle",
+			apps: func() Apps {
+				apps := getAppsTest()
+				apps[0].Dependencies = append(apps[0].Dependencies, Dependency{ID: "testapp2", Use: depWeb, App: "testapp2", NoProvision: true})
+				apps[1].Dependencies = append(apps[1].Dependencies, Dependency{ID: "testapp1", Use: depWeb, App: "testapp1", NoProvision: true})
+				return apps
+			}(),
+			expectError: "apps depend on each other in a cycle: testapp1 -> testapp2 -> testapp1",
+		},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,56 @@
+// Copyright (c) Ollion
+// SPDX-License-Identifier: Apache-2.0
+
+package dependency
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/cldcvr/terrarium/src/pkg/utils"
+	"github.com/rotisserie/eris"
+)
+
+// LoadInterfaces reads the dependency interfaces from the given YAML file,
+// or from all the YAML files in the given directory and its sub-directories.
+func LoadInterfaces(path string) (Interfaces, error) {
+	all := Interfaces{}
+	err := filepath.Walk(path, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() || !utils.IsYaml(info.Name()) {
+			return nil
+		}
+
+		data, err := os.ReadFile(filePath)
+		if err != nil {
+			return eris.Wrapf(err, "error reading file '%s'", filePath)
+		}
+
+		f, err := NewFile(data)
+		if err != nil {
+			return eris.Wrapf(err, "error processing file '%s'", filePath)
+		}
+
+		all = append(all, f.DependencyInterfaces...)
+		return nil
+	})
+	if err != nil {
+		return nil, eris.Wrapf(err, "failed to load dependency interfaces from: %s", path)
+	}
+
+	return all, nil
+}
+
+// GetByID returns the dependency interface with the given ID, or nil if there is none.
+func (iArr Interfaces) GetByID(id string) *Interface {
+	for i := range iArr {
+		if iArr[i].ID == id {
+			return &iArr[i]
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,77 @@
+// Copyright (c) Ollion
+// SPDX-License-Identifier: Apache-2.0
+
+package dependency
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cldcvr/terrarium/src/pkg/metadata/taxonomy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadInterfaces(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"storage.yaml":         "dependency-interfaces:\n  - id: postgres\n    taxonomy: storage/database/rdbms\n",
+		"nested/compute.yml":   "dependency-interfaces:\n  - id: server_web\n    taxonomy: compute/server/web\n",
+		"nested/readme.md":     "not a dependency interface",
+		"invalid/invalid.yaml": "dependency-interfaces: [",
+	}
+	for name, content := range files {
+		filePath := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(filePath), 0o755))
+		require.NoError(t, os.WriteFile(filePath, []byte(content), 0o644))
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		want    Interfaces
+		wantErr bool
+	}{
+		{
+			name: "file",
+			path: filepath.Join(dir, "storage.yaml"),
+			want: Interfaces{{ID: "postgres", Taxonomy: taxonomy.NewTaxonomy("storage", "database", "rdbms")}},
+		},
+		{
+			name: "directory",
+			path: filepath.Join(dir, "nested"),
+			want: Interfaces{{ID: "server_web", Taxonomy: taxonomy.NewTaxonomy("compute", "server", "web")}},
+		},
+		{
+			name:    "invalid file",
+			path:    filepath.Join(dir, "invalid"),
+			wantErr: true,
+		},
+		{
+			name:    "missing path",
+			path:    filepath.Join(dir, "missing"),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LoadInterfaces(tt.path)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestInterfaces_GetByID(t *testing.T) {
+	iArr := Interfaces{{ID: "postgres"}, {ID: "redis"}}
+
+	assert.Equal(t, &iArr[1], iArr.GetByID("redis"))
+	assert.Nil(t, iArr.GetByID("mysql"))
+}
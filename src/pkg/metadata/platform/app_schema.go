@@ -0,0 +1,208 @@
+// Copyright (c) Ollion
+// SPDX-License-Identifier: Apache-2.0
+
+package platform
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/cldcvr/terrarium/src/pkg/jsonschema"
+	"golang.org/x/exp/maps"
+)
+
+// AppSchemaDraft is the JSON schema draft of the app manifest schema. The 'if' and 'then' keywords require draft-07.
+const AppSchemaDraft = "http://json-schema.org/draft-07/schema#"
+
+// AppSchema returns the JSON schema of the app manifests (terrarium.yaml) that can be generated with the platform.
+// The dependency 'use' is limited to the dependencies implemented by the platform, and the 'inputs' and 'outputs'
+// of each dependency are validated against the inputs and outputs of the implementing component.
+func (pm *PlatformMetadata) AppSchema() *jsonschema.Node {
+	dep := &jsonschema.Node{
+		Type: "object",
+		Properties: map[string]*jsonschema.Node{
+			"id": {
+				Type:        "string",
+				Description: "Identifier of the dependency in the project. Defaults to the dependency interface ID.",
+			},
+			"use": {
+				Type:        "string",
+				Description: "ID of the dependency interface implemented by the platform, optionally followed by the version, i.e. 'postgres@11'.",
+				AnyOf: []*jsonschema.Node{
+					{Enum: pm.appUseIDs()},
+					{Pattern: "^(" + pm.appUsePattern() + ")@.+$"},
+				},
+			},
+			"env_prefix": {
+				Type:        "string",
+				Description: "Prefix of the output env vars of the dependency. Defaults to the dependency ID in upper case.",
+			},
+			"inputs": {
+				Type:        "object",
+				Description: "Customization options of the dependency.",
+			},
+			"outputs": {
+				Type:                 "object",
+				Description:          "Map of the app env var names to Mustache templates using the dependency outputs, i.e. '{{host}}:{{port}}'.",
+				AdditionalProperties: &jsonschema.Node{Type: "string"},
+			},
+			"no_provision": {
+				Type:        "boolean",
+				Description: "Set when the dependency is provisioned in another app, and only its outputs are made available here.",
+			},
+			"app": {
+				Type:        "string",
+				Description: "ID of another app whose compute is used as this dependency.",
+			},
+		},
+		AnyOf: []*jsonschema.Node{
+			{Required: []string{"use"}},
+			{Required: []string{"app"}},
+		},
+		AllOf: pm.appDependencyConditions(),
+	}
+
+	return &jsonschema.Node{
+		Schema:      AppSchemaDraft,
+		Title:       "Terrarium app manifest",
+		Description: "Manifest of an app and the dependencies it requires from the platform.",
+		Type:        "object",
+		Required:    []string{"id"},
+		Properties: map[string]*jsonschema.Node{
+			"id": {
+				Type:        "string",
+				Description: "Identifier of the app in the project.",
+			},
+			"name": {
+				Type:        "string",
+				Description: "Human-friendly name of the app.",
+			},
+			"env_prefix": {
+				Type:        "string",
+				Description: "Prefix of the env vars of the app. Defaults to the app ID in upper case.",
+			},
+			"compute":      dep,
+			"dependencies": {Type: "array", Items: dep},
+		},
+	}
+}
+
+// appUseIDs returns the sorted values accepted by the dependency 'use', i.e. the dependency interfaces implemented
+// by the platform, and the IDs of the components selecting a specific implementation (i.e. "postgres__v11").
+func (pm *PlatformMetadata) appUseIDs() []interface{} {
+	ids := map[string]struct{}{}
+	for _, c := range pm.Components {
+		ids[c.ID] = struct{}{}
+		ids[c.InterfaceID()] = struct{}{}
+	}
+
+	sorted := maps.Keys(ids)
+	sort.Strings(sorted)
+
+	values := make([]interface{}, len(sorted))
+	for i, id := range sorted {
+		values[i] = id
+	}
+
+	return values
+}
+
+// appUsePattern returns the regular expression alternation of the values accepted by the dependency 'use'.
+func (pm *PlatformMetadata) appUsePattern() string {
+	ids := []string{}
+	for _, id := range pm.appUseIDs() {
+		ids = append(ids, regexp.QuoteMeta(id.(string)))
+	}
+
+	return strings.Join(ids, "|")
+}
+
+// appDependencyConditions returns the conditional schemas validating the dependency inputs and outputs
+// against the components selected by the 'use'. When a dependency has multiple implementations and the
+// version does not select one explicitly, the inputs must match any of them.
+func (pm *PlatformMetadata) appDependencyConditions() []*jsonschema.Node {
+	conds := []*jsonschema.Node{}
+	for _, id := range pm.appUseIDs() {
+		id := id.(string)
+
+		impls := pm.Components.GetImplementations(id)
+		if strings.Contains(id, ImplSeparator) {
+			impls = []*Component{pm.Components.GetByID(id)}
+		}
+
+		inputs := appInputsSchema(impls)
+		then := &jsonschema.Node{
+			Properties: map[string]*jsonschema.Node{
+				"inputs":  inputs,
+				"outputs": appOutputsSchema(impls),
+			},
+		}
+		if len(inputs.Required) > 0 {
+			then.Required = []string{"inputs"}
+		}
+
+		conds = append(conds, &jsonschema.Node{
+			If: &jsonschema.Node{
+				Required: []string{"use"},
+				Properties: map[string]*jsonschema.Node{
+					"use": {Pattern: "^" + regexp.QuoteMeta(id) + "(@.+)?$"},
+				},
+			},
+			Then: then,
+		})
+	}
+
+	return conds
+}
+
+// appInputsSchema returns the schema of the dependency inputs matching any of the given implementations.
+func appInputsSchema(impls []*Component) *jsonschema.Node {
+	schemas := []*jsonschema.Node{}
+	for _, c := range impls {
+		if c.Inputs != nil {
+			schemas = append(schemas, c.Inputs)
+		}
+	}
+
+	switch {
+	case len(schemas) == 0:
+		return &jsonschema.Node{Type: "object"}
+	case len(schemas) == 1:
+		return schemas[0]
+	}
+
+	return &jsonschema.Node{Type: "object", AnyOf: schemas}
+}
+
+// appOutputsSchema returns the schema of the dependency outputs, where the Mustache templates
+// can only refer to the outputs of the given implementations.
+func appOutputsSchema(impls []*Component) *jsonschema.Node {
+	names := map[string]struct{}{}
+	for _, c := range impls {
+		if c.Outputs == nil {
+			continue
+		}
+
+		for name := range c.Outputs.Properties {
+			names[regexp.QuoteMeta(name)] = struct{}{}
+		}
+	}
+
+	sorted := maps.Keys(names)
+	sort.Strings(sorted)
+
+	// any text where each '{{' starts a reference to one of the outputs, i.e. "{{host}}:{{port}}"
+	alternatives := []string{`[^{]`, `\{[^{]`, `\{$`}
+	if len(sorted) > 0 {
+		alternatives = append(alternatives, `\{\{\s*(`+strings.Join(sorted, "|")+`)\s*\}\}`)
+	}
+
+	return &jsonschema.Node{
+		Type: "object",
+		AdditionalProperties: &jsonschema.Node{
+			Type:    "string",
+			Pattern: "^(" + strings.Join(alternatives, "|") + ")*$",
+		},
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright (c) Ollion
+// SPDX-License-Identifier: Apache-2.0
+
+package platform
+
+import (
+	"testing"
+
+	"github.com/cldcvr/terrarium/src/pkg/jsonschema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestPlatformMetadata_AppSchema(t *testing.T) {
+	pm := &PlatformMetadata{
+		Components: Components{
+			{
+				ID: "postgres",
+				Inputs: &jsonschema.Node{
+					Type: "object",
+					Properties: map[string]*jsonschema.Node{
+						"version": {Type: "string"},
+						"db_name": {Type: "string"},
+					},
+				},
+				Outputs: &jsonschema.Node{
+					Type: "object",
+					Properties: map[string]*jsonschema.Node{
+						"host": {Type: "string"},
+						"port": {Type: "number"},
+					},
+				},
+			},
+			{
+				ID: "redis__v6",
+				Inputs: &jsonschema.Node{
+					Type: "object",
+					Properties: map[string]*jsonschema.Node{
+						"max_memory": {Type: "number"},
+					},
+				},
+			},
+			{
+				ID: "redis__v7",
+				Inputs: &jsonschema.Node{
+					Type: "object",
+					Properties: map[string]*jsonschema.Node{
+						"max_memory": {Type: "number"},
+					},
+					Required: []string{"max_memory"},
+				},
+			},
+		},
+	}
+
+	schema := pm.AppSchema()
+	assert.Equal(t, AppSchemaDraft, schema.Schema)
+	assert.Equal(t, []interface{}{"postgres", "redis", "redis__v6", "redis__v7"}, schema.Properties["dependencies"].Items.Properties["use"].AnyOf[0].Enum)
+
+	tests := []struct {
+		name     string
+		manifest string
+		wantErr  string
+	}{
+		{
+			name: "valid",
+			manifest: `
+id: banking_app
+compute:
+  use: postgres
+dependencies:
+  - id: ledger_db
+    use: postgres@11
+    inputs:
+      db_name: ledger
+    outputs:
+      PG_CON: "host={{host}} port={{ port }}"
+  - use: redis__v7
+    inputs:
+      max_memory: 512
+  - use: redis@6
+  - app: auth_app
+`,
+		},
+		{
+			name: "missing id",
+			manifest: `
+dependencies:
+  - use: postgres
+`,
+			wantErr: "id is required",
+		},
+		{
+			name: "unknown dependency",
+			manifest: `
+id: banking_app
+dependencies:
+  - use: mysql
+`,
+			wantErr: "dependencies.0.use",
+		},
+		{
+			name: "unknown dependency version",
+			manifest: `
+id: banking_app
+dependencies:
+  - use: mysql@8
+`,
+			wantErr: "dependencies.0.use",
+		},
+		{
+			name: "invalid input type",
+			manifest: `
+id: banking_app
+dependencies:
+  - use: postgres
+    inputs:
+      db_name: 10
+`,
+			wantErr: "dependencies.0.inputs.db_name: Invalid type",
+		},
+		{
+			name: "missing required input of the selected implementation",
+			manifest: `
+id: banking_app
+dependencies:
+  - use: redis__v7
+`,
+			wantErr: "dependencies.0: inputs is required",
+		},
+		{
+			name: "unknown output",
+			manifest: `
+id: banking_app
+dependencies:
+  - use: postgres
+    outputs:
+      PG_CON: "host={{hostname}}"
+`,
+			wantErr: "dependencies.0.outputs.PG_CON: Does not match pattern",
+		},
+		{
+			name: "missing use",
+			manifest: `
+id: banking_app
+dependencies:
+  - id: ledger_db
+`,
+			wantErr: "use is required",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var manifest map[string]interface{}
+			require.NoError(t, yaml.Unmarshal([]byte(tt.manifest), &manifest))
+
+			err := schema.Validate(manifest)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
@@ -32,7 +32,11 @@ func GetBlockType(t string) BlockType {
 		BlockType_Local,
 		BlockType_Variable,
 		BlockType_Output,
-		BlockType_Provider:
+		BlockType_Provider,
+		BlockType_Moved,
+		BlockType_Import,
+		BlockType_Check,
+		BlockType_Terraform:
 
 		return bt
 	default:
@@ -71,7 +75,20 @@ func (b BlockID) ParseComponent() (bt BlockType, componentName string) {
 	return bt, ""
 }
 
+// GetBlock returns the block with the ID in the module. Use ModuleBlocks to look up many blocks of the same module,
+// as the blocks not loaded by tfconfig are parsed from the module files on each call.
 func (bID BlockID) GetBlock(m *tfconfig.Module) (b ParsedBlock, found bool) {
+	return NewModuleBlocks(m).GetBlock(bID)
+}
+
+// FindRequirements returns the IDs of the blocks referred by the block with the ID in the module.
+func (bID BlockID) FindRequirements(m *tfconfig.Module) (requirements []BlockID) {
+	return NewModuleBlocks(m).FindRequirements(bID)
+}
+
+// GetBlock returns the block with the ID in the module.
+func (mb *ModuleBlocks) GetBlock(bID BlockID) (b ParsedBlock, found bool) {
+	m := mb.Module
 	bt, bn := bID.Parse()
 	switch bt {
 	case BlockType_ModuleCall:
@@ -101,15 +118,21 @@ func (bID BlockID) GetBlock(m *tfconfig.Module) (b ParsedBlock, found bool) {
 	case BlockType_Provider:
 		b, found = m.RequiredProviders[bn]
 		return
+
+	case BlockType_Moved, BlockType_Import, BlockType_Check, BlockType_Terraform:
+		b, found = mb.metaBlocks().blocks[bID]
+		return
 	}
 
 	return
 }
 
-func (bID BlockID) FindRequirements(m *tfconfig.Module) (requirements []BlockID) {
+// FindRequirements returns the IDs of the blocks referred by the block with the ID in the module.
+func (mb *ModuleBlocks) FindRequirements(bID BlockID) (requirements []BlockID) {
+	m := mb.Module
 	requirements = []BlockID{}
 
-	b, found := bID.GetBlock(m)
+	b, found := mb.GetBlock(bID)
 	if !found || b == nil {
 		return
 	}
@@ -137,6 +160,23 @@ func (bID BlockID) FindRequirements(m *tfconfig.Module) (requirements []BlockID)
 	return
 }
 
+// FindDependsOn returns the blocks in the depends_on of an output. Unlike its requirements, these blocks are
+// pulled along with the output, instead of the output being pulled only when they are pulled.
+func (mb *ModuleBlocks) FindDependsOn(bID BlockID) (dependsOn []BlockID) {
+	bt, name := bID.Parse()
+	if bt != BlockType_Output {
+		return nil
+	}
+
+	for _, v := range mb.metaBlocks().outputDependsOn[name] {
+		if dID, found := getBlockIDFromTFAttribute(v, mb.Module); found {
+			dependsOn = appendSortedUnique(dependsOn, dID)
+		}
+	}
+
+	return
+}
+
 func getBlockIDFromTFAttribute(v tfconfig.AttributeReference, m *tfconfig.Module) (BlockID, bool) {
 	switch v.Type() {
 	case "":
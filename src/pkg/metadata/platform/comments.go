@@ -7,16 +7,32 @@ import (
 	"bufio"
 	"io"
 	"os"
+	"strconv"
 	"strings"
 
 	"github.com/icza/backscanner"
+	"github.com/rotisserie/eris"
 	"golang.org/x/exp/slices"
 )
 
 const (
-	docCommentTitleArgTag = "title"
-	docCommentDescArgTag  = "description"
-	docCommentEnumArgTag  = "enum"
+	docCommentTitleArgTag      = "title"
+	docCommentDescArgTag       = "description"
+	docCommentEnumArgTag       = "enum"
+	docCommentVersionsArgTag   = "versions"
+	docCommentTypeArgTag       = "type"
+	docCommentPatternArgTag    = "pattern"
+	docCommentFormatArgTag     = "format"
+	docCommentExamplesArgTag   = "examples"
+	docCommentMinimumArgTag    = "minimum"
+	docCommentMaximumArgTag    = "maximum"
+	docCommentExclMinArgTag    = "exclusiveMinimum"
+	docCommentExclMaxArgTag    = "exclusiveMaximum"
+	docCommentMinLenArgTag     = "minLength"
+	docCommentMaxLenArgTag     = "maxLength"
+	docCommentRequiredArgTag   = "required"
+	docCommentDeprecatedArgTag = "deprecated"
+	docCommentExtendsArgTag    = "extends"
 )
 
 func SetListFromDocIfFound(values *[]interface{}, valueTagName string, fieldDoc map[string]string) {
@@ -30,6 +46,58 @@ func SetListFromDocIfFound(values *[]interface{}, valueTagName string, fieldDoc
 	}
 }
 
+// SetIntFromDocIfFound sets the value to the integer in the doc tag, if found.
+// It returns an error if the tag value is not an integer.
+func SetIntFromDocIfFound(value **int32, valueTagName string, fieldDoc map[string]string) error {
+	var str string
+	if ok := SetValueFromDocIfFound(&str, valueTagName, fieldDoc); !ok {
+		return nil
+	}
+
+	v, err := strconv.ParseInt(strings.TrimSpace(str), 10, 32)
+	if err != nil {
+		return eris.Errorf("'@%s' doc tag value '%s' is not an integer", valueTagName, str)
+	}
+
+	i := int32(v)
+	*value = &i
+	return nil
+}
+
+// SetNumberFromDocIfFound sets the value to the number in the doc tag, if found.
+// It returns an error if the tag value is not a number.
+func SetNumberFromDocIfFound(value **float64, valueTagName string, fieldDoc map[string]string) error {
+	var str string
+	if ok := SetValueFromDocIfFound(&str, valueTagName, fieldDoc); !ok {
+		return nil
+	}
+
+	v, err := strconv.ParseFloat(strings.TrimSpace(str), 64)
+	if err != nil {
+		return eris.Errorf("'@%s' doc tag value '%s' is not a number", valueTagName, str)
+	}
+
+	*value = &v
+	return nil
+}
+
+// SetBoolFromDocIfFound sets the value to the boolean in the doc tag, if found.
+// It returns an error if the tag value is not a boolean.
+func SetBoolFromDocIfFound(value *bool, valueTagName string, fieldDoc map[string]string) error {
+	var str string
+	if ok := SetValueFromDocIfFound(&str, valueTagName, fieldDoc); !ok {
+		return nil
+	}
+
+	v, err := strconv.ParseBool(strings.TrimSpace(str))
+	if err != nil {
+		return eris.Errorf("'@%s' doc tag value '%s' is not a boolean", valueTagName, str)
+	}
+
+	*value = v
+	return nil
+}
+
 func SetValueFromDocIfFound(value *string, valueTagName string, fieldDoc map[string]string) (ok bool) {
 	if newValue, exists := fieldDoc[valueTagName]; exists && newValue != "" {
 		*value = newValue
@@ -110,3 +110,37 @@ func TestSetValueFromDocIfFound(t *testing.T) {
 		})
 	}
 }
+
+func TestSetIntFromDocIfFound(t *testing.T) {
+	var value *int32
+	assert.NoError(t, SetIntFromDocIfFound(&value, "minLength", map[string]string{"minLength": "30"}))
+	assert.NoError(t, SetIntFromDocIfFound(&value, "maxLength", map[string]string{"minLength": "40"}))
+	assert.Equal(t, int32(30), *value, "value is not changed when the tag is not found")
+
+	assert.NoError(t, SetIntFromDocIfFound(&value, "minLength", map[string]string{"minLength": " 0"}))
+	assert.Equal(t, int32(0), *value)
+
+	assert.EqualError(t, SetIntFromDocIfFound(&value, "minLength", map[string]string{"minLength": "0.5"}), "'@minLength' doc tag value '0.5' is not an integer")
+}
+
+func TestSetNumberFromDocIfFound(t *testing.T) {
+	var value *float64
+	assert.NoError(t, SetNumberFromDocIfFound(&value, "maximum", map[string]string{"minimum": "20"}))
+	assert.Nil(t, value, "value is not set when the tag is not found")
+
+	assert.NoError(t, SetNumberFromDocIfFound(&value, "maximum", map[string]string{"maximum": " 0.5"}))
+	assert.Equal(t, 0.5, *value)
+
+	assert.NoError(t, SetNumberFromDocIfFound(&value, "minimum", map[string]string{"minimum": "0"}))
+	assert.Equal(t, 0.0, *value)
+
+	assert.EqualError(t, SetNumberFromDocIfFound(&value, "minimum", map[string]string{"minimum": "twenty"}), "'@minimum' doc tag value 'twenty' is not a number")
+}
+
+func TestSetBoolFromDocIfFound(t *testing.T) {
+	var value bool
+	assert.NoError(t, SetBoolFromDocIfFound(&value, "required", map[string]string{"required": "true"}))
+	assert.True(t, value)
+
+	assert.EqualError(t, SetBoolFromDocIfFound(&value, "required", map[string]string{"required": "yes please"}), "'@required' doc tag value 'yes please' is not a boolean")
+}
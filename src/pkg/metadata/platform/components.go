@@ -6,13 +6,19 @@ package platform
 import (
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/cldcvr/terraform-config-inspect/tfconfig"
 	"github.com/cldcvr/terrarium/src/pkg/jsonschema"
+	"github.com/cldcvr/terrarium/src/pkg/tf/parser"
+	goversion "github.com/hashicorp/go-version"
+	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/rotisserie/eris"
 	"github.com/xeipuuv/gojsonschema"
 	"github.com/zclconf/go-cty/cty"
+	"golang.org/x/exp/slices"
 	"golang.org/x/text/cases"
 	"golang.org/x/text/language"
 )
@@ -39,6 +45,105 @@ func (cArr Components) GetByID(id string) *Component {
 	return nil
 }
 
+// GetImplementations returns the components implementing the dependency with the given ID,
+// i.e. the component with the same ID and the ones with the ID suffixed by an implementation name (i.e. "postgres__v11").
+func (cArr Components) GetImplementations(depType string) []*Component {
+	impls := []*Component{}
+	for i, v := range cArr {
+		if v.InterfaceID() == depType {
+			impls = append(impls, &cArr[i])
+		}
+	}
+
+	return impls
+}
+
+// GetImplementation returns the component implementing the dependency with the given ID and version.
+// The implementation is selected by matching the version against the 'versions' constraint of each implementation,
+// falling back to the implementation without a constraint. When the version is empty, the component with the same ID
+// as the dependency is returned, or the only implementation of the dependency. It returns nil if the dependency is not implemented.
+func (cArr Components) GetImplementation(depType, version string) (*Component, error) {
+	if strings.Contains(depType, ImplSeparator) {
+		return cArr.GetByID(depType), nil // the implementation is selected explicitly
+	}
+
+	impls := cArr.GetImplementations(depType)
+	if len(impls) == 0 {
+		return nil, nil
+	}
+
+	if version == "" {
+		if c := cArr.GetByID(depType); c != nil {
+			return c, nil
+		}
+
+		if len(impls) == 1 {
+			return impls[0], nil
+		}
+
+		return nil, eris.Errorf("component '%s' has multiple implementations [%s]. set the version to select one, i.e. '%s@<version>'", depType, describeImplementations(impls), depType)
+	}
+
+	// versions that are not semantic, i.e. "latest", only match the implementations without a constraint
+	v, _ := goversion.NewVersion(version)
+
+	matched := []*Component{}
+	unconstrained := []*Component{}
+	for _, c := range impls {
+		if c.Versions == "" {
+			unconstrained = append(unconstrained, c)
+			continue
+		}
+
+		constraints, err := goversion.NewConstraint(c.Versions)
+		if err != nil {
+			return nil, eris.Wrapf(err, "invalid versions constraint '%s' of component '%s'", c.Versions, c.ID)
+		}
+
+		if v != nil && constraints.Check(v) {
+			matched = append(matched, c)
+		}
+	}
+
+	if len(matched) == 0 {
+		matched = unconstrained
+	}
+
+	switch len(matched) {
+	case 0:
+		return nil, eris.Errorf("none of the implementations of component '%s' supports the version '%s'. available implementations: [%s]", depType, version, describeImplementations(impls))
+	case 1:
+		return matched[0], nil
+	}
+
+	return nil, eris.Errorf("version '%s' of component '%s' matches multiple implementations [%s]", version, depType, describeImplementations(matched))
+}
+
+// describeImplementations returns a comma separated list of the implementation IDs along with their version constraints.
+func describeImplementations(impls []*Component) string {
+	desc := make([]string, len(impls))
+	for i, c := range impls {
+		desc[i] = c.ID
+		if c.Versions != "" {
+			desc[i] += " (" + c.Versions + ")"
+		}
+	}
+
+	return strings.Join(desc, ", ")
+}
+
+// InterfaceID returns the ID of the dependency implemented by the component,
+// i.e. the component ID without the implementation suffix ("postgres" for "postgres__v11").
+func (c Component) InterfaceID() string {
+	return GetInterfaceID(c.ID)
+}
+
+// GetInterfaceID returns the ID of the dependency implemented by the component with the given ID.
+func GetInterfaceID(componentID string) string {
+	id, _, _ := strings.Cut(componentID, ImplSeparator)
+	return id
+}
+
 func (cArr *Components) Append(c Component) *Component {
 	(*cArr) = append((*cArr), c)
 	return &(*cArr)[len(*cArr)-1]
@@ -60,6 +165,7 @@ func (cArr *Components) Parse(platformModule *tfconfig.Module) {
 		c.Title = tfValueToTitle(id, nil) // default to component name in title format
 		SetValueFromDocIfFound(&c.Title, docCommentTitleArgTag, docs)
 		SetValueFromDocIfFound(&c.Description, docCommentDescArgTag, docs)
+		SetValueFromDocIfFound(&c.Versions, docCommentVersionsArgTag, docs)
 
 		c.fetchInputs(platformModule)
 		c.fetchOutputs(platformModule)
@@ -86,10 +192,112 @@ func (c *Component) fetchInputs(m *tfconfig.Module) {
 	cv, _ := v.Expression.Value(nil)
 	valMap := cv.AsValueMap()
 	if mv, ok := valMap["default"]; ok {
-		extractSchema(c.Inputs, mv, "", fieldDoc)
+		c.docErrs = extractSchema(c.Inputs, mv, "", fieldDoc)
+	}
+
+	typed, err := getInputsTypeSchema(m, v)
+	if err != nil {
+		c.docErrs = append(c.docErrs, eris.Wrapf(err, "inputs of component '%s'", c.ID))
+	} else if typed != nil {
+		c.Inputs = mergeTypeSchema(typed, c.Inputs, "")
 	}
 }
 
+// getInputsTypeSchema returns the schema of the inputs declared by the '@type' doc tag of the component local,
+// either as a type expression (i.e. 'object({ name = string, tags = optional(map(string)) })'),
+// or as a reference to a variable declared with the type constraint (i.e. 'var.postgres_inputs').
+// Returns nil if the inputs type is not declared.
+func getInputsTypeSchema(m *tfconfig.Module, local *tfconfig.Local) (*jsonschema.Node, error) {
+	var typeTag string
+	if !SetValueFromDocIfFound(&typeTag, docCommentTypeArgTag, getBlockDoc(local.Pos)) {
+		return nil, nil
+	}
+
+	if varName, ok := strings.CutPrefix(typeTag, "var."); ok {
+		v, ok := m.Variables[varName]
+		if !ok || v.Type == "" {
+			return nil, eris.Errorf("variable '%s' in the '@%s' doc tag is not declared with a type", varName, docCommentTypeArgTag)
+		}
+		typeTag = v.Type
+	}
+
+	return parser.ParseTypeSchema(typeTag)
+}
+
+// mergeTypeSchema overlays the titles, descriptions, default values and doc tag constraints of the schema
+// inferred from the default values onto the schema of the declared type. The declared type takes precedence,
+// i.e. the items of an empty list default are typed, and the attributes that are not optional are required
+// unless a default value is set for them.
+func mergeTypeSchema(typed, inferred *jsonschema.Node, fieldName string) *jsonschema.Node {
+	if typed.Title == "" {
+		typed.Title = tfValueToTitle(fieldName, nil)
+	}
+
+	if inferred == nil {
+		for name, prop := range typed.Properties {
+			typed.Properties[name] = mergeTypeSchema(prop, nil, name)
+		}
+		if typed.Items != nil {
+			typed.Items = mergeTypeSchema(typed.Items, nil, fieldName)
+		}
+		return typed
+	}
+
+	if typed.Type == "" { // any type
+		typed.Type = inferred.Type
+	}
+	if inferred.Title != "" {
+		typed.Title = inferred.Title
+	}
+	if inferred.Default != nil {
+		typed.Default = inferred.Default
+	}
+	typed.Description, typed.Deprecated = inferred.Description, inferred.Deprecated
+	typed.Pattern, typed.Format = inferred.Pattern, inferred.Format
+	typed.Minimum, typed.Maximum = inferred.Minimum, inferred.Maximum
+	typed.ExclusiveMinimum, typed.ExclusiveMaximum = inferred.ExclusiveMinimum, inferred.ExclusiveMaximum
+	typed.MinLength, typed.MaxLength = inferred.MinLength, inferred.MaxLength
+	typed.Enum = castDocValues(inferred.Enum, typed.Type)
+	typed.Examples = castDocValues(inferred.Examples, typed.Type)
+
+	if len(typed.Properties) > 0 || len(inferred.Properties) > 0 {
+		if typed.Properties == nil {
+			typed.Properties = map[string]*jsonschema.Node{}
+		}
+		for name, prop := range typed.Properties {
+			typed.Properties[name] = mergeTypeSchema(prop, inferred.Properties[name], name)
+		}
+		for name, prop := range inferred.Properties {
+			if _, ok := typed.Properties[name]; !ok {
+				typed.Properties[name] = prop
+			}
+		}
+
+		required := inferred.Required
+		for _, name := range typed.Required {
+			if typed.Properties[name].Default == nil && !slices.Contains(required, name) {
+				required = append(required, name)
+			}
+		}
+		sort.Strings(required)
+		typed.Required = required
+	}
+
+	if typed.Items != nil {
+		typed.Items = mergeTypeSchema(typed.Items, inferred.Items, fieldName)
+	} else {
+		typed.Items = inferred.Items
+	}
+
+	return typed
+}
+
+// DocErrors returns the errors in the doc comment tags of the component inputs and outputs,
+// i.e. a '@minimum' tag that is not a number, or a '@type' tag that is not a valid type.
+func (c Component) DocErrors() []error {
+	return c.docErrs
+}
+
 func getLocalInputBlockDocs(block *tfconfig.Local) (docsByKey map[string]map[string]string) {
 	docsByKey = make(map[string]map[string]string)
 	switch localExpr := block.Expression.(type) {
@@ -144,9 +352,70 @@ func (c *Component) fetchOutputs(m *tfconfig.Module) {
 		}
 		node.Title = tfValueToTitle(outputKey, &prefix)
 		node.Description = v.Description
+		node.Sensitive = v.Sensitive
+
+		// the output is a map of the value for each dependency, so the type of its elements is the output type.
+		typeErrs := []error{}
+		if inferred := parser.InferElementSchema(v.Value.Expression, moduleTraversalResolver(m, &typeErrs)); inferred != nil && inferred.Type != "" {
+			node.Type, node.Items = inferred.Type, inferred.Items
+		}
+
+		var typeTag string
+		if SetValueFromDocIfFound(&typeTag, docCommentTypeArgTag, getBlockDoc(v.Pos)) {
+			tagged, err := parser.ParseTypeSchema(typeTag)
+			if err != nil {
+				typeErrs = append(typeErrs, eris.Wrapf(err, "invalid '@%s' doc tag", docCommentTypeArgTag))
+			} else {
+				node.Type, node.Items = tagged.Type, tagged.Items
+			}
+		}
+
+		for _, err := range typeErrs {
+			c.docErrs = append(c.docErrs, eris.Wrapf(err, "output '%s'", outputKey))
+		}
 	}
 }
 
+// moduleTraversalResolver returns a resolver of the types of the variables and locals declared in the module,
+// and of the outputs of the module calls that are loaded along with the module (i.e. using the resolved modules schema).
+// The errors in parsing the declared variable types are added to errs.
+func moduleTraversalResolver(m *tfconfig.Module, errs *[]error) parser.TraversalResolver {
+	var resolve parser.TraversalResolver
+	resolve = func(traversal hcl.Traversal) *jsonschema.Node {
+		names := []string{traversal.RootName()}
+		for _, step := range traversal[1:] {
+			if attr, ok := step.(hcl.TraverseAttr); ok {
+				names = append(names, attr.Name)
+			}
+		}
+
+		switch {
+		case names[0] == "var" && len(names) == 2:
+			if v, ok := m.Variables[names[1]]; ok && v.Type != "" {
+				node, err := parser.ParseTypeSchema(v.Type)
+				if err != nil {
+					*errs = append(*errs, eris.Wrapf(err, "invalid type of variable '%s'", names[1]))
+				}
+				return node
+			}
+		case names[0] == "local" && len(names) == 2:
+			if l, ok := m.Locals[names[1]]; ok && l != nil && l.Expression != nil {
+				return parser.InferSchema(l.Expression, resolve)
+			}
+		case names[0] == "module" && len(names) == 3:
+			if mc, ok := m.ModuleCalls[names[1]]; ok && mc.Module != nil {
+				if out, ok := mc.Module.Outputs[names[2]]; ok && out.Value.Expression != nil {
+					return parser.InferSchema(out.Value.Expression, moduleTraversalResolver(mc.Module, errs))
+				}
+			}
+		}
+
+		return nil
+	}
+
+	return resolve
+}
+
 // tfValueToTitle removes the common component prefix and converts snake-case "default_db_name" to "Default Db Name"
 func tfValueToTitle(value string, prefix *string) string {
 	if prefix == nil {
@@ -156,12 +425,28 @@ func tfValueToTitle(value string, prefix *string) string {
 	return cases.Title(language.Und, cases.NoLower).String(strings.ReplaceAll(strings.TrimPrefix(value, *prefix), "_", " "))
 }
 
-func extractSchema(existingSchema *jsonschema.Node, value cty.Value, fieldName string, fieldDocs map[string]map[string]string) {
+func extractSchema(existingSchema *jsonschema.Node, value cty.Value, fieldName string, fieldDocs map[string]map[string]string) (errs []error) {
 	existingSchema.Title = tfValueToTitle(fieldName, nil) // default to field name in title format
 	if fieldDoc, ok := fieldDocs[fieldName]; ok {
 		SetValueFromDocIfFound(&existingSchema.Title, docCommentTitleArgTag, fieldDoc)
 		SetValueFromDocIfFound(&existingSchema.Description, docCommentDescArgTag, fieldDoc)
 		SetListFromDocIfFound(&existingSchema.Enum, docCommentEnumArgTag, fieldDoc)
+		SetListFromDocIfFound(&existingSchema.Examples, docCommentExamplesArgTag, fieldDoc)
+		SetValueFromDocIfFound(&existingSchema.Pattern, docCommentPatternArgTag, fieldDoc)
+		SetValueFromDocIfFound(&existingSchema.Format, docCommentFormatArgTag, fieldDoc)
+		for _, err := range []error{
+			SetNumberFromDocIfFound(&existingSchema.Minimum, docCommentMinimumArgTag, fieldDoc),
+			SetNumberFromDocIfFound(&existingSchema.Maximum, docCommentMaximumArgTag, fieldDoc),
+			SetNumberFromDocIfFound(&existingSchema.ExclusiveMinimum, docCommentExclMinArgTag, fieldDoc),
+			SetNumberFromDocIfFound(&existingSchema.ExclusiveMaximum, docCommentExclMaxArgTag, fieldDoc),
+			SetIntFromDocIfFound(&existingSchema.MinLength, docCommentMinLenArgTag, fieldDoc),
+			SetIntFromDocIfFound(&existingSchema.MaxLength, docCommentMaxLenArgTag, fieldDoc),
+			SetBoolFromDocIfFound(&existingSchema.Deprecated, docCommentDeprecatedArgTag, fieldDoc),
+		} {
+			if err != nil {
+				errs = append(errs, eris.Wrapf(err, "input '%s'", fieldName))
+			}
+		}
 	}
 
 	switch value.Type().FriendlyName() {
@@ -173,22 +458,28 @@ func extractSchema(existingSchema *jsonschema.Node, value cty.Value, fieldName s
 			existingSchema.Properties = map[string]*jsonschema.Node{}
 		}
 
+		existingSchema.Required = nil
 		for key, val := range mapValue {
 			existingSchema.Properties[key] = &jsonschema.Node{}
-			extractSchema(existingSchema.Properties[key], val, key, fieldDocs)
+			errs = append(errs, extractSchema(existingSchema.Properties[key], val, key, fieldDocs)...)
+
+			var required bool
+			if err := SetBoolFromDocIfFound(&required, docCommentRequiredArgTag, fieldDocs[key]); err != nil {
+				errs = append(errs, eris.Wrapf(err, "input '%s'", key))
+			} else if required {
+				existingSchema.Required = append(existingSchema.Required, key)
+			}
 		}
+		sort.Strings(existingSchema.Required)
 	case "string":
 		existingSchema.Type = gojsonschema.TYPE_STRING
 		existingSchema.Default = value.AsString()
-		return
 	case "number":
 		existingSchema.Type = gojsonschema.TYPE_NUMBER
 		existingSchema.Default, _ = value.AsBigFloat().Float64()
-		return
 	case "bool":
 		existingSchema.Type = gojsonschema.TYPE_BOOLEAN
 		existingSchema.Default = value.True()
-		return
 	case "tuple":
 		listVal := value.AsValueSlice()
 		existingSchema.Type = gojsonschema.TYPE_ARRAY
@@ -197,7 +488,36 @@ func extractSchema(existingSchema *jsonschema.Node, value cty.Value, fieldName s
 		}
 
 		if len(listVal) > 0 {
-			extractSchema(existingSchema.Items, listVal[0], fieldName, fieldDocs)
+			errs = append(errs, extractSchema(existingSchema.Items, listVal[0], fieldName, fieldDocs)...)
+		}
+	}
+
+	// the enum and example values in the doc tags are strings, which are converted to the type of the input.
+	existingSchema.Enum = castDocValues(existingSchema.Enum, existingSchema.Type)
+	existingSchema.Examples = castDocValues(existingSchema.Examples, existingSchema.Type)
+	return
+}
+
+// castDocValues converts the string values from a doc tag to numbers or booleans for the inputs of that type.
+// The values that can not be converted are kept as-is, for the schema validation to report them.
+func castDocValues(values []interface{}, schemaType string) []interface{} {
+	for i, v := range values {
+		str, ok := v.(string)
+		if !ok {
+			continue
+		}
+
+		switch schemaType {
+		case gojsonschema.TYPE_NUMBER:
+			if f, err := strconv.ParseFloat(str, 64); err == nil {
+				values[i] = f
+			}
+		case gojsonschema.TYPE_BOOLEAN:
+			if b, err := strconv.ParseBool(str); err == nil {
+				values[i] = b
+			}
 		}
 	}
+
+	return values
 }
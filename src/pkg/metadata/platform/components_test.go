@@ -4,11 +4,16 @@
 package platform
 
 import (
+	"os"
+	"path/filepath"
+	"sort"
 	"testing"
 
 	"github.com/cldcvr/terraform-config-inspect/tfconfig"
+	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hclsyntax"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/zclconf/go-cty/cty"
 )
 
@@ -44,6 +49,7 @@ func createTestPlatformModule() *tfconfig.Module {
 			},
 			"tr_component_test_output2": {
 				Description: "Output description 2",
+				Sensitive:   true,
 			},
 			"output3": {},
 		},
@@ -132,6 +138,8 @@ func TestComponent_fetchOutputs(t *testing.T) {
 	assert.NotNil(t, component.Outputs, "Outputs should not be nil after fetching")
 	assert.NotNil(t, component.Outputs.Properties["output1"], "output1 property should be present in Outputs")
 	assert.NotNil(t, component.Outputs.Properties["output2"], "output2 property should be present in Outputs")
+	assert.False(t, component.Outputs.Properties["output1"].Sensitive, "output1 should not be sensitive")
+	assert.True(t, component.Outputs.Properties["output2"].Sensitive, "output2 should be sensitive")
 }
 
 func Test_getLocalInputBlockDocs(t *testing.T) {
@@ -211,3 +219,213 @@ func Test_getBlockDoc(t *testing.T) {
 		})
 	}
 }
+
+func TestComponents_GetImplementation(t *testing.T) {
+	components := Components{
+		{ID: "mysql"},
+		{ID: "postgres__v11", Versions: ">= 11, < 12"},
+		{ID: "postgres__v15", Versions: ">= 12"},
+		{ID: "redis"},
+		{ID: "redis__cluster", Versions: ">= 7"},
+	}
+
+	tests := []struct {
+		name    string
+		depType string
+		version string
+		wantID  string
+		wantErr string
+	}{
+		{name: "single component", depType: "mysql", wantID: "mysql"},
+		{name: "not implemented", depType: "kafka", wantID: ""},
+		{name: "version in range", depType: "postgres", version: "11.2", wantID: "postgres__v11"},
+		{name: "version in open range", depType: "postgres", version: "15", wantID: "postgres__v15"},
+		{name: "explicit implementation", depType: "postgres__v11", wantID: "postgres__v11"},
+		{name: "unconstrained fallback", depType: "redis", version: "6", wantID: "redis"},
+		{name: "non semantic version fallback", depType: "redis", version: "latest", wantID: "redis"},
+		{name: "no version uses the same ID", depType: "redis", wantID: "redis"},
+		{name: "version not supported", depType: "postgres", version: "10", wantErr: "none of the implementations of component 'postgres' supports the version '10'. available implementations: [postgres__v11 (>= 11, < 12), postgres__v15 (>= 12)]"},
+		{name: "no version with multiple implementations", depType: "postgres", wantErr: "component 'postgres' has multiple implementations [postgres__v11 (>= 11, < 12), postgres__v15 (>= 12)]. set the version to select one, i.e. 'postgres@<version>'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := components.GetImplementation(tt.depType, tt.version)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			if tt.wantID == "" {
+				assert.Nil(t, got)
+				return
+			}
+
+			if assert.NotNil(t, got) {
+				assert.Equal(t, tt.wantID, got.ID)
+			}
+		})
+	}
+}
+
+func TestComponent_InterfaceID(t *testing.T) {
+	assert.Equal(t, "postgres", Component{ID: "postgres__v11"}.InterfaceID())
+	assert.Equal(t, "postgres", Component{ID: "postgres"}.InterfaceID())
+}
+
+func TestComponent_fetchOutputs_types(t *testing.T) {
+	parseExpr := func(src string) hcl.Expression {
+		expr, diags := hclsyntax.ParseExpression([]byte(src), "", hcl.InitialPos)
+		require.False(t, diags.HasErrors(), diags.Error())
+		return expr
+	}
+
+	module := &tfconfig.Module{
+		Variables: map[string]*tfconfig.Variable{
+			"db_port": {Name: "db_port", Type: "number"},
+		},
+		Locals: map[string]*tfconfig.Local{
+			"db_zones": {Name: "db_zones", Expression: parseExpr(`["a", "b"]`)},
+		},
+		ModuleCalls: map[string]*tfconfig.ModuleCall{
+			"tr_component_db": {
+				Module: &tfconfig.Module{
+					Outputs: map[string]*tfconfig.Output{
+						"endpoint": {Value: tfconfig.ResourceAttributeReference{Expression: parseExpr(`"${aws_db_instance.this.address}:5432"`)}},
+					},
+				},
+			},
+		},
+		Outputs: map[string]*tfconfig.Output{
+			"tr_component_db_endpoint": {Value: tfconfig.ResourceAttributeReference{Expression: parseExpr(`{ for k, v in module.tr_component_db : k => v.endpoint }`)}},
+			"tr_component_db_port":     {Value: tfconfig.ResourceAttributeReference{Expression: parseExpr(`{ for k, v in module.tr_component_db : k => var.db_port }`)}},
+			"tr_component_db_zones":    {Value: tfconfig.ResourceAttributeReference{Expression: parseExpr(`{ for k, v in module.tr_component_db : k => local.db_zones }`)}},
+			"tr_component_db_arn":      {Value: tfconfig.ResourceAttributeReference{Expression: parseExpr(`{ for k, v in module.tr_component_db : k => v.arn }`)}},
+		},
+	}
+
+	c := Component{ID: "db"}
+	c.fetchOutputs(module)
+
+	assert.Equal(t, "string", c.Outputs.Properties["endpoint"].Type)
+	assert.Equal(t, "number", c.Outputs.Properties["port"].Type)
+	assert.Equal(t, "array", c.Outputs.Properties["zones"].Type)
+	assert.Equal(t, "string", c.Outputs.Properties["zones"].Items.Type)
+	assert.Equal(t, "", c.Outputs.Properties["arn"].Type, "type of unknown values is not set")
+}
+
+func TestComponent_fetchOutputs_typeErrors(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.tf"), []byte(`
+variable "db_port" {
+  type = numbr
+}
+
+module "tr_component_db" {
+  source = "./modules/db"
+}
+
+# @type: list(
+output "tr_component_db_endpoint" {
+  value = { for k, v in module.tr_component_db : k => v.endpoint }
+}
+
+output "tr_component_db_port" {
+  value = { for k, v in module.tr_component_db : k => var.db_port }
+}
+
+# @type: list(string)
+output "tr_component_db_zones" {
+  value = { for k, v in module.tr_component_db : k => v.zones }
+}
+`), 0o644))
+
+	m, _ := tfconfig.LoadModule(dir, &tfconfig.ResolvedModulesSchema{})
+
+	c := Component{ID: "db"}
+	c.fetchOutputs(m)
+
+	msgs := []string{}
+	for _, err := range c.DocErrors() {
+		msgs = append(msgs, err.Error())
+	}
+	sort.Strings(msgs)
+	require.Len(t, msgs, 2)
+	assert.Contains(t, msgs[0], "output 'endpoint': invalid '@type' doc tag")
+	assert.Contains(t, msgs[1], "output 'port': invalid type of variable 'db_port'")
+	assert.Equal(t, "array", c.Outputs.Properties["zones"].Type)
+}
+
+func TestComponent_fetchInputs_types(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.tf"), []byte(`
+variable "db_inputs" {
+  type = object({
+    version = string
+    users = optional(list(object({
+      name  = string
+      admin = optional(bool, false)
+    })), [])
+    tags = optional(map(string))
+  })
+}
+
+locals {
+  # @type: var.db_inputs
+  tr_component_db = {
+    default = {
+      # @title: Engine Version
+      version = "15"
+      users   = []
+    }
+  }
+
+  # @type: object({ name = string, size = optional(number, 1) })
+  tr_component_cache = {
+    default = {}
+  }
+
+  # @type: var.undeclared
+  tr_component_queue = {
+    default = {}
+  }
+}
+`), 0o644))
+
+	m, diags := tfconfig.LoadModule(dir, &tfconfig.ResolvedModulesSchema{})
+	require.False(t, diags.HasErrors(), diags.Error())
+
+	db := Component{ID: "db"}
+	db.fetchInputs(m)
+	assert.Empty(t, db.DocErrors())
+	assert.Equal(t, "object", db.Inputs.Type)
+	assert.Empty(t, db.Inputs.Required, "inputs with a default value are not required")
+	assert.Equal(t, "Engine Version", db.Inputs.Properties["version"].Title)
+	assert.Equal(t, "15", db.Inputs.Properties["version"].Default)
+	assert.Equal(t, "Tags", db.Inputs.Properties["tags"].Title)
+	users := db.Inputs.Properties["users"]
+	require.NotNil(t, users.Items, "items of an empty list default are typed")
+	assert.Equal(t, "object", users.Items.Type)
+	assert.Equal(t, []string{"name"}, users.Items.Required)
+	assert.Equal(t, false, users.Items.Properties["admin"].Default)
+	assert.NoError(t, db.Inputs.Validate(map[string]interface{}{"users": []interface{}{map[string]interface{}{"name": "app"}}}))
+	assert.Error(t, db.Inputs.Validate(map[string]interface{}{"users": []interface{}{map[string]interface{}{"admin": true}}}))
+
+	cache := Component{ID: "cache"}
+	cache.fetchInputs(m)
+	assert.Empty(t, cache.DocErrors())
+	assert.Equal(t, []string{"name"}, cache.Inputs.Required)
+	assert.Equal(t, 1.0, cache.Inputs.Properties["size"].Default)
+
+	queue := Component{ID: "queue"}
+	queue.fetchInputs(m)
+	require.Len(t, queue.DocErrors(), 1)
+	assert.ErrorContains(t, queue.DocErrors()[0], "variable 'undeclared' in the '@type' doc tag is not declared with a type")
+}
+
+func Test_castDocValues(t *testing.T) {
+	assert.Equal(t, []interface{}{1.0, 2.5, "three"}, castDocValues([]interface{}{"1", "2.5", "three"}, "number"))
+	assert.Equal(t, []interface{}{true, false}, castDocValues([]interface{}{"true", "false"}, "boolean"))
+	assert.Equal(t, []interface{}{"1", "2"}, castDocValues([]interface{}{"1", "2"}, "string"))
+}
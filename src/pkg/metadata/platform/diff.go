@@ -0,0 +1,221 @@
+// Copyright (c) Ollion
+// SPDX-License-Identifier: Apache-2.0
+
+package platform
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/cldcvr/terrarium/src/pkg/jsonschema"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+)
+
+// ChangeKind represents the kind of a change between two revisions of the platform metadata.
+type ChangeKind string
+
+const (
+	ChangeKind_ComponentAdded      ChangeKind = "component_added"
+	ChangeKind_ComponentRemoved    ChangeKind = "component_removed"
+	ChangeKind_InputAdded          ChangeKind = "input_added"
+	ChangeKind_InputRemoved        ChangeKind = "input_removed"
+	ChangeKind_InputTypeChanged    ChangeKind = "input_type_changed"
+	ChangeKind_InputRequired       ChangeKind = "input_required"
+	ChangeKind_InputDefaultChanged ChangeKind = "input_default_changed"
+	ChangeKind_OutputAdded         ChangeKind = "output_added"
+	ChangeKind_OutputRemoved       ChangeKind = "output_removed"
+	ChangeKind_ProfileAdded        ChangeKind = "profile_added"
+	ChangeKind_ProfileRemoved      ChangeKind = "profile_removed"
+)
+
+// Change represents a difference between two revisions of the platform metadata.
+// A change is breaking when the app manifests working with the old revision may fail with the new one.
+type Change struct {
+	Kind      ChangeKind  `json:"kind"`
+	Component string      `json:"component,omitempty"` // ID of the component the change applies to
+	Name      string      `json:"name,omitempty"`      // Path of the input (i.e. "tags[*].key"), name of the output or ID of the profile
+	Old       interface{} `json:"old,omitempty"`       // Previous type or default value of the input
+	New       interface{} `json:"new,omitempty"`       // New type or default value of the input
+	Breaking  bool        `json:"breaking"`
+}
+
+// Changes is a slice of Change objects.
+type Changes []Change
+
+// String describes the change, i.e. "output 'host' of component 'postgres' is removed".
+func (c Change) String() string {
+	switch c.Kind {
+	case ChangeKind_ComponentAdded:
+		return fmt.Sprintf("component '%s' is added", c.Component)
+	case ChangeKind_ComponentRemoved:
+		return fmt.Sprintf("component '%s' is removed", c.Component)
+	case ChangeKind_InputAdded:
+		if c.Breaking {
+			return fmt.Sprintf("required input '%s' is added to component '%s'", c.Name, c.Component)
+		}
+		return fmt.Sprintf("input '%s' is added to component '%s'", c.Name, c.Component)
+	case ChangeKind_InputRemoved:
+		return fmt.Sprintf("input '%s' of component '%s' is removed", c.Name, c.Component)
+	case ChangeKind_InputTypeChanged:
+		return fmt.Sprintf("type of input '%s' of component '%s' is changed from '%v' to '%v'", c.Name, c.Component, c.Old, c.New)
+	case ChangeKind_InputRequired:
+		return fmt.Sprintf("input '%s' of component '%s' is now required", c.Name, c.Component)
+	case ChangeKind_InputDefaultChanged:
+		return fmt.Sprintf("default of input '%s' of component '%s' is changed from '%v' to '%v'", c.Name, c.Component, c.Old, c.New)
+	case ChangeKind_OutputAdded:
+		return fmt.Sprintf("output '%s' is added to component '%s'", c.Name, c.Component)
+	case ChangeKind_OutputRemoved:
+		return fmt.Sprintf("output '%s' of component '%s' is removed", c.Name, c.Component)
+	case ChangeKind_ProfileAdded:
+		return fmt.Sprintf("profile '%s' is added", c.Name)
+	case ChangeKind_ProfileRemoved:
+		return fmt.Sprintf("profile '%s' is removed", c.Name)
+	}
+
+	return string(c.Kind)
+}
+
+// Breaking returns the breaking changes.
+func (cArr Changes) Breaking() Changes {
+	breaking := Changes{}
+	for _, c := range cArr {
+		if c.Breaking {
+			breaking = append(breaking, c)
+		}
+	}
+
+	return breaking
+}
+
+// Diff returns the changes from the platform metadata to the given newer revision of it,
+// i.e. the added and removed components, the removed or retyped inputs and the removed outputs.
+// The changes are ordered by the component ID, followed by the profile changes.
+func (pm *PlatformMetadata) Diff(newer *PlatformMetadata) Changes {
+	changes := Changes{}
+
+	ids := map[string]struct{}{}
+	for _, c := range append(append(Components{}, pm.Components...), newer.Components...) {
+		ids[c.ID] = struct{}{}
+	}
+
+	sortedIDs := maps.Keys(ids)
+	sort.Strings(sortedIDs)
+	for _, id := range sortedIDs {
+		oldComp, newComp := pm.Components.GetByID(id), newer.Components.GetByID(id)
+		switch {
+		case oldComp == nil:
+			changes = append(changes, Change{Kind: ChangeKind_ComponentAdded, Component: id})
+		case newComp == nil:
+			changes = append(changes, Change{Kind: ChangeKind_ComponentRemoved, Component: id, Breaking: true})
+		default:
+			changes = append(changes, diffInputs(id, "", oldComp.Inputs, newComp.Inputs)...)
+			changes = append(changes, diffOutputs(id, oldComp.Outputs, newComp.Outputs)...)
+		}
+	}
+
+	for _, p := range pm.Profiles {
+		if !slices.ContainsFunc(newer.Profiles, func(np Profile) bool { return np.ID == p.ID }) {
+			changes = append(changes, Change{Kind: ChangeKind_ProfileRemoved, Name: p.ID, Breaking: true})
+		}
+	}
+
+	for _, p := range newer.Profiles {
+		if !slices.ContainsFunc(pm.Profiles, func(op Profile) bool { return op.ID == p.ID }) {
+			changes = append(changes, Change{Kind: ChangeKind_ProfileAdded, Name: p.ID})
+		}
+	}
+
+	return changes
+}
+
+// diffInputs returns the changes of the properties of the input schema, and of their nested properties and items.
+func diffInputs(compID, path string, oldNode, newNode *jsonschema.Node) Changes {
+	changes := Changes{}
+	if oldNode == nil {
+		oldNode = &jsonschema.Node{}
+	}
+	if newNode == nil {
+		newNode = &jsonschema.Node{}
+	}
+
+	if path != "" {
+		// the type change is breaking only if the values set for the old type are not accepted by the new one.
+		if oldNode.Type != newNode.Type {
+			if !jsonschema.IsTypeCompatible(oldNode.Type, newNode.Type) {
+				return append(changes, Change{Kind: ChangeKind_InputTypeChanged, Component: compID, Name: path, Old: oldNode.Type, New: newNode.Type, Breaking: true})
+			}
+			changes = append(changes, Change{Kind: ChangeKind_InputTypeChanged, Component: compID, Name: path, Old: oldNode.Type, New: newNode.Type})
+		}
+
+		if !reflect.DeepEqual(oldNode.Default, newNode.Default) {
+			changes = append(changes, Change{Kind: ChangeKind_InputDefaultChanged, Component: compID, Name: path, Old: oldNode.Default, New: newNode.Default})
+		}
+	}
+
+	if oldNode.Items != nil || newNode.Items != nil {
+		changes = append(changes, diffInputs(compID, path+"[*]", oldNode.Items, newNode.Items)...)
+	}
+
+	names := maps.Keys(oldNode.Properties)
+	for name := range newNode.Properties {
+		if _, ok := oldNode.Properties[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		propPath := name
+		if path != "" {
+			propPath = path + "." + name
+		}
+
+		oldProp, newProp := oldNode.Properties[name], newNode.Properties[name]
+		// the inputs are required regardless of their default, as the app manifests must set them (i.e. `@required: true`).
+		required := slices.Contains(newNode.Required, name) && newProp != nil
+		switch {
+		case oldProp == nil:
+			changes = append(changes, Change{Kind: ChangeKind_InputAdded, Component: compID, Name: propPath, Breaking: required})
+		case newProp == nil:
+			changes = append(changes, Change{Kind: ChangeKind_InputRemoved, Component: compID, Name: propPath, Breaking: true})
+		default:
+			if required && !slices.Contains(oldNode.Required, name) {
+				changes = append(changes, Change{Kind: ChangeKind_InputRequired, Component: compID, Name: propPath, Breaking: true})
+			}
+			changes = append(changes, diffInputs(compID, propPath, oldProp, newProp)...)
+		}
+	}
+
+	return changes
+}
+
+// diffOutputs returns the outputs added to or removed from the component.
+func diffOutputs(compID string, oldNode, newNode *jsonschema.Node) Changes {
+	changes := Changes{}
+	if oldNode == nil {
+		oldNode = &jsonschema.Node{}
+	}
+	if newNode == nil {
+		newNode = &jsonschema.Node{}
+	}
+
+	names := maps.Keys(oldNode.Properties)
+	for name := range newNode.Properties {
+		if _, ok := oldNode.Properties[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if _, ok := newNode.Properties[name]; !ok {
+			changes = append(changes, Change{Kind: ChangeKind_OutputRemoved, Component: compID, Name: name, Breaking: true})
+		} else if _, ok := oldNode.Properties[name]; !ok {
+			changes = append(changes, Change{Kind: ChangeKind_OutputAdded, Component: compID, Name: name})
+		}
+	}
+
+	return changes
+}
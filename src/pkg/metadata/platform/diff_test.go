@@ -0,0 +1,144 @@
+// Copyright (c) Ollion
+// SPDX-License-Identifier: Apache-2.0
+
+package platform
+
+import (
+	"testing"
+
+	"github.com/cldcvr/terrarium/src/pkg/jsonschema"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlatformMetadata_Diff(t *testing.T) {
+	postgres := func(inputs map[string]*jsonschema.Node, required []string, outputs ...string) Component {
+		c := Component{
+			ID:      "postgres",
+			Inputs:  &jsonschema.Node{Type: "object", Properties: inputs, Required: required},
+			Outputs: &jsonschema.Node{Type: "object", Properties: map[string]*jsonschema.Node{}},
+		}
+		for _, name := range outputs {
+			c.Outputs.Properties[name] = &jsonschema.Node{Type: "string"}
+		}
+		return c
+	}
+
+	tests := []struct {
+		name  string
+		old   PlatformMetadata
+		new   PlatformMetadata
+		want  Changes
+		descs []string
+	}{
+		{
+			name: "no change",
+			old:  PlatformMetadata{Components: Components{postgres(map[string]*jsonschema.Node{"version": {Type: "number", Default: 11}}, nil, "host")}},
+			new:  PlatformMetadata{Components: Components{postgres(map[string]*jsonschema.Node{"version": {Type: "number", Default: 11}}, nil, "host")}},
+			want: Changes{},
+		},
+		{
+			name: "components and profiles",
+			old: PlatformMetadata{
+				Components: Components{{ID: "postgres"}, {ID: "redis"}},
+				Profiles:   Profiles{{ID: "dev"}, {ID: "prod"}},
+			},
+			new: PlatformMetadata{
+				Components: Components{{ID: "postgres"}, {ID: "mysql"}},
+				Profiles:   Profiles{{ID: "prod"}, {ID: "staging"}},
+			},
+			want: Changes{
+				{Kind: ChangeKind_ComponentAdded, Component: "mysql"},
+				{Kind: ChangeKind_ComponentRemoved, Component: "redis", Breaking: true},
+				{Kind: ChangeKind_ProfileRemoved, Name: "dev", Breaking: true},
+				{Kind: ChangeKind_ProfileAdded, Name: "staging"},
+			},
+			descs: []string{
+				"component 'mysql' is added",
+				"component 'redis' is removed",
+				"profile 'dev' is removed",
+				"profile 'staging' is added",
+			},
+		},
+		{
+			name: "inputs",
+			old: PlatformMetadata{Components: Components{postgres(map[string]*jsonschema.Node{
+				"version":  {Type: "number", Default: 11},
+				"db_name":  {Type: "string"},
+				"port":     {Type: "number"},
+				"replicas": {Type: "integer"},
+				"tags":     {Type: "array", Items: &jsonschema.Node{Type: "object", Properties: map[string]*jsonschema.Node{"key": {Type: "string"}}}},
+				"backup":   {Type: "boolean", Default: false},
+			}, nil)}},
+			new: PlatformMetadata{Components: Components{postgres(map[string]*jsonschema.Node{
+				"version":   {Type: "number", Default: 15},
+				"port":      {Type: "string"},
+				"replicas":  {Type: "number"},
+				"tags":      {Type: "array", Items: &jsonschema.Node{Type: "object", Properties: map[string]*jsonschema.Node{"key": {Type: "number"}}}},
+				"backup":    {Type: "boolean", Default: false},
+				"storage":   {Type: "number", Default: 20},
+				"multi_az":  {Type: "boolean", Default: false},
+				"subnet_id": {Type: "string"},
+			}, []string{"backup", "storage", "subnet_id"})}},
+			want: Changes{
+				{Kind: ChangeKind_InputRequired, Component: "postgres", Name: "backup", Breaking: true},
+				{Kind: ChangeKind_InputRemoved, Component: "postgres", Name: "db_name", Breaking: true},
+				{Kind: ChangeKind_InputAdded, Component: "postgres", Name: "multi_az"},
+				{Kind: ChangeKind_InputTypeChanged, Component: "postgres", Name: "port", Old: "number", New: "string", Breaking: true},
+				{Kind: ChangeKind_InputTypeChanged, Component: "postgres", Name: "replicas", Old: "integer", New: "number"},
+				{Kind: ChangeKind_InputAdded, Component: "postgres", Name: "storage", Breaking: true},
+				{Kind: ChangeKind_InputAdded, Component: "postgres", Name: "subnet_id", Breaking: true},
+				{Kind: ChangeKind_InputTypeChanged, Component: "postgres", Name: "tags[*].key", Old: "string", New: "number", Breaking: true},
+				{Kind: ChangeKind_InputDefaultChanged, Component: "postgres", Name: "version", Old: 11, New: 15},
+			},
+			descs: []string{
+				"input 'backup' of component 'postgres' is now required",
+				"input 'db_name' of component 'postgres' is removed",
+				"input 'multi_az' is added to component 'postgres'",
+				"type of input 'port' of component 'postgres' is changed from 'number' to 'string'",
+				"type of input 'replicas' of component 'postgres' is changed from 'integer' to 'number'",
+				"required input 'storage' is added to component 'postgres'",
+				"required input 'subnet_id' is added to component 'postgres'",
+				"type of input 'tags[*].key' of component 'postgres' is changed from 'string' to 'number'",
+				"default of input 'version' of component 'postgres' is changed from '11' to '15'",
+			},
+		},
+		{
+			name: "outputs",
+			old:  PlatformMetadata{Components: Components{postgres(nil, nil, "host", "port")}},
+			new:  PlatformMetadata{Components: Components{postgres(nil, nil, "endpoint", "port")}},
+			want: Changes{
+				{Kind: ChangeKind_OutputAdded, Component: "postgres", Name: "endpoint"},
+				{Kind: ChangeKind_OutputRemoved, Component: "postgres", Name: "host", Breaking: true},
+			},
+			descs: []string{
+				"output 'endpoint' is added to component 'postgres'",
+				"output 'host' of component 'postgres' is removed",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.old.Diff(&tt.new)
+			assert.Equal(t, tt.want, got)
+
+			descs := []string{}
+			for _, c := range got {
+				descs = append(descs, c.String())
+			}
+			if tt.descs != nil {
+				assert.Equal(t, tt.descs, descs)
+			}
+		})
+	}
+}
+
+func TestChanges_Breaking(t *testing.T) {
+	changes := Changes{
+		{Kind: ChangeKind_ComponentAdded, Component: "mysql"},
+		{Kind: ChangeKind_ComponentRemoved, Component: "redis", Breaking: true},
+	}
+
+	assert.Equal(t, Changes{{Kind: ChangeKind_ComponentRemoved, Component: "redis", Breaking: true}}, changes.Breaking())
+	assert.Empty(t, Changes{}.Breaking())
+}
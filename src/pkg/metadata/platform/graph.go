@@ -9,7 +9,6 @@ import (
 
 	"github.com/cldcvr/terraform-config-inspect/tfconfig"
 	"github.com/rotisserie/eris"
-	"golang.org/x/exp/slices"
 )
 
 func NewGraph(platformModule *tfconfig.Module) Graph {
@@ -19,6 +18,7 @@ func NewGraph(platformModule *tfconfig.Module) Graph {
 }
 
 func (g *Graph) Parse(srcModule *tfconfig.Module) {
+	mb := NewModuleBlocks(srcModule)
 	toTraverse := map[BlockID]struct{}{}
 
 	for k := range srcModule.ModuleCalls {
@@ -34,18 +34,28 @@ func (g *Graph) Parse(srcModule *tfconfig.Module) {
 		toTraverse[bID] = struct{}{}
 	}
 
+	for bID := range mb.metaBlocks().blocks {
+		toTraverse[bID] = struct{}{}
+	}
+
+	parsed := map[BlockID]struct{}{}
+	for _, node := range *g {
+		parsed[node.ID] = struct{}{}
+	}
+
 	for len(toTraverse) > 0 {
 		for bID := range toTraverse {
-			if g.GetByID(bID) != nil {
-				delete(toTraverse, bID)
+			delete(toTraverse, bID)
+			if _, ok := parsed[bID]; ok {
 				continue
 			}
 
-			blockRequirements := bID.FindRequirements(srcModule)
-			g.Append(bID, blockRequirements)
-			delete(toTraverse, bID)
+			blockRequirements := mb.FindRequirements(bID)
+			node := g.Append(bID, blockRequirements)
+			node.DependsOn = mb.FindDependsOn(bID)
+			parsed[bID] = struct{}{}
 
-			for _, reqBId := range blockRequirements {
+			for _, reqBId := range node.edges() {
 				toTraverse[reqBId] = struct{}{}
 			}
 		}
@@ -75,19 +85,20 @@ type GraphWalkerCB func(blockId BlockID) error
 
 // Walk a function to traverse all requirements starting from the given nodes
 // and call cb exactly once for each node that is connected to the given set of nodes.
-// Terraform Outputs are traversed differently in the end, such that, each
-// output that is able to resolve with the blocks been traversed, are selected.
+// Terraform Outputs, along with the moved, import and check blocks and the terraform settings,
+// are traversed differently in the end, such that, each of them that is able to resolve
+// with the blocks been traversed, are selected.
 func (g *Graph) Walk(roots []BlockID, cb GraphWalkerCB) error {
-	roots = slices.Compact(roots)
-	traverser := make([]BlockID, len(roots)) // nodes before `i` are visited and after `i` are queued
-	copy(traverser, roots)
+	gi := g.Index()
+	t := &graphTraverser{queued: map[BlockID]struct{}{}}
+	t.append(roots)
 
-	err := g.traverseRootBlocks(&traverser, cb)
+	err := t.traverseRootBlocks(gi, cb)
 	if err != nil {
 		return eris.Wrap(err, "error traversing hcl blocks")
 	}
 
-	err = g.traverseOutputBlocks(&traverser, cb)
+	err = t.traverseDependentBlocks(gi, cb)
 	if err != nil {
 		return eris.Wrap(err, "error traversing hcl output blocks")
 	}
@@ -95,10 +106,26 @@ func (g *Graph) Walk(roots []BlockID, cb GraphWalkerCB) error {
 	return nil
 }
 
+// graphTraverser holds the nodes visited and queued while walking the graph.
+type graphTraverser struct {
+	order  []BlockID // nodes before `next` are visited and after `next` are queued
+	next   int
+	queued map[BlockID]struct{}
+}
+
+func (t *graphTraverser) append(ids []BlockID) {
+	for _, bID := range ids {
+		if _, ok := t.queued[bID]; !ok {
+			t.queued[bID] = struct{}{}
+			t.order = append(t.order, bID)
+		}
+	}
+}
+
 // traverse all requirements starting from the given nodes
-func (g *Graph) traverseRootBlocks(traverser *[]BlockID, cb GraphWalkerCB) error {
-	for i := 0; i < len(*traverser); i++ {
-		node := g.GetByID((*traverser)[i])
+func (t *graphTraverser) traverseRootBlocks(gi *GraphIndex, cb GraphWalkerCB) error {
+	for ; t.next < len(t.order); t.next++ {
+		node := gi.GetByID(t.order[t.next])
 		if node == nil {
 			continue
 		}
@@ -108,44 +135,63 @@ func (g *Graph) traverseRootBlocks(traverser *[]BlockID, cb GraphWalkerCB) error
 			return err
 		}
 
-		g.appendRequirements(traverser, node.Requirements)
+		t.append(node.edges())
 	}
 
 	return nil
 }
 
-func (g *Graph) appendRequirements(traverser *[]BlockID, requirements []BlockID) {
-	for _, bID := range requirements {
-		if !slices.Contains(*traverser, bID) {
-			*traverser = append(*traverser, bID)
-		}
-	}
+// blocks that are pulled along with the blocks they refer to, instead of being required by them.
+var dependentBlockTypes = map[BlockType]struct{}{
+	BlockType_Output:    {},
+	BlockType_Moved:     {},
+	BlockType_Import:    {},
+	BlockType_Check:     {},
+	BlockType_Terraform: {},
 }
 
-// traverse outputs whose requirements are already traversed.
-func (g *Graph) traverseOutputBlocks(traverser *[]BlockID, cb GraphWalkerCB) error {
-	for _, node := range *g {
-		bt, _ := node.ID.Parse()
-		if bt != BlockType_Output {
-			continue
-		}
+// traverse outputs and other dependent blocks whose requirements are already traversed.
+// The blocks in the depends_on of an output can resolve the requirements of the blocks checked
+// before it, hence the dependent blocks are checked again until a pass traverses no new block.
+func (t *graphTraverser) traverseDependentBlocks(gi *GraphIndex, cb GraphWalkerCB) error {
+	visited := map[BlockID]struct{}{}
+	for traversed := -1; traversed != len(t.order)+len(visited); {
+		traversed = len(t.order) + len(visited)
+
+		for _, node := range gi.graph {
+			bt, _ := node.ID.Parse()
+			if _, ok := dependentBlockTypes[bt]; !ok {
+				continue
+			}
 
-		if !g.allDependenciesTraversed(traverser, node.Requirements) {
-			continue
-		}
+			if _, ok := visited[node.ID]; ok {
+				continue
+			}
 
-		err := cb(node.ID)
-		if err != nil {
-			return err
+			if _, ok := t.queued[node.ID]; ok || !t.allTraversed(node.Requirements) {
+				continue
+			}
+
+			visited[node.ID] = struct{}{}
+			err := cb(node.ID)
+			if err != nil {
+				return err
+			}
+
+			// the blocks in the depends_on of an output are pulled along with it.
+			t.append(node.DependsOn)
+			if err := t.traverseRootBlocks(gi, cb); err != nil {
+				return err
+			}
 		}
 	}
 
 	return nil
 }
 
-func (g *Graph) allDependenciesTraversed(traverser *[]BlockID, requirements []BlockID) bool {
-	for _, bId := range requirements {
-		if !slices.Contains(*traverser, bId) {
+func (t *graphTraverser) allTraversed(requirements []BlockID) bool {
+	for _, bID := range requirements {
+		if _, ok := t.queued[bID]; !ok {
 			return false
 		}
 	}
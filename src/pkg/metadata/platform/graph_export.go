@@ -0,0 +1,187 @@
+// Copyright (c) Ollion
+// SPDX-License-Identifier: Apache-2.0
+
+package platform
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/rotisserie/eris"
+)
+
+// GraphFormat is the format the graph is exported in.
+type GraphFormat string
+
+const (
+	GraphFormatDOT     GraphFormat = "dot"     // Graphviz DOT format
+	GraphFormatMermaid GraphFormat = "mermaid" // Mermaid flowchart format
+)
+
+// graphNodeClass is the highlighting class of a node in the exported graph.
+type graphNodeClass string
+
+const (
+	graphNodeClassComponent graphNodeClass = "component"
+	graphNodeClassVariable  graphNodeClass = "variable"
+	graphNodeClassLocal     graphNodeClass = "local"
+	graphNodeClassOutput    graphNodeClass = "output"
+	graphNodeClassOther     graphNodeClass = ""
+)
+
+var (
+	dotNodeAttrs = map[graphNodeClass]string{
+		graphNodeClassComponent: `shape=box, style="filled,bold", fillcolor="#a6cee3"`,
+		graphNodeClassVariable:  `shape=parallelogram, style=filled, fillcolor="#b2df8a"`,
+		graphNodeClassLocal:     `shape=note, style=filled, fillcolor="#fdbf6f"`,
+		graphNodeClassOutput:    `shape=ellipse, style=filled, fillcolor="#fb9a99"`,
+		graphNodeClassOther:     `shape=box`,
+	}
+
+	mermaidClassDefs = []string{
+		"classDef component fill:#a6cee3,stroke:#1f78b4,stroke-width:2px",
+		"classDef variable fill:#b2df8a,stroke:#33a02c",
+		"classDef local fill:#fdbf6f,stroke:#ff7f00",
+		"classDef output fill:#fb9a99,stroke:#e31a1c",
+	}
+)
+
+// GraphFormats returns the list of supported graph export formats.
+func GraphFormats() []string {
+	return []string{string(GraphFormatDOT), string(GraphFormatMermaid)}
+}
+
+// Subgraph returns the part of the graph that is pulled when walking it from the given roots,
+// i.e. the blocks that end up in the generated code for the given components.
+func (g *Graph) Subgraph(roots []BlockID) (Graph, error) {
+	visited := map[BlockID]struct{}{}
+	err := g.Walk(roots, func(bID BlockID) error {
+		visited[bID] = struct{}{}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sub := Graph{}
+	for _, node := range *g {
+		if _, ok := visited[node.ID]; !ok {
+			continue
+		}
+
+		requirements := []BlockID{}
+		for _, reqID := range node.Requirements {
+			if _, ok := visited[reqID]; ok {
+				requirements = append(requirements, reqID)
+			}
+		}
+
+		var dependsOn []BlockID
+		for _, depID := range node.DependsOn {
+			if _, ok := visited[depID]; ok {
+				dependsOn = append(dependsOn, depID)
+			}
+		}
+		sub.Append(node.ID, requirements).DependsOn = dependsOn
+	}
+
+	return sub, nil
+}
+
+// Export writes the graph to w in the given format. Each node points to the nodes it requires,
+// with a dashed line to the nodes in the depends_on of an output, and the components, variables,
+// locals and outputs are highlighted.
+func (g Graph) Export(w io.Writer, format GraphFormat) error {
+	var sb strings.Builder
+	switch format {
+	case GraphFormatDOT:
+		g.writeDOT(&sb)
+	case GraphFormatMermaid:
+		g.writeMermaid(&sb)
+	default:
+		return eris.Errorf("unsupported graph format '%s'. must be one of: %s", format, strings.Join(GraphFormats(), ", "))
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return eris.Wrap(err, "failed to write the graph")
+}
+
+func (g Graph) writeDOT(sb *strings.Builder) {
+	sb.WriteString("digraph platform {\n")
+	sb.WriteString("  rankdir=LR;\n")
+	sb.WriteString("  node [fontname=\"Helvetica\"];\n")
+	for _, node := range g {
+		fmt.Fprintf(sb, "  %q [%s];\n", node.ID, dotNodeAttrs[node.ID.graphNodeClass()])
+	}
+
+	for _, node := range g {
+		for _, reqID := range sortedBlockIDs(node.Requirements) {
+			fmt.Fprintf(sb, "  %q -> %q;\n", node.ID, reqID)
+		}
+		for _, depID := range sortedBlockIDs(node.DependsOn) {
+			fmt.Fprintf(sb, "  %q -> %q [style=dashed];\n", node.ID, depID)
+		}
+	}
+	sb.WriteString("}\n")
+}
+
+func (g Graph) writeMermaid(sb *strings.Builder) {
+	// mermaid node ids can not contain all the characters allowed in the block ids, hence the ids are indexed.
+	nodeIDs := make(map[BlockID]string, len(g))
+	for i, node := range g {
+		nodeIDs[node.ID] = fmt.Sprintf("n%d", i)
+	}
+
+	sb.WriteString("flowchart LR\n")
+	for _, classDef := range mermaidClassDefs {
+		fmt.Fprintf(sb, "  %s\n", classDef)
+	}
+
+	for _, node := range g {
+		fmt.Fprintf(sb, "  %s[\"%s\"]", nodeIDs[node.ID], strings.ReplaceAll(string(node.ID), `"`, "#quot;"))
+		if class := node.ID.graphNodeClass(); class != graphNodeClassOther {
+			fmt.Fprintf(sb, ":::%s", class)
+		}
+		sb.WriteString("\n")
+	}
+
+	for _, node := range g {
+		for _, reqID := range sortedBlockIDs(node.Requirements) {
+			if reqNodeID, ok := nodeIDs[reqID]; ok {
+				fmt.Fprintf(sb, "  %s --> %s\n", nodeIDs[node.ID], reqNodeID)
+			}
+		}
+		for _, depID := range sortedBlockIDs(node.DependsOn) {
+			if depNodeID, ok := nodeIDs[depID]; ok {
+				fmt.Fprintf(sb, "  %s -.-> %s\n", nodeIDs[node.ID], depNodeID)
+			}
+		}
+	}
+}
+
+func (bID BlockID) graphNodeClass() graphNodeClass {
+	bt, name := bID.Parse()
+	switch bt {
+	case BlockType_ModuleCall:
+		if strings.HasPrefix(name, ComponentPrefix) {
+			return graphNodeClassComponent
+		}
+	case BlockType_Variable:
+		return graphNodeClassVariable
+	case BlockType_Local:
+		return graphNodeClassLocal
+	case BlockType_Output:
+		return graphNodeClassOutput
+	}
+
+	return graphNodeClassOther
+}
+
+func sortedBlockIDs(ids []BlockID) []BlockID {
+	sorted := make([]BlockID, len(ids))
+	copy(sorted, ids)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted
+}
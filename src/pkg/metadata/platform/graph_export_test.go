@@ -0,0 +1,108 @@
+// Copyright (c) Ollion
+// SPDX-License-Identifier: Apache-2.0
+
+package platform
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var exportTestGraph = Graph{
+	{ID: "local.tr_component_postgres", Requirements: []BlockID{}},
+	{ID: "module.tr_component_postgres", Requirements: []BlockID{"var.db_class", "local.tr_component_postgres", "module.vpc"}},
+	{ID: "module.tr_component_redis", Requirements: []BlockID{"module.vpc"}},
+	{ID: "module.vpc", Requirements: []BlockID{}},
+	{ID: "output.tr_component_postgres_host", Requirements: []BlockID{"module.tr_component_postgres"}},
+	{ID: "output.tr_component_redis_host", Requirements: []BlockID{"module.tr_component_redis"}},
+	{ID: "var.db_class", Requirements: []BlockID{}},
+}
+
+func TestGraph_Subgraph(t *testing.T) {
+	sub, err := exportTestGraph.Subgraph([]BlockID{"module.tr_component_postgres"})
+	require.NoError(t, err)
+	assert.Equal(t, Graph{
+		{ID: "local.tr_component_postgres", Requirements: []BlockID{}},
+		{ID: "module.tr_component_postgres", Requirements: []BlockID{"var.db_class", "local.tr_component_postgres", "module.vpc"}},
+		{ID: "module.vpc", Requirements: []BlockID{}},
+		{ID: "output.tr_component_postgres_host", Requirements: []BlockID{"module.tr_component_postgres"}},
+		{ID: "var.db_class", Requirements: []BlockID{}},
+	}, sub)
+}
+
+func TestGraph_Export(t *testing.T) {
+	g := Graph{
+		{ID: "module.tr_component_postgres", Requirements: []BlockID{"var.db_class", "local.db_name", "module.vpc"}},
+		{ID: "local.db_name", Requirements: []BlockID{}},
+		{ID: "module.vpc", Requirements: []BlockID{}},
+		{ID: "output.tr_component_postgres_host", Requirements: []BlockID{"module.tr_component_postgres"}},
+		{ID: "var.db_class", Requirements: []BlockID{}},
+	}
+
+	tests := []struct {
+		name    string
+		format  GraphFormat
+		want    string
+		wantErr string
+	}{
+		{
+			name:   "dot",
+			format: GraphFormatDOT,
+			want: `digraph platform {
+  rankdir=LR;
+  node [fontname="Helvetica"];
+  "module.tr_component_postgres" [shape=box, style="filled,bold", fillcolor="#a6cee3"];
+  "local.db_name" [shape=note, style=filled, fillcolor="#fdbf6f"];
+  "module.vpc" [shape=box];
+  "output.tr_component_postgres_host" [shape=ellipse, style=filled, fillcolor="#fb9a99"];
+  "var.db_class" [shape=parallelogram, style=filled, fillcolor="#b2df8a"];
+  "module.tr_component_postgres" -> "local.db_name";
+  "module.tr_component_postgres" -> "module.vpc";
+  "module.tr_component_postgres" -> "var.db_class";
+  "output.tr_component_postgres_host" -> "module.tr_component_postgres";
+}
+`,
+		},
+		{
+			name:   "mermaid",
+			format: GraphFormatMermaid,
+			want: `flowchart LR
+  classDef component fill:#a6cee3,stroke:#1f78b4,stroke-width:2px
+  classDef variable fill:#b2df8a,stroke:#33a02c
+  classDef local fill:#fdbf6f,stroke:#ff7f00
+  classDef output fill:#fb9a99,stroke:#e31a1c
+  n0["module.tr_component_postgres"]:::component
+  n1["local.db_name"]:::local
+  n2["module.vpc"]
+  n3["output.tr_component_postgres_host"]:::output
+  n4["var.db_class"]:::variable
+  n0 --> n1
+  n0 --> n2
+  n0 --> n4
+  n3 --> n0
+`,
+		},
+		{
+			name:    "unsupported",
+			format:  "svg",
+			wantErr: "unsupported graph format 'svg'. must be one of: dot, mermaid",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sb strings.Builder
+			err := g.Export(&sb, tt.format)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, sb.String())
+		})
+	}
+}
@@ -0,0 +1,177 @@
+// Copyright (c) Ollion
+// SPDX-License-Identifier: Apache-2.0
+
+package platform
+
+import (
+	"strings"
+
+	"github.com/rotisserie/eris"
+)
+
+// ErrGraphCycle is returned when the terraform blocks require each other in a cycle, i.e. two locals referring to each other.
+var ErrGraphCycle = eris.New("cycle in the terraform blocks graph")
+
+// GraphIndex indexes the graph nodes by their ID, and the nodes requiring each node,
+// for constant time lookups in large platforms.
+type GraphIndex struct {
+	graph      Graph
+	byID       map[BlockID]int
+	requiredBy map[BlockID][]BlockID
+}
+
+// Index returns the index of the graph. The index must be rebuilt when the graph is changed.
+func (g Graph) Index() *GraphIndex {
+	gi := &GraphIndex{
+		graph:      g,
+		byID:       make(map[BlockID]int, len(g)),
+		requiredBy: map[BlockID][]BlockID{},
+	}
+
+	for i, node := range g {
+		gi.byID[node.ID] = i
+		for _, reqID := range node.edges() {
+			gi.requiredBy[reqID] = appendSortedUnique(gi.requiredBy[reqID], node.ID)
+		}
+	}
+
+	return gi
+}
+
+// GetByID returns the graph node with the given ID, or nil if not found.
+func (gi *GraphIndex) GetByID(id BlockID) *GraphNode {
+	if i, ok := gi.byID[id]; ok {
+		return &gi.graph[i]
+	}
+
+	return nil
+}
+
+// RequiredBy returns the sorted IDs of the nodes that require the given node directly.
+func (gi *GraphIndex) RequiredBy(id BlockID) []BlockID {
+	return gi.requiredBy[id]
+}
+
+// AllRequiredBy returns the sorted IDs of the nodes that require the given node directly or through other nodes,
+// i.e. the blocks affected by a change to 'var.region'.
+func (gi *GraphIndex) AllRequiredBy(id BlockID) []BlockID {
+	result := []BlockID{}
+	visited := map[BlockID]struct{}{id: {}}
+	queue := []BlockID{id}
+	for len(queue) > 0 {
+		for _, reqByID := range gi.requiredBy[queue[0]] {
+			if _, ok := visited[reqByID]; ok {
+				continue
+			}
+
+			visited[reqByID] = struct{}{}
+			result = appendSortedUnique(result, reqByID)
+			queue = append(queue, reqByID)
+		}
+		queue = queue[1:]
+	}
+
+	return result
+}
+
+// TopologicalSort returns the IDs of the graph nodes ordered such that each node comes after the nodes it requires.
+// The order is deterministic, the nodes that do not require each other are ordered by their ID.
+// Returns ErrGraphCycle with the chain of blocks if the nodes require each other in a cycle.
+// Requirements that are not nodes of the graph are ignored.
+func (gi *GraphIndex) TopologicalSort() ([]BlockID, error) {
+	if cycle := gi.FindCycle(); cycle != nil {
+		return nil, eris.Wrap(ErrGraphCycle, formatBlockChain(cycle))
+	}
+
+	sorted := make([]BlockID, 0, len(gi.graph))
+	visited := make(map[BlockID]struct{}, len(gi.graph))
+	var visit func(id BlockID)
+	visit = func(id BlockID) {
+		if _, ok := visited[id]; ok {
+			return
+		}
+		visited[id] = struct{}{}
+
+		node := gi.GetByID(id)
+		for _, reqID := range sortedBlockIDs(node.edges()) {
+			if gi.GetByID(reqID) != nil {
+				visit(reqID)
+			}
+		}
+		sorted = append(sorted, id)
+	}
+
+	for _, id := range gi.sortedIDs() {
+		visit(id)
+	}
+
+	return sorted, nil
+}
+
+// FindCycle returns the chain of blocks that require each other in a cycle, starting and ending with the same block,
+// i.e. [local.a, local.b, local.a], or nil if the graph has no cycle.
+func (gi *GraphIndex) FindCycle() []BlockID {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+
+	state := make(map[BlockID]int, len(gi.graph))
+	path := []BlockID{}
+	var visit func(id BlockID) []BlockID
+	visit = func(id BlockID) []BlockID {
+		switch state[id] {
+		case visited:
+			return nil
+		case visiting:
+			for i, pathID := range path {
+				if pathID == id {
+					return append(append([]BlockID{}, path[i:]...), id)
+				}
+			}
+		}
+
+		state[id] = visiting
+		path = append(path, id)
+		for _, reqID := range sortedBlockIDs(gi.GetByID(id).edges()) {
+			if gi.GetByID(reqID) == nil {
+				continue
+			}
+
+			if cycle := visit(reqID); cycle != nil {
+				return cycle
+			}
+		}
+		path = path[:len(path)-1]
+		state[id] = visited
+
+		return nil
+	}
+
+	for _, id := range gi.sortedIDs() {
+		if cycle := visit(id); cycle != nil {
+			return cycle
+		}
+	}
+
+	return nil
+}
+
+func (gi *GraphIndex) sortedIDs() []BlockID {
+	ids := make([]BlockID, 0, len(gi.graph))
+	for _, node := range gi.graph {
+		ids = append(ids, node.ID)
+	}
+
+	return sortedBlockIDs(ids)
+}
+
+// formatBlockChain formats the blocks as "local.a -> local.b -> local.a".
+func formatBlockChain(chain []BlockID) string {
+	strs := make([]string, 0, len(chain))
+	for _, id := range chain {
+		strs = append(strs, string(id))
+	}
+
+	return strings.Join(strs, " -> ")
+}
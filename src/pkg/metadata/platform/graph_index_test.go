@@ -0,0 +1,93 @@
+// Copyright (c) Ollion
+// SPDX-License-Identifier: Apache-2.0
+
+package platform
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGraphIndex_TopologicalSort(t *testing.T) {
+	tests := []struct {
+		name      string
+		graph     Graph
+		want      []BlockID
+		wantCycle []BlockID
+	}{
+		{
+			name: "requirements first",
+			graph: Graph{
+				{ID: "module.tr_component_postgres", Requirements: []BlockID{"local.db_name", "var.region"}},
+				{ID: "output.tr_component_postgres_host", Requirements: []BlockID{"module.tr_component_postgres"}},
+				{ID: "local.db_name", Requirements: []BlockID{"var.prefix"}},
+				{ID: "var.prefix"},
+				{ID: "var.region"},
+			},
+			want: []BlockID{"var.prefix", "local.db_name", "var.region", "module.tr_component_postgres", "output.tr_component_postgres_host"},
+		},
+		{
+			name: "requirements outside the graph are ignored",
+			graph: Graph{
+				{ID: "module.tr_component_postgres", Requirements: []BlockID{"var.region"}},
+			},
+			want: []BlockID{"module.tr_component_postgres"},
+		},
+		{
+			name: "cycle",
+			graph: Graph{
+				{ID: "module.tr_component_postgres", Requirements: []BlockID{"local.a"}},
+				{ID: "local.a", Requirements: []BlockID{"local.b"}},
+				{ID: "local.b", Requirements: []BlockID{"local.c"}},
+				{ID: "local.c", Requirements: []BlockID{"local.a"}},
+			},
+			wantCycle: []BlockID{"local.a", "local.b", "local.c", "local.a"},
+		},
+		{
+			name: "self reference",
+			graph: Graph{
+				{ID: "local.a", Requirements: []BlockID{"local.a"}},
+			},
+			wantCycle: []BlockID{"local.a", "local.a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gi := tt.graph.Index()
+			assert.Equal(t, tt.wantCycle, gi.FindCycle())
+
+			got, err := gi.TopologicalSort()
+			if tt.wantCycle != nil {
+				assert.ErrorIs(t, err, ErrGraphCycle)
+				assert.ErrorContains(t, err, formatBlockChain(tt.wantCycle))
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestGraphIndex_RequiredBy(t *testing.T) {
+	gi := Graph{
+		{ID: "module.tr_component_postgres", Requirements: []BlockID{"local.db_name", "var.region"}},
+		{ID: "module.tr_component_redis", Requirements: []BlockID{"var.region"}},
+		{ID: "output.tr_component_postgres_host", Requirements: []BlockID{"module.tr_component_postgres"}},
+		{ID: "local.db_name", Requirements: []BlockID{"var.prefix"}},
+		{ID: "var.prefix"},
+		{ID: "var.region"},
+	}.Index()
+
+	assert.Equal(t, &GraphNode{ID: "var.prefix"}, gi.GetByID("var.prefix"))
+	assert.Nil(t, gi.GetByID("var.unknown"))
+
+	assert.Equal(t, []BlockID{"module.tr_component_postgres", "module.tr_component_redis"}, gi.RequiredBy("var.region"))
+	assert.Empty(t, gi.RequiredBy("output.tr_component_postgres_host"))
+
+	assert.Equal(t, []BlockID{"local.db_name", "module.tr_component_postgres", "output.tr_component_postgres_host"}, gi.AllRequiredBy("var.prefix"))
+	assert.Equal(t, []BlockID{"module.tr_component_postgres", "module.tr_component_redis", "output.tr_component_postgres_host"}, gi.AllRequiredBy("var.region"))
+}
@@ -5,11 +5,14 @@ package platform
 
 import (
 	"errors"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/cldcvr/terraform-config-inspect/tfconfig"
 	"github.com/rotisserie/eris"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestGraph_GetByID(t *testing.T) {
@@ -107,13 +110,13 @@ func TestParseWithNestedModules(t *testing.T) {
 	// Test Parse with nested modules
 	graph := NewGraph(module)
 	assert.Equal(t, Graph{
-		{"data.resource_type.label2", []BlockID{}},
-		{"local.local1", []BlockID{}},
-		{"module.module2", []BlockID{"resource.resource_type.label1"}},
-		{"module.tr_component_module1", []BlockID{"data.resource_type.label2", "local.local1", "module.module2", "var.var1"}},
-		{"output.output1", []BlockID{}},
-		{"resource.resource_type.label1", []BlockID{}},
-		{"var.var1", []BlockID{}},
+		{ID: "data.resource_type.label2", Requirements: []BlockID{}},
+		{ID: "local.local1", Requirements: []BlockID{}},
+		{ID: "module.module2", Requirements: []BlockID{"resource.resource_type.label1"}},
+		{ID: "module.tr_component_module1", Requirements: []BlockID{"data.resource_type.label2", "local.local1", "module.module2", "var.var1"}},
+		{ID: "output.output1", Requirements: []BlockID{}},
+		{ID: "resource.resource_type.label1", Requirements: []BlockID{}},
+		{ID: "var.var1", Requirements: []BlockID{}},
 	}, graph)
 }
 
@@ -144,6 +147,17 @@ func TestWalk(t *testing.T) {
 			roots:        []BlockID{"A", "A", "Z"},
 			expectedPath: []BlockID{"A", "Z", "B", "D", "output.A", "output.B"},
 		},
+		{
+			name: "should walk the outputs resolved by the depends_on of a later output",
+			graph: Graph{
+				GraphNode{ID: "A", Requirements: []BlockID{}},
+				GraphNode{ID: "B", Requirements: []BlockID{}},
+				GraphNode{ID: "output.A", Requirements: []BlockID{"B"}},
+				GraphNode{ID: "output.B", Requirements: []BlockID{"A"}, DependsOn: []BlockID{"B"}},
+			},
+			roots:        []BlockID{"A"},
+			expectedPath: []BlockID{"A", "output.B", "B", "output.A"},
+		},
 		{
 			name:  "should return error if walker function returns error",
 			graph: defaultG,
@@ -191,3 +205,82 @@ func TestWalk(t *testing.T) {
 		})
 	}
 }
+
+func TestGraph_Parse_metaBlocks(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.tf"), []byte(`
+terraform {
+  required_version = ">= 1.5"
+  backend "s3" {}
+}
+
+resource "aws_iam_role" "db" {
+  name = "db"
+}
+
+resource "aws_s3_bucket" "logs" {
+  bucket = "logs"
+}
+
+module "tr_component_postgres" {
+  source     = "./postgres"
+  depends_on = [aws_iam_role.db]
+}
+
+module "tr_component_redis" {
+  source = "./redis"
+}
+
+output "tr_component_postgres_host" {
+  value      = { for k, v in module.tr_component_postgres : k => v.host }
+  depends_on = [aws_s3_bucket.logs]
+}
+
+moved {
+  from = aws_iam_role.old
+  to   = aws_iam_role.db
+}
+
+import {
+  to = aws_iam_role.db
+  id = "db-role"
+}
+
+check "db_role" {
+  assert {
+    condition     = aws_iam_role.db.name != ""
+    error_message = "db role name is empty"
+  }
+}
+`), 0o644))
+
+	m, diags := tfconfig.LoadModule(dir, &tfconfig.ResolvedModulesSchema{})
+	require.False(t, diags.HasErrors(), diags.Error())
+
+	g := NewGraph(m)
+	assert.Equal(t, []BlockID{"resource.aws_iam_role.db"}, g.GetByID("module.tr_component_postgres").Requirements, "depends_on of a module call")
+	assert.Equal(t, []BlockID{"module.tr_component_postgres"}, g.GetByID("output.tr_component_postgres_host").Requirements)
+	assert.Equal(t, []BlockID{"resource.aws_s3_bucket.logs"}, g.GetByID("output.tr_component_postgres_host").DependsOn)
+	assert.Equal(t, []BlockID{"resource.aws_iam_role.db"}, g.GetByID("moved.aws_iam_role.db").Requirements)
+	assert.Equal(t, []BlockID{"resource.aws_iam_role.db"}, g.GetByID("import.aws_iam_role.db").Requirements)
+	assert.Equal(t, []BlockID{"resource.aws_iam_role.db"}, g.GetByID("check.db_role").Requirements)
+	assert.NotNil(t, g.GetByID("terraform.backend"))
+	assert.NotNil(t, g.GetByID("terraform.required_version"))
+
+	walk := func(roots ...BlockID) []BlockID {
+		traversed := []BlockID{}
+		require.NoError(t, g.Walk(roots, func(bID BlockID) error {
+			traversed = append(traversed, bID)
+			return nil
+		}))
+		return traversed
+	}
+
+	assert.ElementsMatch(t, []BlockID{
+		"module.tr_component_postgres", "resource.aws_iam_role.db", "provider.aws", "output.tr_component_postgres_host", "resource.aws_s3_bucket.logs",
+		"moved.aws_iam_role.db", "import.aws_iam_role.db", "check.db_role", "terraform.backend", "terraform.required_version",
+	}, walk("module.tr_component_postgres"))
+	assert.ElementsMatch(t, []BlockID{
+		"module.tr_component_redis", "terraform.backend", "terraform.required_version",
+	}, walk("module.tr_component_redis"))
+}
@@ -0,0 +1,191 @@
+// Copyright (c) Ollion
+// SPDX-License-Identifier: Apache-2.0
+
+package platform
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/cldcvr/terraform-config-inspect/tfconfig"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+const (
+	hclBlockMoved             = "moved"
+	hclBlockImport            = "import"
+	hclBlockCheck             = "check"
+	hclBlockTerraform         = "terraform"
+	hclBlockOutput            = "output"
+	hclBlockRequiredProviders = "required_providers"
+	hclAttrTo                 = "to"
+	hclAttrDependsOn          = "depends_on"
+)
+
+// MetaBlock is a top-level block that is not loaded by tfconfig, i.e. a 'moved', 'import' or 'check' block,
+// or a setting of the 'terraform' block (i.e. the backend). It is pulled along with the blocks it refers to.
+type MetaBlock struct {
+	Pos          tfconfig.SourcePos
+	Dependencies map[string]tfconfig.AttributeReference
+}
+
+func (b *MetaBlock) GetPos() tfconfig.SourcePos {
+	return b.Pos
+}
+
+func (b *MetaBlock) GetDependencies() map[string]tfconfig.AttributeReference {
+	return b.Dependencies
+}
+
+// NewMetaBlockID returns the ID of a block identified by the address it refers to, i.e. the 'to' address of a 'moved' block.
+// The whitespaces in the address are removed, so that the ID does not depend on the formatting.
+func NewMetaBlockID(bt BlockType, address string) BlockID {
+	return NewBlockID(bt, strings.Join(strings.Fields(address), ""))
+}
+
+type metaBlocks struct {
+	blocks          map[BlockID]*MetaBlock
+	outputDependsOn map[string]map[string]tfconfig.AttributeReference // by output name
+}
+
+// ModuleBlocks looks up the blocks of a module, including the blocks not loaded by tfconfig,
+// which are parsed from the module files the first time they are looked up.
+type ModuleBlocks struct {
+	Module *tfconfig.Module
+	meta   *metaBlocks
+}
+
+func NewModuleBlocks(m *tfconfig.Module) *ModuleBlocks {
+	return &ModuleBlocks{Module: m}
+}
+
+func (mb *ModuleBlocks) metaBlocks() *metaBlocks {
+	if mb.meta == nil {
+		mb.meta = parseMetaBlocks(mb.Module)
+	}
+
+	return mb.meta
+}
+
+// parseMetaBlocks returns the blocks of the module that are not loaded by tfconfig, and the depends_on of the outputs.
+func parseMetaBlocks(m *tfconfig.Module) *metaBlocks {
+	mb := &metaBlocks{blocks: map[BlockID]*MetaBlock{}, outputDependsOn: map[string]map[string]tfconfig.AttributeReference{}}
+	if m.Path != "" {
+		files, _ := filepath.Glob(filepath.Join(m.Path, "*.tf"))
+		sort.Strings(files)
+		for _, file := range files {
+			mb.parseFile(file)
+		}
+	}
+
+	return mb
+}
+
+func (mb *metaBlocks) parseFile(filename string) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return
+	}
+
+	f, diags := hclsyntax.ParseConfig(content, filename, hcl.InitialPos)
+	if diags.HasErrors() {
+		return // the errors are reported by tfconfig
+	}
+
+	body, ok := f.Body.(*hclsyntax.Body)
+	if !ok {
+		return
+	}
+
+	for _, b := range body.Blocks {
+		switch b.Type {
+		case hclBlockMoved, hclBlockImport:
+			to, ok := b.Body.Attributes[hclAttrTo]
+			if !ok {
+				continue
+			}
+
+			bt := BlockType_Moved
+			if b.Type == hclBlockImport {
+				bt = BlockType_Import
+			}
+
+			address := string(to.Expr.Range().SliceBytes(content))
+			mb.blocks[NewMetaBlockID(bt, address)] = newMetaBlock(b.DefRange(), b.Body)
+		case hclBlockCheck:
+			if len(b.Labels) > 0 {
+				mb.blocks[NewBlockID(BlockType_Check, b.Labels[0])] = newMetaBlock(b.DefRange(), b.Body)
+			}
+		case hclBlockTerraform:
+			for name, attr := range b.Body.Attributes {
+				mb.blocks[NewBlockID(BlockType_Terraform, name)] = newMetaBlock(attr.NameRange, nil)
+			}
+			for _, nb := range b.Body.Blocks {
+				if nb.Type != hclBlockRequiredProviders {
+					mb.blocks[NewBlockID(BlockType_Terraform, nb.Type)] = newMetaBlock(nb.DefRange(), nil)
+				}
+			}
+		case hclBlockOutput:
+			if dependsOn, ok := b.Body.Attributes[hclAttrDependsOn]; ok && len(b.Labels) > 0 {
+				deps := map[string]tfconfig.AttributeReference{}
+				collectDependencies(dependsOn.Expr, deps)
+				mb.outputDependsOn[b.Labels[0]] = deps
+			}
+		}
+	}
+}
+
+func newMetaBlock(r hcl.Range, body *hclsyntax.Body) *MetaBlock {
+	b := &MetaBlock{
+		Pos: tfconfig.SourcePos{
+			Filename:  r.Filename,
+			Line:      r.Start.Line,
+			StartByte: r.Start.Byte,
+			EndLine:   r.End.Line,
+			EndByte:   r.End.Byte,
+		},
+		Dependencies: map[string]tfconfig.AttributeReference{},
+	}
+
+	if body != nil {
+		collectBodyDependencies(body, b.Dependencies)
+	}
+
+	return b
+}
+
+// collectBodyDependencies collects the references in the attributes of the body and of its nested blocks.
+func collectBodyDependencies(body *hclsyntax.Body, out map[string]tfconfig.AttributeReference) {
+	for _, attr := range body.Attributes {
+		collectDependencies(attr.Expr, out)
+	}
+
+	for _, nb := range body.Blocks {
+		collectBodyDependencies(nb.Body, out)
+	}
+}
+
+// collectDependencies collects the blocks referred by the expression, the same way tfconfig collects the block dependencies.
+func collectDependencies(expr hcl.Expression, out map[string]tfconfig.AttributeReference) {
+	for _, traversal := range expr.Variables() {
+		ref := tfconfig.ResourceAttributeReference{ResourceType: traversal.RootName()}
+		if ref.ResourceType == "data" {
+			traversal = traversal[1:] // data resources are referenced as data.<type>.<name>.<attribute>
+		}
+
+		for i, step := range traversal {
+			if attr, ok := step.(hcl.TraverseAttr); ok {
+				if i == 0 {
+					ref.ResourceType = attr.Name
+				} else if i == 1 {
+					ref.ResourceName = attr.Name
+				}
+			}
+		}
+
+		out[ref.Root()] = ref
+	}
+}
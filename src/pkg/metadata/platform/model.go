@@ -12,6 +12,9 @@ import (
 const (
 	ComponentDocPrefix = "component"     // Documentation comment prefix (i.e. "component[<name-override>]: <description>")
 	ComponentPrefix    = "tr_component_" // Prefix for component identifiers in terraform code
+	TaxonPrefix        = "tr_taxon_"     // Prefix for taxon switch identifiers in terraform code (i.e. "tr_taxon_<level>_enabled")
+	SwitchSuffix       = "_enabled"      // Suffix for component and taxon switch identifiers in terraform code
+	ImplSeparator      = "__"            // Separator of the dependency interface and the implementation in component identifiers (i.e. "postgres__v11")
 )
 
 // Profile represents a set of pre-set configuration variables that can be applied to generated Terraform code.
@@ -19,6 +22,7 @@ type Profile struct {
 	ID          string `yaml:",omitempty"` // Unique identifier for the profile
 	Title       string `yaml:",omitempty"` // Descriptive title for the profile
 	Description string `yaml:",omitempty"` // Detailed description of the profile's properties
+	Extends     string `yaml:",omitempty"` // ID of the profile whose configuration is inherited and overridden by this profile
 }
 
 // Profiles is a slice of Profile objects.
@@ -31,6 +35,9 @@ type Component struct {
 	Description string           `yaml:",omitempty"` // Detailed description of the component's functionality
 	Inputs      *jsonschema.Node `yaml:",omitempty"` // Input parameters required by the component
 	Outputs     *jsonschema.Node `yaml:",omitempty"` // Output properties produced by the component
+	Versions    string           `yaml:",omitempty"` // Version constraint of the dependency supported by the implementation (i.e. ">= 11, < 12")
+
+	docErrs []error // Errors in the doc comment tags of the inputs
 }
 
 // Components is a slice of Component objects.
@@ -52,6 +59,20 @@ type BlockID string
 type GraphNode struct {
 	ID           BlockID   // Unique identifier for the graph node
 	Requirements []BlockID // IDs of other graph nodes that the current node depends on
+	DependsOn    []BlockID `yaml:",omitempty"` // IDs of the graph nodes in the depends_on of an output, pulled along with it
+}
+
+// edges returns the IDs of the graph nodes required by the node, including its depends_on.
+func (n GraphNode) edges() []BlockID {
+	if len(n.DependsOn) == 0 {
+		return n.Requirements
+	}
+
+	edges := append([]BlockID{}, n.Requirements...)
+	for _, id := range n.DependsOn {
+		edges = appendSortedUnique(edges, id)
+	}
+	return edges
 }
 
 // Graph defines the relationships between terraform blocks.
@@ -61,14 +82,18 @@ type Graph []GraphNode
 type BlockType string
 
 const (
-	BlockType_Undefined  BlockType = ""         // Undefined block type
-	BlockType_ModuleCall BlockType = "module"   // Module call block type
-	BlockType_Resource   BlockType = "resource" // Resource block type
-	BlockType_Data       BlockType = "data"     // Data block type
-	BlockType_Local      BlockType = "local"    // Local block type
-	BlockType_Variable   BlockType = "var"      // Variable block type
-	BlockType_Output     BlockType = "output"   // Output block type
-	BlockType_Provider   BlockType = "provider" // Provider block type
+	BlockType_Undefined  BlockType = ""          // Undefined block type
+	BlockType_ModuleCall BlockType = "module"    // Module call block type
+	BlockType_Resource   BlockType = "resource"  // Resource block type
+	BlockType_Data       BlockType = "data"      // Data block type
+	BlockType_Local      BlockType = "local"     // Local block type
+	BlockType_Variable   BlockType = "var"       // Variable block type
+	BlockType_Output     BlockType = "output"    // Output block type
+	BlockType_Provider   BlockType = "provider"  // Provider block type
+	BlockType_Moved      BlockType = "moved"     // Moved block type, identified by its 'to' address
+	BlockType_Import     BlockType = "import"    // Import block type, identified by its 'to' address
+	BlockType_Check      BlockType = "check"     // Check block type
+	BlockType_Terraform  BlockType = "terraform" // Setting of the terraform block (i.e. the backend), identified by its name
 )
 
 type ParsedBlock interface {
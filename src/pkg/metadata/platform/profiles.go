@@ -6,9 +6,15 @@ package platform
 import (
 	"os"
 	"path"
+	"sort"
 	"strings"
 
 	"github.com/cldcvr/terraform-config-inspect/tfconfig"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/rotisserie/eris"
+	"github.com/zclconf/go-cty/cty"
 )
 
 const (
@@ -28,6 +34,7 @@ func (pArr *Profiles) Parse(platformModule *tfconfig.Module) {
 					if doc, err := GetDoc(path.Join(platformModule.Path, item.Name()), -1, false); err == nil {
 						SetValueFromDocIfFound(&p.Title, docCommentTitleArgTag, doc)
 						SetValueFromDocIfFound(&p.Description, docCommentDescArgTag, doc)
+						SetValueFromDocIfFound(&p.Extends, docCommentExtendsArgTag, doc)
 					}
 				}
 			}
@@ -49,3 +56,118 @@ func (pArr *Profiles) Append(p Profile) *Profile {
 	(*pArr) = append((*pArr), p)
 	return &(*pArr)[len(*pArr)-1]
 }
+
+// GetLineage returns the IDs of the profiles inherited by the given profile through the '@extends' doc tag,
+// starting with the base profile and ending with the given profile, i.e. [base, prod].
+// It returns an error if a profile extends an unknown profile, or the profiles extend each other in a cycle.
+func (pArr Profiles) GetLineage(id string) ([]string, error) {
+	lineage := []string{}
+	seen := map[string]struct{}{}
+	for id != "" {
+		p := pArr.GetByID(id)
+		if p == nil {
+			if len(lineage) == 0 {
+				return nil, eris.Errorf("profile '%s' is not defined", id)
+			}
+			return nil, eris.Errorf("profile '%s' extends the undefined profile '%s'", lineage[0], id)
+		}
+
+		if _, ok := seen[id]; ok {
+			chain := make([]string, 0, len(lineage)+1)
+			for i := len(lineage) - 1; i >= 0; i-- {
+				chain = append(chain, lineage[i])
+			}
+			return nil, eris.Errorf("profiles extend each other in a cycle: %s", strings.Join(append(chain, id), " -> "))
+		}
+		seen[id] = struct{}{}
+
+		lineage = append([]string{id}, lineage...)
+		id = p.Extends
+	}
+
+	return lineage, nil
+}
+
+// ProfileFilePath returns the path of the terraform input file of the profile in the platform directory.
+func ProfileFilePath(dir, id string) string {
+	return path.Join(dir, id+profileFileSuffix)
+}
+
+// LoadProfileValues returns the values of the variables set by the profile files, where the variables set by a file
+// override the ones set by the files before it, the same way terraform applies multiple '-var-file' options.
+func LoadProfileValues(files ...string) (map[string]cty.Value, error) {
+	values := map[string]cty.Value{}
+	for _, file := range files {
+		attrs, _, err := parseProfileFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, attr := range attrs {
+			val, diags := attr.Expr.Value(nil)
+			if diags.HasErrors() {
+				return nil, eris.Wrapf(diags, "invalid value of variable '%s' in file: %s", attr.Name, file)
+			}
+			values[attr.Name] = val
+		}
+	}
+
+	return values, nil
+}
+
+// MergeProfileFiles returns the content of the profile files merged, where the variables set by a file override
+// the ones set by the files before it. The variables keep the position they are first set at, and the header comments
+// of the last file, i.e. its '@title' and '@extends' doc tags, are kept at the top.
+func MergeProfileFiles(files ...string) ([]byte, error) {
+	merged := hclwrite.NewEmptyFile()
+	for _, file := range files {
+		attrs, content, err := parseProfileFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		f, diags := hclwrite.ParseConfig(content, file, hcl.InitialPos)
+		if diags.HasErrors() {
+			return nil, eris.Wrapf(diags, "failed to parse file: %s", file)
+		}
+
+		for _, attr := range attrs {
+			merged.Body().SetAttributeRaw(attr.Name, f.Body().GetAttribute(attr.Name).Expr().BuildTokens(nil))
+		}
+	}
+
+	if len(files) == 0 {
+		return merged.Bytes(), nil
+	}
+
+	header, err := readCommentLines(files[len(files)-1], -1, false)
+	if err != nil {
+		return nil, eris.Wrapf(err, "failed to read file: %s", files[len(files)-1])
+	}
+	if len(header) == 0 {
+		return merged.Bytes(), nil
+	}
+
+	return append([]byte(strings.Join(header, "\n")+"\n\n"), merged.Bytes()...), nil
+}
+
+// parseProfileFile returns the attributes of the terraform input file in the order they are set, along with the file content.
+func parseProfileFile(file string) ([]*hclsyntax.Attribute, []byte, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, nil, eris.Wrapf(err, "failed to read file: %s", file)
+	}
+
+	f, diags := hclsyntax.ParseConfig(content, file, hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, nil, eris.Wrapf(diags, "failed to parse file: %s", file)
+	}
+
+	attrs := []*hclsyntax.Attribute{}
+	for _, attr := range f.Body.(*hclsyntax.Body).Attributes {
+		attrs = append(attrs, attr)
+	}
+	sort.Slice(attrs, func(i, j int) bool { return attrs[i].SrcRange.Start.Byte < attrs[j].SrcRange.Start.Byte })
+
+	return attrs, content, nil
+}
@@ -4,10 +4,14 @@
 package platform
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/cldcvr/terraform-config-inspect/tfconfig"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
 )
 
 func TestProfiles_Parse(t *testing.T) {
@@ -38,6 +42,7 @@ func TestProfiles_Parse(t *testing.T) {
 					ID:          "prod",
 					Title:       "",
 					Description: "",
+					Extends:     "dev",
 				},
 			},
 		},
@@ -70,6 +75,7 @@ func TestProfiles_Parse(t *testing.T) {
 					ID:          "prod",
 					Title:       "",
 					Description: "",
+					Extends:     "dev",
 				},
 			},
 		},
@@ -81,3 +87,82 @@ func TestProfiles_Parse(t *testing.T) {
 		})
 	}
 }
+
+func TestProfiles_GetLineage(t *testing.T) {
+	profiles := Profiles{
+		{ID: "base"},
+		{ID: "staging", Extends: "base"},
+		{ID: "prod", Extends: "staging"},
+		{ID: "broken", Extends: "unknown"},
+		{ID: "a", Extends: "b"},
+		{ID: "b", Extends: "a"},
+	}
+
+	tests := []struct {
+		id      string
+		want    []string
+		wantErr string
+	}{
+		{id: "base", want: []string{"base"}},
+		{id: "prod", want: []string{"base", "staging", "prod"}},
+		{id: "unknown", wantErr: "profile 'unknown' is not defined"},
+		{id: "broken", wantErr: "profile 'broken' extends the undefined profile 'unknown'"},
+		{id: "a", wantErr: "profiles extend each other in a cycle: a -> b -> a"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			got, err := profiles.GetLineage(tt.id)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestMergeProfileFiles(t *testing.T) {
+	dir := t.TempDir()
+	base := ProfileFilePath(dir, "base")
+	prod := ProfileFilePath(dir, "prod")
+	require.NoError(t, os.WriteFile(base, []byte(`# Base configuration profile.
+region         = "us-east-1"
+instance_class = "db.t3.micro"
+tags = {
+  team = "platform"
+}
+`), 0o644))
+	require.NoError(t, os.WriteFile(prod, []byte(`# @title: Production
+# @extends: base
+instance_class = "db.r5.large" # sized for production
+multi_az       = true
+`), 0o644))
+
+	values, err := LoadProfileValues(base, prod)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]cty.Value{
+		"region":         cty.StringVal("us-east-1"),
+		"instance_class": cty.StringVal("db.r5.large"),
+		"tags":           cty.ObjectVal(map[string]cty.Value{"team": cty.StringVal("platform")}),
+		"multi_az":       cty.True,
+	}, values)
+
+	merged, err := MergeProfileFiles(base, prod)
+	require.NoError(t, err)
+	assert.Equal(t, `# @title: Production
+# @extends: base
+
+region         = "us-east-1"
+instance_class = "db.r5.large"
+tags = {
+  team = "platform"
+}
+multi_az = true
+`, string(merged))
+
+	_, err = LoadProfileValues(filepath.Join(dir, "missing.tfvars"))
+	assert.ErrorContains(t, err, "failed to read file")
+}
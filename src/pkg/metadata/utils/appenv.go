@@ -20,6 +20,7 @@ type EnvVars []EnvVar
 // EnvVar env variable Object
 type EnvVar struct {
 	Key, Value string
+	Sensitive  bool // set when the value refers to a sensitive component output
 }
 
 // GetAppEnvTemplate based on the app-dependencies, and component metadata,
@@ -28,7 +29,7 @@ type EnvVar struct {
 func GetAppEnvTemplate(pm *platform.PlatformMetadata, app app.App) EnvVars {
 	envVars := EnvVars{}
 	for _, appDep := range app.GetDependencies() {
-		comp := pm.Components.GetByID(appDep.Use)
+		comp := pm.Components.GetByID(appDep.ComponentID())
 		if comp == nil || comp.Outputs == nil {
 			continue
 		}
@@ -39,9 +40,11 @@ func GetAppEnvTemplate(pm *platform.PlatformMetadata, app app.App) EnvVars {
 
 		prefix := getEnvVarPrefix(app.EnvPrefix, appDep.EnvPrefix)
 
+		sensitiveOutputs := getSensitiveOutputs(comp, depDefaults)
+
 		for k, v := range finalOutputs {
 			varName := prefix + k
-			envVars = append(envVars, EnvVar{varName, v})
+			envVars = append(envVars, EnvVar{varName, v, isSensitiveValue(v, sensitiveOutputs)})
 		}
 	}
 
@@ -57,6 +60,30 @@ func getDepDefaults(comp *platform.Component, appDep app.Dependency) map[string]
 	return depDefaults
 }
 
+// getSensitiveOutputs returns the paths of the sensitive outputs of the component in the env templates,
+// i.e. `tr_component_postgres_password.value.db`, as the placeholders of their default env values refer to them.
+func getSensitiveOutputs(comp *platform.Component, depDefaults map[string]string) map[string]struct{} {
+	sensitive := map[string]struct{}{}
+	for outputName, output := range comp.Outputs.Properties {
+		if output != nil && output.Sensitive {
+			for _, m := range envTemplatePlaceholder.FindAllStringSubmatch(depDefaults[outputName], -1) {
+				sensitive[m[1]] = struct{}{}
+			}
+		}
+	}
+	return sensitive
+}
+
+// isSensitiveValue returns true if any of the placeholders in the rendered env value refers to a sensitive output.
+func isSensitiveValue(val string, sensitiveOutputs map[string]struct{}) bool {
+	for _, m := range envTemplatePlaceholder.FindAllStringSubmatch(val, -1) {
+		if _, ok := sensitiveOutputs[m[1]]; ok {
+			return true
+		}
+	}
+	return false
+}
+
 // getRenderedOutputs updates the Outputs of appDep based on depDefaults.
 func getRenderedOutputs(appDep *app.Dependency, depDefaults map[string]string) (finalOutputs map[string]string) {
 	if len(appDep.Outputs) > 0 {
@@ -110,6 +137,19 @@ func (vars EnvVars) RenderWithQuotes() string {
 	return vars.render(true)
 }
 
+// Split returns the env variables that are not sensitive and the ones that are, in the same order.
+func (vars EnvVars) Split() (plain, sensitive EnvVars) {
+	plain, sensitive = EnvVars{}, EnvVars{}
+	for _, v := range vars {
+		if v.Sensitive {
+			sensitive = append(sensitive, v)
+		} else {
+			plain = append(plain, v)
+		}
+	}
+	return
+}
+
 //  Implement sort.Interface to make EnvVars sortable by var name
 
 func (vars EnvVars) Len() int {
@@ -53,8 +53,8 @@ func TestGetAppEnvTemplate(t *testing.T) {
 				},
 			},
 			want: EnvVars{
-				{"OUTP1", `{{ tr_component_comp1_outp1.value.mydep1 }}`},
-				{"OUTP2", `{{ tr_component_comp1_outp2.value.mydep1 }}`},
+				{"OUTP1", `{{ tr_component_comp1_outp1.value.mydep1 }}`, false},
+				{"OUTP2", `{{ tr_component_comp1_outp2.value.mydep1 }}`, false},
 			},
 		},
 		{
@@ -98,9 +98,53 @@ func TestGetAppEnvTemplate(t *testing.T) {
 				},
 			},
 			want: EnvVars{
-				{"APP_MYDEP2_COMB", `combination of {{ tr_component_comp1_outp1.value.mydep2 }} and {{ tr_component_comp1_outp2.value.mydep2 }}`},
-				{"APP_OUTP1", `{{ tr_component_comp1_outp1.value.mydep1 }}`},
-				{"APP_OUTP2", `{{ tr_component_comp1_outp2.value.mydep1 }}`},
+				{"APP_MYDEP2_COMB", `combination of {{ tr_component_comp1_outp1.value.mydep2 }} and {{ tr_component_comp1_outp2.value.mydep2 }}`, false},
+				{"APP_OUTP1", `{{ tr_component_comp1_outp1.value.mydep1 }}`, false},
+				{"APP_OUTP2", `{{ tr_component_comp1_outp2.value.mydep1 }}`, false},
+			},
+		},
+		{
+			name: "sensitive outputs",
+			args: args{
+				app: app.App{
+					Dependencies: app.Dependencies{
+						{
+							ID:      "mydep1",
+							Use:     "comp1",
+							Outputs: map[string]string{},
+						},
+						{
+							ID:        "mydep2",
+							Use:       "comp1",
+							EnvPrefix: "MYDEP2",
+							Outputs: map[string]string{
+								"URL":  `postgres://{{user}}:{{password}}@{{host}}`,
+								"HOST": `{{host}}`,
+							},
+						},
+					},
+				},
+				pm: &platform.PlatformMetadata{
+					Components: platform.Components{
+						{
+							ID: "comp1",
+							Outputs: &jsonschema.Node{
+								Properties: map[string]*jsonschema.Node{
+									"host":     {},
+									"user":     {},
+									"password": {Sensitive: true},
+								},
+							},
+						},
+					},
+				},
+			},
+			want: EnvVars{
+				{"HOST", `{{ tr_component_comp1_host.value.mydep1 }}`, false},
+				{"MYDEP2_HOST", `{{ tr_component_comp1_host.value.mydep2 }}`, false},
+				{"MYDEP2_URL", `postgres://{{ tr_component_comp1_user.value.mydep2 }}:{{ tr_component_comp1_password.value.mydep2 }}@{{ tr_component_comp1_host.value.mydep2 }}`, true},
+				{"PASSWORD", `{{ tr_component_comp1_password.value.mydep1 }}`, true},
+				{"USER", `{{ tr_component_comp1_user.value.mydep1 }}`, false},
 			},
 		},
 	}
@@ -113,6 +157,28 @@ func TestGetAppEnvTemplate(t *testing.T) {
 	}
 }
 
+func Test_isSensitiveValue(t *testing.T) {
+	sensitiveOutputs := map[string]struct{}{"tr_component_comp1_password.value.mydep1": {}}
+
+	tests := []struct {
+		name string
+		val  string
+		want bool
+	}{
+		{name: "placeholder", val: `{{ tr_component_comp1_password.value.mydep1 }}`, want: true},
+		{name: "unescaped placeholder without spaces", val: `{{{tr_component_comp1_password.value.mydep1}}}`, want: true},
+		{name: "placeholder in a template", val: `postgres://user:{{ tr_component_comp1_password.value.mydep1 }}@host`, want: true},
+		{name: "another dependency with the same prefix", val: `{{ tr_component_comp1_password.value.mydep10 }}`, want: false},
+		{name: "another output with the same prefix", val: `{{ tr_component_comp1_password_hint.value.mydep1 }}`, want: false},
+		{name: "output path without placeholder", val: `tr_component_comp1_password.value.mydep1`, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, isSensitiveValue(tt.val, sensitiveOutputs))
+		})
+	}
+}
+
 func TestEnvVars(t *testing.T) {
 	tests := []struct {
 		name             string
@@ -122,8 +188,8 @@ func TestEnvVars(t *testing.T) {
 	}{
 		{
 			vars: EnvVars{
-				{"APP_OUTP1", `{{ tr_component_comp1_outp1.value.mydep1 }}`},
-				{"APP_OUTP2", `{{ tr_component_comp1_outp2.value.mydep1 }}`},
+				{"APP_OUTP1", `{{ tr_component_comp1_outp1.value.mydep1 }}`, false},
+				{"APP_OUTP2", `{{ tr_component_comp1_outp2.value.mydep1 }}`, false},
 			},
 			wantRender: heredoc.Doc(`
 			APP_OUTP1={{ tr_component_comp1_outp1.value.mydep1 }}
@@ -144,6 +210,22 @@ func TestEnvVars(t *testing.T) {
 	}
 }
 
+func TestEnvVars_Split(t *testing.T) {
+	vars := EnvVars{
+		{"HOST", "host", false},
+		{"PASSWORD", "password", true},
+		{"PORT", "port", false},
+	}
+
+	plain, sensitive := vars.Split()
+	assert.Equal(t, EnvVars{{"HOST", "host", false}, {"PORT", "port", false}}, plain)
+	assert.Equal(t, EnvVars{{"PASSWORD", "password", true}}, sensitive)
+
+	plain, sensitive = EnvVars{}.Split()
+	assert.Empty(t, plain)
+	assert.Empty(t, sensitive)
+}
+
 func TestTemplate(t *testing.T) {
 	stateOut := map[string]interface{}{
 		"tr_component_postgres_host": map[string]interface{}{
@@ -5,6 +5,7 @@ package utils
 
 import (
 	"errors"
+	"fmt"
 
 	"github.com/cldcvr/terrarium/src/pkg/metadata/app"
 	"github.com/cldcvr/terrarium/src/pkg/metadata/platform"
@@ -18,6 +19,10 @@ var (
 
 // MatchAppAndPlatform validate app dependency inputs and set default inputs
 func MatchAppAndPlatform(pm *platform.PlatformMetadata, apps app.Apps, ignoreUnimplemented bool) (err error) {
+	if err = ResolveImplementations(pm, apps); err != nil {
+		return
+	}
+
 	for _, app := range pkgutils.ToRefArr(apps) {
 		deps := pkgutils.ToRefArr(app.Dependencies)
 		if app.Compute.ID != "" {
@@ -41,7 +46,7 @@ func MatchAppAndPlatform(pm *platform.PlatformMetadata, apps app.Apps, ignoreUni
 
 // validateDependency validate and set default values to the dependency inputs.
 func validateDependency(pm *platform.PlatformMetadata, appDep *app.Dependency) error {
-	comp := pm.Components.GetByID(appDep.Use)
+	comp := pm.Components.GetByID(appDep.ComponentID())
 	if comp == nil {
 		return eris.Wrapf(ErrComponentNotImplemented, "'%s.%s'", appDep.ID, appDep.Use)
 	}
@@ -58,3 +63,73 @@ func validateDependency(pm *platform.PlatformMetadata, appDep *app.Dependency) e
 	comp.Inputs.ApplyDefaultsToMSI(appDep.Inputs)
 	return nil
 }
+
+// ResolveImplementations sets the implementation of the app dependencies to the platform component implementing them.
+// A platform can implement a dependency with multiple components, one for each range of versions
+// (i.e. "postgres__v11" and "postgres__v15"), and the implementation is selected using the version input of the dependency.
+// The version input is consumed by the selection, hence it is removed from the inputs of the dependency.
+// The dependencies with 'no_provision' use the implementation of the dependency with the same ID provisioned by another app.
+// The dependencies that are not implemented by the platform, or already resolved, are left as-is.
+func ResolveImplementations(pm *platform.PlatformMetadata, apps app.Apps) error {
+	provisioned := map[string]string{} // '<use>.<id>' of the provisioned dependencies to the implementation used
+
+	resolve := func(dep *app.Dependency) error {
+		key := dep.Use + "." + dep.ID
+		if dep.NoProvision && dep.Implementation == "" {
+			if impl, ok := provisioned[key]; ok {
+				dep.Implementation = impl
+				return nil
+			}
+		}
+
+		if dep.Implementation == "" {
+			comp, err := pm.Components.GetImplementation(dep.Use, getDependencyVersion(dep))
+			if err != nil {
+				return eris.Wrapf(err, "failed to select the implementation of '%s.%s'", dep.ID, dep.Use)
+			}
+
+			if comp != nil && comp.ID != dep.Use {
+				dep.Implementation = comp.ID
+				delete(dep.Inputs, "version")
+			}
+		}
+
+		if !dep.NoProvision && dep.Implementation != "" {
+			provisioned[key] = dep.Implementation
+		}
+
+		return nil
+	}
+
+	// the provisioned dependencies are resolved first, for the dependencies with 'no_provision' to use the same implementation.
+	for _, noProvision := range []bool{false, true} {
+		for _, a := range pkgutils.ToRefArr(apps) {
+			deps := pkgutils.ToRefArr(a.Dependencies)
+			if a.Compute.ID != "" {
+				deps = append(deps, &a.Compute)
+			}
+
+			for _, dep := range deps {
+				if dep.NoProvision != noProvision {
+					continue
+				}
+
+				if err := resolve(dep); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// getDependencyVersion returns the version input of the dependency, i.e. "11" for "postgres@11".
+func getDependencyVersion(dep *app.Dependency) string {
+	v, ok := dep.Inputs["version"]
+	if !ok || v == nil {
+		return ""
+	}
+
+	return fmt.Sprint(v)
+}
@@ -76,6 +76,42 @@ func TestMatchAppAndPlatform(t *testing.T) {
 			wantErr: true,
 			errMsg:  "component 'testDep.comp1' does not contain a valid set of inputs: validation failed with following errors: \n\tinput1: Invalid type. Expected: number, given: string",
 		},
+		{
+			name: "Input does not match pattern",
+			pm: &platform.PlatformMetadata{
+				Components: platform.Components{
+					{
+						ID: "postgres",
+						Inputs: &jsonschema.Node{
+							Type: gojsonschema.TYPE_OBJECT,
+							Properties: map[string]*jsonschema.Node{
+								"db_name": {
+									Type:    gojsonschema.TYPE_STRING,
+									Pattern: "^[a-z][a-z0-9_]*$",
+								},
+							},
+							Required: []string{"db_name"},
+						},
+					},
+				},
+			},
+			apps: app.Apps{
+				app.App{
+					ID: "testApp",
+					Dependencies: app.Dependencies{
+						app.Dependency{
+							ID:  "db",
+							Use: "postgres",
+							Inputs: map[string]interface{}{
+								"db_name": "Orders-DB",
+							},
+						},
+					},
+				},
+			},
+			wantErr: true,
+			errMsg:  "component 'db.postgres' does not contain a valid set of inputs: validation failed with following errors: \n\tdb_name: Does not match pattern '^[a-z][a-z0-9_]*$'",
+		},
 		{
 			name: "Success set defaults",
 			pm: &platform.PlatformMetadata{
@@ -156,3 +192,59 @@ func TestMatchAppAndPlatform(t *testing.T) {
 		})
 	}
 }
+
+func TestResolveImplementations(t *testing.T) {
+	pm := &platform.PlatformMetadata{
+		Components: platform.Components{
+			{ID: "postgres__v11", Versions: ">= 11, < 12"},
+			{ID: "postgres__v15", Versions: ">= 15"},
+			{ID: "redis"},
+		},
+	}
+
+	apps := app.Apps{
+		{
+			ID: "api",
+			Dependencies: app.Dependencies{
+				{ID: "db", Use: "postgres", EnvPrefix: "DB", Inputs: map[string]interface{}{"version": "11"}},
+				{ID: "cache", Use: "redis"},
+				{ID: "queue", Use: "kafka"},
+			},
+		},
+		{
+			ID: "worker",
+			Dependencies: app.Dependencies{
+				{ID: "db", Use: "postgres", NoProvision: true},
+				{ID: "reports", Use: "postgres", Inputs: map[string]interface{}{"version": 15}},
+			},
+		},
+	}
+
+	err := ResolveImplementations(pm, apps)
+	assert.NoError(t, err)
+	assert.Equal(t, "postgres__v11", apps[0].Dependencies[0].Implementation)
+	assert.Equal(t, "postgres", apps[0].Dependencies[0].Use, "use is left as written in the app")
+	assert.NotContains(t, apps[0].Dependencies[0].Inputs, "version", "version input is consumed by the selection")
+	assert.Equal(t, "redis", apps[0].Dependencies[1].ComponentID())
+	assert.Empty(t, apps[0].Dependencies[1].Implementation)
+	assert.Equal(t, "kafka", apps[0].Dependencies[2].ComponentID(), "unimplemented dependency is left as-is")
+	assert.Equal(t, "postgres__v11", apps[1].Dependencies[0].Implementation, "no_provision dependency uses the provisioned implementation")
+	assert.Equal(t, "postgres__v15", apps[1].Dependencies[1].Implementation)
+
+	err = ResolveImplementations(pm, apps)
+	assert.NoError(t, err, "resolved dependencies are not selected again")
+	assert.Equal(t, "postgres__v11", apps[0].Dependencies[0].Implementation)
+
+	envVars := GetAppEnvTemplate(&platform.PlatformMetadata{
+		Components: platform.Components{{
+			ID: "postgres__v11",
+			Outputs: &jsonschema.Node{
+				Properties: map[string]*jsonschema.Node{"host": {}},
+			},
+		}},
+	}, apps[0])
+	assert.Equal(t, EnvVars{{Key: "DB_HOST", Value: "{{ tr_component_postgres__v11_host.value.db }}"}}, envVars, "env var names are not derived from the implementation")
+
+	err = ResolveImplementations(pm, app.Apps{{ID: "api", Dependencies: app.Dependencies{{ID: "db", Use: "postgres"}}}})
+	assert.ErrorContains(t, err, "failed to select the implementation of 'db.postgres': component 'postgres' has multiple implementations")
+}
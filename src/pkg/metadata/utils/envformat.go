@@ -0,0 +1,327 @@
+// Copyright (c) Ollion
+// SPDX-License-Identifier: Apache-2.0
+
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/rotisserie/eris"
+	"github.com/zclconf/go-cty/cty"
+	"golang.org/x/exp/maps"
+	"gopkg.in/yaml.v3"
+)
+
+// EnvFormat is the name of a format the app env variables can be rendered in.
+type EnvFormat string
+
+const (
+	EnvFormatDotEnv          EnvFormat = "dotenv"
+	EnvFormatK8sConfigMap    EnvFormat = "k8s-configmap"
+	EnvFormatK8sSecret       EnvFormat = "k8s-secret"
+	EnvFormatDockerCompose   EnvFormat = "docker-compose"
+	EnvFormatJSON            EnvFormat = "json"
+	EnvFormatTerraformOutput EnvFormat = "terraform-output"
+)
+
+// EnvRenderer renders the env variables of an app in a specific format.
+type EnvRenderer interface {
+	// FileName returns the name of the file to write the rendered env variables of the app to.
+	FileName(appID string) string
+
+	// Render returns the env variables of the app in the format.
+	Render(appID string, vars EnvVars) ([]byte, error)
+}
+
+// EnvValueEscaper is implemented by the renderers that write the env values into quoted strings.
+// It escapes a terraform output value before it replaces a placeholder in the rendered template,
+// so that values containing quotes, backslashes or newlines do not break the env file.
+type EnvValueEscaper interface {
+	EscapeValue(val string) string
+}
+
+var envRenderers = map[EnvFormat]EnvRenderer{
+	EnvFormatDotEnv:          dotEnvRenderer{},
+	EnvFormatK8sConfigMap:    k8sRenderer{kind: "ConfigMap"},
+	EnvFormatK8sSecret:       k8sRenderer{kind: "Secret"},
+	EnvFormatDockerCompose:   dockerComposeRenderer{},
+	EnvFormatJSON:            jsonRenderer{},
+	EnvFormatTerraformOutput: tfOutputRenderer{},
+}
+
+// RegisterEnvRenderer adds a renderer for the given format, replacing any existing renderer for it.
+func RegisterEnvRenderer(format EnvFormat, r EnvRenderer) {
+	envRenderers[format] = r
+}
+
+// GetEnvRenderer returns the renderer registered for the given format.
+func GetEnvRenderer(format EnvFormat) (EnvRenderer, error) {
+	r, ok := envRenderers[format]
+	if !ok {
+		return nil, eris.Errorf("unsupported env format '%s'. must be one of: %s", format, strings.Join(EnvFormats(), ", "))
+	}
+
+	return r, nil
+}
+
+// EnvFormats returns the sorted names of all the registered env formats.
+func EnvFormats() []string {
+	formats := make([]string, 0, len(envRenderers))
+	for f := range envRenderers {
+		formats = append(formats, string(f))
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+// GetEnvFileFormat returns the format of the app env file with the given name, as named by the registered renderers.
+// When more than one format matches the file name, the one with the longest file name suffix is returned.
+func GetEnvFileFormat(fileName string) (EnvFormat, bool) {
+	const appIDMarker = "\x00"
+
+	var (
+		format    EnvFormat
+		suffixLen = -1
+	)
+	for _, f := range EnvFormats() {
+		r := envRenderers[EnvFormat(f)]
+		for _, name := range []string{r.FileName(appIDMarker), SecretFileName(r, appIDMarker)} {
+			prefix, suffix, _ := strings.Cut(name, appIDMarker)
+			if len(fileName) > len(prefix)+len(suffix) &&
+				strings.HasPrefix(fileName, prefix) && strings.HasSuffix(fileName, suffix) && len(suffix) > suffixLen {
+				format, suffixLen = EnvFormat(f), len(suffix)
+			}
+		}
+	}
+
+	return format, suffixLen >= 0
+}
+
+// SecretFileName returns the name of the file to write the sensitive env variables of the app to,
+// in the format of the renderer. The file name is the renderer's file name with ".secrets" added
+// after the app ID (e.g. "app_<id>.secrets.env.mustache").
+func SecretFileName(r EnvRenderer, appID string) string {
+	fileName := r.FileName(appID)
+	appPrefix := "app_" + appID + "."
+	if !strings.HasPrefix(fileName, appPrefix) {
+		return "secrets_" + fileName
+	}
+
+	return appPrefix + "secrets." + strings.TrimPrefix(fileName, appPrefix)
+}
+
+// RenderAs renders the env variables of the app in the given format.
+func (vars EnvVars) RenderAs(format EnvFormat, appID string) ([]byte, error) {
+	r, err := GetEnvRenderer(format)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.Render(appID, vars)
+}
+
+// Map returns the env variables as a map of key to value.
+func (vars EnvVars) Map() map[string]string {
+	m := make(map[string]string, len(vars))
+	for _, v := range vars {
+		m[v.Key] = v.Value
+	}
+	return m
+}
+
+// dotEnvRenderer renders the env variables as a .env file.
+type dotEnvRenderer struct{}
+
+func (dotEnvRenderer) FileName(appID string) string {
+	return "app_" + appID + ".env.mustache"
+}
+
+func (dotEnvRenderer) Render(appID string, vars EnvVars) ([]byte, error) {
+	return []byte(vars.RenderWithQuotes()), nil
+}
+
+// EscapeValue escapes the value the same way RenderWithQuotes quotes it.
+func (dotEnvRenderer) EscapeValue(val string) string {
+	return trimQuotes(strconv.Quote(val))
+}
+
+// k8sRenderer renders the env variables as a Kubernetes ConfigMap or Secret manifest.
+type k8sRenderer struct {
+	kind string
+}
+
+type k8sObject struct {
+	APIVersion string        `yaml:"apiVersion"`
+	Kind       string        `yaml:"kind"`
+	Metadata   k8sObjectMeta `yaml:"metadata"`
+	Type       string        `yaml:"type,omitempty"`
+	Data       yamlEnvMap    `yaml:"data,omitempty"`
+	StringData yamlEnvMap    `yaml:"stringData,omitempty"`
+}
+
+type k8sObjectMeta struct {
+	Name string `yaml:"name"`
+}
+
+var k8sInvalidNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+func (r k8sRenderer) FileName(appID string) string {
+	return "app_" + appID + "." + strings.ToLower(r.kind) + ".yaml.mustache"
+}
+
+func (r k8sRenderer) Render(appID string, vars EnvVars) ([]byte, error) {
+	obj := k8sObject{
+		APIVersion: "v1",
+		Kind:       r.kind,
+		Metadata:   k8sObjectMeta{Name: k8sName(appID)},
+	}
+
+	if r.kind == "Secret" {
+		obj.Type = "Opaque"
+		obj.StringData = vars.Map()
+	} else {
+		obj.Data = vars.Map()
+	}
+
+	return marshalYAML(obj)
+}
+
+func (k8sRenderer) EscapeValue(val string) string {
+	return escapeYAMLValue(val)
+}
+
+// k8sName converts the app ID to a valid Kubernetes resource name.
+func k8sName(appID string) string {
+	return strings.Trim(k8sInvalidNameChars.ReplaceAllString(strings.ToLower(appID), "-"), "-")
+}
+
+// dockerComposeRenderer renders the env variables as the environment of a docker-compose service.
+type dockerComposeRenderer struct{}
+
+type composeFile struct {
+	Services map[string]composeService `yaml:"services"`
+}
+
+type composeService struct {
+	Environment yamlEnvMap `yaml:"environment"`
+}
+
+func (dockerComposeRenderer) FileName(appID string) string {
+	return "app_" + appID + ".compose.yaml.mustache"
+}
+
+func (dockerComposeRenderer) Render(appID string, vars EnvVars) ([]byte, error) {
+	return marshalYAML(composeFile{
+		Services: map[string]composeService{
+			appID: {Environment: vars.Map()},
+		},
+	})
+}
+
+func (dockerComposeRenderer) EscapeValue(val string) string {
+	return escapeYAMLValue(val)
+}
+
+// jsonRenderer renders the env variables as a JSON object.
+type jsonRenderer struct{}
+
+func (jsonRenderer) FileName(appID string) string {
+	return "app_" + appID + ".env.json.mustache"
+}
+
+func (jsonRenderer) Render(appID string, vars EnvVars) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(vars.Map()); err != nil {
+		return nil, eris.Wrapf(err, "failed to render env variables of app '%s' as json", appID)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (jsonRenderer) EscapeValue(val string) string {
+	return escapeJSONValue(val)
+}
+
+// tfOutputRenderer renders the env variables as a terraform output block,
+// so that they can be read by other terraform configurations.
+type tfOutputRenderer struct{}
+
+func (tfOutputRenderer) FileName(appID string) string {
+	return "app_" + appID + ".env.tf.mustache"
+}
+
+func (tfOutputRenderer) Render(appID string, vars EnvVars) ([]byte, error) {
+	values := make(map[string]cty.Value, len(vars))
+	for _, v := range vars {
+		values[v.Key] = cty.StringVal(v.Value)
+	}
+
+	f := hclwrite.NewEmptyFile()
+	b := f.Body().AppendNewBlock("output", []string{"app_" + appID + "_env"})
+	if len(values) > 0 {
+		b.Body().SetAttributeValue("value", cty.ObjectVal(values))
+	} else {
+		b.Body().SetAttributeValue("value", cty.EmptyObjectVal)
+	}
+
+	return hclwrite.Format(f.Bytes()), nil
+}
+
+// EscapeValue escapes the value for a terraform quoted string, including the `${` and `%{` template sequences.
+func (tfOutputRenderer) EscapeValue(val string) string {
+	return trimQuotes(string(hclwrite.TokensForValue(cty.StringVal(val)).Bytes()))
+}
+
+// yamlEnvMap is a map of env variables marshalled to YAML with the keys sorted and the values double quoted,
+// so that the values filled into the rendered template can be escaped the same way regardless of their content.
+type yamlEnvMap map[string]string
+
+func (m yamlEnvMap) MarshalYAML() (interface{}, error) {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	keys := maps.Keys(m)
+	sort.Strings(keys)
+	for _, k := range keys {
+		node.Content = append(node.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: k},
+			&yaml.Node{Kind: yaml.ScalarNode, Value: m[k], Style: yaml.DoubleQuotedStyle},
+		)
+	}
+	return node, nil
+}
+
+// escapeYAMLValue escapes the value for a YAML double quoted string, for which the JSON escape sequences are valid.
+func escapeYAMLValue(val string) string {
+	return escapeJSONValue(val)
+}
+
+func escapeJSONValue(val string) string {
+	buf := &bytes.Buffer{}
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(val) // encoding a string never fails
+	return trimQuotes(strings.TrimSuffix(buf.String(), "\n"))
+}
+
+func trimQuotes(quoted string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(quoted, `"`), `"`)
+}
+
+func marshalYAML(v interface{}) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	enc := yaml.NewEncoder(buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(v); err != nil {
+		return nil, eris.Wrap(err, "failed to render env variables as yaml")
+	}
+
+	return buf.Bytes(), nil
+}
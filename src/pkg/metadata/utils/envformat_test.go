@@ -0,0 +1,160 @@
+// Copyright (c) Ollion
+// SPDX-License-Identifier: Apache-2.0
+
+package utils
+
+import (
+	"testing"
+
+	"github.com/MakeNowJust/heredoc/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnvVars_RenderAs(t *testing.T) {
+	vars := EnvVars{
+		{"APP_HOST", `{{ tr_component_postgres_host.value.db }}`, false},
+		{"APP_PORT", `{{ tr_component_postgres_port.value.db }}`, false},
+	}
+
+	tests := []struct {
+		format       EnvFormat
+		wantFileName string
+		want         string
+	}{
+		{
+			format:       EnvFormatDotEnv,
+			wantFileName: "app_voting_be.env.mustache",
+			want: heredoc.Doc(`
+			APP_HOST="{{ tr_component_postgres_host.value.db }}"
+			APP_PORT="{{ tr_component_postgres_port.value.db }}"
+			`),
+		},
+		{
+			format:       EnvFormatK8sConfigMap,
+			wantFileName: "app_voting_be.configmap.yaml.mustache",
+			want: heredoc.Doc(`
+			apiVersion: v1
+			kind: ConfigMap
+			metadata:
+			  name: voting-be
+			data:
+			  APP_HOST: "{{ tr_component_postgres_host.value.db }}"
+			  APP_PORT: "{{ tr_component_postgres_port.value.db }}"
+			`),
+		},
+		{
+			format:       EnvFormatK8sSecret,
+			wantFileName: "app_voting_be.secret.yaml.mustache",
+			want: heredoc.Doc(`
+			apiVersion: v1
+			kind: Secret
+			metadata:
+			  name: voting-be
+			type: Opaque
+			stringData:
+			  APP_HOST: "{{ tr_component_postgres_host.value.db }}"
+			  APP_PORT: "{{ tr_component_postgres_port.value.db }}"
+			`),
+		},
+		{
+			format:       EnvFormatDockerCompose,
+			wantFileName: "app_voting_be.compose.yaml.mustache",
+			want: heredoc.Doc(`
+			services:
+			  voting_be:
+			    environment:
+			      APP_HOST: "{{ tr_component_postgres_host.value.db }}"
+			      APP_PORT: "{{ tr_component_postgres_port.value.db }}"
+			`),
+		},
+		{
+			format:       EnvFormatJSON,
+			wantFileName: "app_voting_be.env.json.mustache",
+			want: heredoc.Doc(`
+			{
+			  "APP_HOST": "{{ tr_component_postgres_host.value.db }}",
+			  "APP_PORT": "{{ tr_component_postgres_port.value.db }}"
+			}
+			`),
+		},
+		{
+			format:       EnvFormatTerraformOutput,
+			wantFileName: "app_voting_be.env.tf.mustache",
+			want: heredoc.Doc(`
+			output "app_voting_be_env" {
+			  value = {
+			    APP_HOST = "{{ tr_component_postgres_host.value.db }}"
+			    APP_PORT = "{{ tr_component_postgres_port.value.db }}"
+			  }
+			}
+			`),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			r, err := GetEnvRenderer(tt.format)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantFileName, r.FileName("voting_be"))
+
+			got, err := vars.RenderAs(tt.format, "voting_be")
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(got))
+		})
+	}
+
+	t.Run("unsupported format", func(t *testing.T) {
+		_, err := vars.RenderAs("xml", "voting_be")
+		assert.EqualError(t, err, "unsupported env format 'xml'. must be one of: docker-compose, dotenv, json, k8s-configmap, k8s-secret, terraform-output")
+	})
+}
+
+type customEnvRenderer struct{}
+
+func (customEnvRenderer) FileName(appID string) string { return appID + ".ini" }
+
+func (customEnvRenderer) Render(appID string, vars EnvVars) ([]byte, error) { return nil, nil }
+
+func TestSecretFileName(t *testing.T) {
+	tests := []struct {
+		name string
+		r    EnvRenderer
+		want string
+	}{
+		{name: "dotenv", r: dotEnvRenderer{}, want: "app_voting_be.secrets.env.mustache"},
+		{name: "k8s secret", r: k8sRenderer{kind: "Secret"}, want: "app_voting_be.secrets.secret.yaml.mustache"},
+		{name: "custom file name", r: customEnvRenderer{}, want: "secrets_voting_be.ini"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, SecretFileName(tt.r, "voting_be"))
+		})
+	}
+}
+
+func TestGetEnvFileFormat(t *testing.T) {
+	tests := []struct {
+		fileName   string
+		wantFormat EnvFormat
+		wantOk     bool
+	}{
+		{fileName: "app_voting_be.env.mustache", wantFormat: EnvFormatDotEnv, wantOk: true},
+		{fileName: "app_voting_be.secrets.env.mustache", wantFormat: EnvFormatDotEnv, wantOk: true},
+		{fileName: "app_voting_be.env.json.mustache", wantFormat: EnvFormatJSON, wantOk: true},
+		{fileName: "app_voting_be.env.tf.mustache", wantFormat: EnvFormatTerraformOutput, wantOk: true},
+		{fileName: "app_voting_be.secrets.secret.yaml.mustache", wantFormat: EnvFormatK8sSecret, wantOk: true},
+		{fileName: "app_voting_be.compose.yaml.mustache", wantFormat: EnvFormatDockerCompose, wantOk: true},
+		{fileName: "app_voting_be.txt.mustache"},
+		{fileName: "app_.env.mustache"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fileName, func(t *testing.T) {
+			got, ok := GetEnvFileFormat(tt.fileName)
+			assert.Equal(t, tt.wantOk, ok)
+			assert.Equal(t, tt.wantFormat, got)
+		})
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright (c) Ollion
+// SPDX-License-Identifier: Apache-2.0
+
+package utils
+
+import (
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rotisserie/eris"
+)
+
+// envTemplatePlaceholder matches the `{{ <path> }}` and `{{{ <path> }}}` placeholders in the env templates.
+// The templates are not rendered with mustache, as it HTML-escapes the values and renders the values missing
+// from the terraform outputs as empty strings, instead of escaping them for the env file format and reporting them.
+var envTemplatePlaceholder = regexp.MustCompile(`{{{?\s*([^{}\s]+)\s*}?}}`)
+
+// MissingOutputsError is returned when the env template refers to values that are not in the terraform outputs.
+type MissingOutputsError struct {
+	Paths []string // sorted paths of the missing values, e.g. `tr_component_postgres_host.value.db`
+}
+
+func (e *MissingOutputsError) Error() string {
+	return "missing terraform outputs: " + strings.Join(e.Paths, ", ")
+}
+
+// RenderEnvTemplate replaces the placeholders in the env template, as created by GetAppEnvTemplate and rendered
+// in the given format, with the values from the terraform outputs (i.e. `terraform output -json`).
+// The values are escaped for the format when its renderer implements EnvValueEscaper, and are left as is otherwise.
+// It returns a MissingOutputsError if any of the placeholders can not be resolved.
+func RenderEnvTemplate(format EnvFormat, template string, tfOutputs map[string]interface{}) (string, error) {
+	escape := func(val string) string { return val }
+	if e, ok := envRenderers[format].(EnvValueEscaper); ok {
+		escape = e.EscapeValue
+	}
+
+	missing := map[string]struct{}{}
+	rendered := envTemplatePlaceholder.ReplaceAllStringFunc(template, func(placeholder string) string {
+		p := envTemplatePlaceholder.FindStringSubmatch(placeholder)[1]
+		val, ok := lookupOutput(tfOutputs, p)
+		if !ok {
+			missing[p] = struct{}{}
+			return placeholder
+		}
+		return escape(val)
+	})
+
+	if len(missing) > 0 {
+		err := &MissingOutputsError{}
+		for p := range missing {
+			err.Paths = append(err.Paths, p)
+		}
+		sort.Strings(err.Paths)
+		return "", err
+	}
+
+	return rendered, nil
+}
+
+// lookupOutput returns the value at the dot separated path in the terraform outputs, formatted as a string.
+func lookupOutput(tfOutputs map[string]interface{}, valPath string) (string, bool) {
+	var val interface{} = tfOutputs
+	for _, key := range strings.Split(valPath, ".") {
+		m, ok := val.(map[string]interface{})
+		if !ok {
+			return "", false
+		}
+
+		if val, ok = m[key]; !ok {
+			return "", false
+		}
+	}
+
+	switch v := val.(type) {
+	case nil:
+		return "", false
+	case string:
+		return v, true
+	case bool:
+		return strconv.FormatBool(v), true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return "", false
+		}
+		return string(b), true
+	}
+}
+
+// ParseTerraformOutputs parses the output of `terraform output -json`.
+func ParseTerraformOutputs(content []byte) (map[string]interface{}, error) {
+	tfOutputs := map[string]interface{}{}
+	if err := json.Unmarshal(content, &tfOutputs); err != nil {
+		return nil, eris.Wrap(err, "failed to parse terraform outputs. expected the output of 'terraform output -json'")
+	}
+
+	return tfOutputs, nil
+}
@@ -0,0 +1,119 @@
+// Copyright (c) Ollion
+// SPDX-License-Identifier: Apache-2.0
+
+package utils
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestRenderEnvTemplate(t *testing.T) {
+	tfOutputs, err := ParseTerraformOutputs([]byte(`{
+		"tr_component_postgres_host": {"sensitive": false, "value": {"db": "db.example.com"}},
+		"tr_component_postgres_port": {"sensitive": false, "value": {"db": 5432}},
+		"tr_component_postgres_ssl": {"sensitive": false, "value": {"db": true}},
+		"tr_component_postgres_zones": {"sensitive": false, "value": {"db": ["a", "b"]}},
+		"tr_component_postgres_password": {"sensitive": true, "value": {"db": null}}
+	}`))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		template string
+		want     string
+		wantErr  string
+	}{
+		{
+			name:     "all types",
+			template: "HOST=\"{{ tr_component_postgres_host.value.db }}\"\nPORT={{tr_component_postgres_port.value.db}}\nSSL={{{ tr_component_postgres_ssl.value.db }}}\nZONES={{ tr_component_postgres_zones.value.db }}\n",
+			want:     "HOST=\"db.example.com\"\nPORT=5432\nSSL=true\nZONES=[\\\"a\\\",\\\"b\\\"]\n",
+		},
+		{
+			name:     "templated value",
+			template: `URL="postgres://{{ tr_component_postgres_host.value.db }}:{{ tr_component_postgres_port.value.db }}"`,
+			want:     `URL="postgres://db.example.com:5432"`,
+		},
+		{
+			name:     "missing outputs",
+			template: "A={{ tr_component_redis_host.value.cache }}\nB={{ tr_component_postgres_host.value.other }}\nC={{ tr_component_postgres_password.value.db }}\nD={{ tr_component_redis_host.value.cache }}\n",
+			wantErr:  "missing terraform outputs: tr_component_postgres_host.value.other, tr_component_postgres_password.value.db, tr_component_redis_host.value.cache",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RenderEnvTemplate(EnvFormatDotEnv, tt.template, tfOutputs)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRenderEnvTemplate_escaping(t *testing.T) {
+	password := "p\"a'ss\\w: #${o}%{r}\nd&<>"
+	tfOutputs := map[string]interface{}{
+		"tr_component_postgres_password": map[string]interface{}{"value": map[string]interface{}{"db": password}},
+	}
+	vars := EnvVars{{Key: "DB_PASSWORD", Value: "{{ tr_component_postgres_password.value.db }}", Sensitive: true}}
+
+	parsers := map[EnvFormat]func(t *testing.T, content []byte) string{
+		EnvFormatDotEnv: func(t *testing.T, content []byte) string {
+			val, err := strconv.Unquote(strings.TrimSuffix(strings.TrimPrefix(string(content), "DB_PASSWORD="), "\n"))
+			require.NoError(t, err)
+			return val
+		},
+		EnvFormatJSON: func(t *testing.T, content []byte) string {
+			m := map[string]string{}
+			require.NoError(t, json.Unmarshal(content, &m))
+			return m["DB_PASSWORD"]
+		},
+		EnvFormatK8sSecret: func(t *testing.T, content []byte) string {
+			obj := struct {
+				StringData map[string]string `yaml:"stringData"`
+			}{}
+			require.NoError(t, yaml.Unmarshal(content, &obj))
+			return obj.StringData["DB_PASSWORD"]
+		},
+		EnvFormatDockerCompose: func(t *testing.T, content []byte) string {
+			obj := struct {
+				Services map[string]struct {
+					Environment map[string]string `yaml:"environment"`
+				} `yaml:"services"`
+			}{}
+			require.NoError(t, yaml.Unmarshal(content, &obj))
+			return obj.Services["voting_be"].Environment["DB_PASSWORD"]
+		},
+		EnvFormatTerraformOutput: func(t *testing.T, content []byte) string {
+			f, diags := hclsyntax.ParseConfig(content, "env.tf", hcl.InitialPos)
+			require.False(t, diags.HasErrors(), diags.Error())
+			val, diags := f.Body.(*hclsyntax.Body).Blocks[0].Body.Attributes["value"].Expr.Value(nil)
+			require.False(t, diags.HasErrors(), diags.Error())
+			return val.GetAttr("DB_PASSWORD").AsString()
+		},
+	}
+
+	for format, parse := range parsers {
+		t.Run(string(format), func(t *testing.T) {
+			template, err := vars.RenderAs(format, "voting_be")
+			require.NoError(t, err)
+
+			got, err := RenderEnvTemplate(format, string(template), tfOutputs)
+			require.NoError(t, err)
+			assert.Equal(t, password, parse(t, []byte(got)))
+		})
+	}
+}
@@ -0,0 +1,337 @@
+// Copyright (c) Ollion
+// SPDX-License-Identifier: Apache-2.0
+
+package parser
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/cldcvr/terrarium/src/pkg/jsonschema"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/ext/typeexpr"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/rotisserie/eris"
+	"github.com/xeipuuv/gojsonschema"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// TraversalResolver returns the schema of the value referred by the traversal (i.e. "module.db[*].host"),
+// or nil if it is not known.
+type TraversalResolver func(traversal hcl.Traversal) *jsonschema.Node
+
+// functions with a known return type.
+var funcTypes = map[string]cty.Type{}
+
+func init() {
+	add := func(ty cty.Type, names ...string) {
+		for _, name := range names {
+			funcTypes[name] = ty
+		}
+	}
+
+	add(cty.String,
+		"abspath", "base64decode", "base64encode", "basename", "chomp", "cidrhost", "cidrnetmask", "cidrsubnet", "dirname",
+		"file", "format", "join", "jsonencode", "lower", "md5", "replace", "sha1", "sha256", "sha512", "substr", "templatefile",
+		"timestamp", "title", "tostring", "trim", "trimprefix", "trimspace", "trimsuffix", "upper", "urlencode", "uuid", "yamlencode")
+	add(cty.Number,
+		"abs", "ceil", "floor", "index", "length", "log", "max", "min", "parseint", "pow", "signum", "sum", "tonumber")
+	add(cty.Bool,
+		"alltrue", "anytrue", "can", "contains", "endswith", "startswith", "tobool")
+	add(cty.List(cty.String),
+		"cidrsubnets", "formatlist", "keys", "split")
+	add(cty.List(cty.Number),
+		"range")
+	add(cty.List(cty.DynamicPseudoType),
+		"chunklist", "compact", "concat", "distinct", "flatten", "reverse", "setintersection", "setproduct", "setsubtract",
+		"setunion", "slice", "sort", "tolist", "toset", "values")
+	add(cty.Map(cty.DynamicPseudoType),
+		"merge", "tomap", "transpose", "zipmap")
+}
+
+// InferSchema returns the schema of the value of the expression inferred from its syntax,
+// or nil if the type can not be inferred.
+func InferSchema(expr hcl.Expression, resolve TraversalResolver) *jsonschema.Node {
+	return newTypeInferrer(resolve).infer(expr)
+}
+
+// InferElementSchema returns the schema of the elements of the collection built by the expression,
+// i.e. the value of each key in '{ for k, v in module.db : k => v.host }', or nil if it can not be inferred.
+func InferElementSchema(expr hcl.Expression, resolve TraversalResolver) *jsonschema.Node {
+	ti := newTypeInferrer(resolve)
+	if fe, ok := expr.(*hclsyntax.ForExpr); ok {
+		return ti.inferForValue(fe)
+	}
+
+	if node := ti.infer(expr); node != nil {
+		return node.Items
+	}
+
+	return nil
+}
+
+// ParseTypeSchema parses a JSON schema type name (i.e. "string") or a terraform type constraint (i.e. "list(string)")
+// and returns the schema of the type.
+func ParseTypeSchema(typeStr string) (*jsonschema.Node, error) {
+	switch typeStr {
+	case gojsonschema.TYPE_STRING, gojsonschema.TYPE_NUMBER, gojsonschema.TYPE_INTEGER, gojsonschema.TYPE_BOOLEAN, gojsonschema.TYPE_ARRAY, gojsonschema.TYPE_OBJECT:
+		return &jsonschema.Node{Type: typeStr}, nil
+	}
+
+	ty, defaults, err := ParseType(typeStr)
+	if err != nil {
+		return nil, err
+	}
+
+	node := TypeSchema(ty)
+	setTypeDefaults(node, defaults)
+	return node, nil
+}
+
+// ParseType parses a terraform type constraint (i.e. "list(string)") and returns the type,
+// along with the default values of its optional object attributes.
+func ParseType(typeStr string) (cty.Type, *typeexpr.Defaults, error) {
+	expr, diags := hclsyntax.ParseExpression([]byte(typeStr), "", hcl.InitialPos)
+	if diags.HasErrors() {
+		return cty.NilType, nil, eris.Wrapf(diags, "invalid type '%s'", typeStr)
+	}
+
+	ty, defaults, diags := typeexpr.TypeConstraintWithDefaults(expr)
+	if diags.HasErrors() {
+		return cty.NilType, nil, eris.Wrapf(diags, "invalid type '%s'", typeStr)
+	}
+
+	return ty, defaults, nil
+}
+
+// setTypeDefaults sets the default values of the optional object attributes (i.e. 'optional(number, 5)') in the schema.
+func setTypeDefaults(node *jsonschema.Node, defaults *typeexpr.Defaults) {
+	if node == nil || defaults == nil {
+		return
+	}
+
+	for name, val := range defaults.DefaultValues {
+		if prop, ok := node.Properties[name]; ok && !val.IsNull() {
+			prop.Default = ctyToGo(val)
+		}
+	}
+
+	for key, child := range defaults.Children {
+		if key == "" {
+			setTypeDefaults(node.Items, child)
+		} else {
+			setTypeDefaults(node.Properties[key], child)
+		}
+	}
+}
+
+// ctyToGo converts the value to the go types used for JSON values (i.e. float64 for numbers).
+func ctyToGo(val cty.Value) interface{} {
+	b, err := ctyjson.Marshal(val, val.Type())
+	if err != nil {
+		return nil
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return nil
+	}
+
+	return v
+}
+
+// TypeSchema returns the schema of the terraform type. The object attributes that are not optional are required.
+func TypeSchema(ty cty.Type) *jsonschema.Node {
+	switch {
+	case ty == cty.String:
+		return &jsonschema.Node{Type: gojsonschema.TYPE_STRING}
+	case ty == cty.Number:
+		return &jsonschema.Node{Type: gojsonschema.TYPE_NUMBER}
+	case ty == cty.Bool:
+		return &jsonschema.Node{Type: gojsonschema.TYPE_BOOLEAN}
+	case ty.IsListType(), ty.IsSetType():
+		node := &jsonschema.Node{Type: gojsonschema.TYPE_ARRAY}
+		if ty.ElementType() != cty.DynamicPseudoType {
+			node.Items = TypeSchema(ty.ElementType())
+		}
+		return node
+	case ty.IsTupleType():
+		node := &jsonschema.Node{Type: gojsonschema.TYPE_ARRAY}
+		if elems := ty.TupleElementTypes(); len(elems) > 0 {
+			node.Items = TypeSchema(elems[0])
+		}
+		return node
+	case ty.IsMapType():
+		return &jsonschema.Node{Type: gojsonschema.TYPE_OBJECT}
+	case ty.IsObjectType():
+		node := &jsonschema.Node{Type: gojsonschema.TYPE_OBJECT, Properties: map[string]*jsonschema.Node{}}
+		for name, attrType := range ty.AttributeTypes() {
+			node.Properties[name] = TypeSchema(attrType)
+			if !ty.AttributeOptional(name) {
+				node.Required = append(node.Required, name)
+			}
+		}
+		sort.Strings(node.Required)
+		return node
+	}
+
+	return &jsonschema.Node{} // any type
+}
+
+type typeInferrer struct {
+	resolve TraversalResolver
+	scope   map[string]func(rest hcl.Traversal) *jsonschema.Node // for expression variables
+}
+
+func newTypeInferrer(resolve TraversalResolver) *typeInferrer {
+	if resolve == nil {
+		resolve = func(hcl.Traversal) *jsonschema.Node { return nil }
+	}
+
+	return &typeInferrer{resolve: resolve, scope: map[string]func(hcl.Traversal) *jsonschema.Node{}}
+}
+
+func (ti *typeInferrer) infer(expr hcl.Expression) *jsonschema.Node {
+	switch e := expr.(type) {
+	case *hclsyntax.LiteralValueExpr:
+		if e.Val.IsNull() {
+			return nil
+		}
+		return TypeSchema(e.Val.Type())
+	case *hclsyntax.TemplateExpr:
+		return &jsonschema.Node{Type: gojsonschema.TYPE_STRING}
+	case *hclsyntax.TemplateWrapExpr:
+		return ti.infer(e.Wrapped)
+	case *hclsyntax.ParenthesesExpr:
+		return ti.infer(e.Expression)
+	case *hclsyntax.TupleConsExpr:
+		node := &jsonschema.Node{Type: gojsonschema.TYPE_ARRAY}
+		if len(e.Exprs) > 0 {
+			node.Items = ti.infer(e.Exprs[0])
+		}
+		return node
+	case *hclsyntax.ObjectConsExpr:
+		return &jsonschema.Node{Type: gojsonschema.TYPE_OBJECT}
+	case *hclsyntax.ForExpr:
+		if e.KeyExpr != nil {
+			return &jsonschema.Node{Type: gojsonschema.TYPE_OBJECT}
+		}
+		return &jsonschema.Node{Type: gojsonschema.TYPE_ARRAY, Items: ti.inferForValue(e)}
+	case *hclsyntax.SplatExpr:
+		return &jsonschema.Node{Type: gojsonschema.TYPE_ARRAY}
+	case *hclsyntax.BinaryOpExpr:
+		if e.Op != nil {
+			return TypeSchema(e.Op.Type)
+		}
+	case *hclsyntax.UnaryOpExpr:
+		if e.Op != nil {
+			return TypeSchema(e.Op.Type)
+		}
+	case *hclsyntax.ConditionalExpr:
+		if node := ti.infer(e.TrueResult); node != nil {
+			return node
+		}
+		return ti.infer(e.FalseResult)
+	case *hclsyntax.FunctionCallExpr:
+		return ti.inferFunctionCall(e)
+	}
+
+	if traversal, ok := getTraversal(expr); ok {
+		return ti.inferTraversal(traversal)
+	}
+
+	if e, ok := expr.(*hclsyntax.IndexExpr); ok {
+		if node := ti.infer(e.Collection); node != nil {
+			return node.Items
+		}
+	}
+
+	return nil
+}
+
+func (ti *typeInferrer) inferFunctionCall(e *hclsyntax.FunctionCallExpr) *jsonschema.Node {
+	if ty, ok := funcTypes[e.Name]; ok {
+		return TypeSchema(ty)
+	}
+
+	if len(e.Args) == 0 {
+		return nil
+	}
+
+	switch e.Name {
+	case "coalesce", "nonsensitive", "sensitive", "try":
+		return ti.infer(e.Args[0])
+	case "element", "one":
+		if node := ti.infer(e.Args[0]); node != nil {
+			return node.Items
+		}
+	case "lookup":
+		if len(e.Args) > 2 {
+			return ti.infer(e.Args[2])
+		}
+	}
+
+	return nil
+}
+
+// inferForValue returns the schema of the value of each item of the for expression.
+func (ti *typeInferrer) inferForValue(e *hclsyntax.ForExpr) *jsonschema.Node {
+	var elem func(rest hcl.Traversal) *jsonschema.Node
+	if coll, ok := getTraversal(e.CollExpr); ok {
+		elem = func(rest hcl.Traversal) *jsonschema.Node {
+			traversal := append(append(hcl.Traversal{}, coll...), hcl.TraverseIndex{Key: cty.DynamicVal})
+			return ti.inferTraversal(append(traversal, rest...))
+		}
+	} else {
+		var items *jsonschema.Node
+		if node := ti.infer(e.CollExpr); node != nil {
+			items = node.Items
+		}
+		elem = func(rest hcl.Traversal) *jsonschema.Node {
+			if len(rest) == 0 {
+				return items
+			}
+			return nil
+		}
+	}
+
+	prev, hadPrev := ti.scope[e.ValVar]
+	ti.scope[e.ValVar] = elem
+	defer func() {
+		if hadPrev {
+			ti.scope[e.ValVar] = prev
+		} else {
+			delete(ti.scope, e.ValVar)
+		}
+	}()
+
+	return ti.infer(e.ValExpr)
+}
+
+func (ti *typeInferrer) inferTraversal(traversal hcl.Traversal) *jsonschema.Node {
+	if elem, ok := ti.scope[traversal.RootName()]; ok {
+		return elem(traversal[1:])
+	}
+
+	return ti.resolve(traversal)
+}
+
+// getTraversal returns the absolute traversal of a reference expression, with the variable index keys
+// replaced by unknown values, i.e. "module.db[*].host" for 'module.db[each.key].host'.
+func getTraversal(expr hcl.Expression) (hcl.Traversal, bool) {
+	switch e := expr.(type) {
+	case *hclsyntax.ScopeTraversalExpr:
+		return e.Traversal, true
+	case *hclsyntax.RelativeTraversalExpr:
+		if source, ok := getTraversal(e.Source); ok {
+			return append(append(hcl.Traversal{}, source...), e.Traversal...), true
+		}
+	case *hclsyntax.IndexExpr:
+		if coll, ok := getTraversal(e.Collection); ok {
+			return append(append(hcl.Traversal{}, coll...), hcl.TraverseIndex{Key: cty.DynamicVal}), true
+		}
+	}
+
+	return nil, false
+}
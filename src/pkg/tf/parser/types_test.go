@@ -0,0 +1,169 @@
+// Copyright (c) Ollion
+// SPDX-License-Identifier: Apache-2.0
+
+package parser
+
+import (
+	"testing"
+
+	"github.com/cldcvr/terrarium/src/pkg/jsonschema"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInferElementSchema(t *testing.T) {
+	// resolves the outputs of 'module.db' instances
+	resolve := func(traversal hcl.Traversal) *jsonschema.Node {
+		if traversal.RootName() != "module" || len(traversal) != 4 {
+			return nil
+		}
+
+		switch traversal[3].(hcl.TraverseAttr).Name {
+		case "host":
+			return &jsonschema.Node{Type: "string"}
+		case "port":
+			return &jsonschema.Node{Type: "number"}
+		}
+
+		return nil
+	}
+
+	tests := []struct {
+		name string
+		expr string
+		want *jsonschema.Node
+	}{
+		{
+			name: "module output",
+			expr: `{ for k, v in module.db : k => v.host }`,
+			want: &jsonschema.Node{Type: "string"},
+		},
+		{
+			name: "indexed module output",
+			expr: `{ for k, _ in local.tr_component_db : k => module.db[k].port }`,
+			want: &jsonschema.Node{Type: "number"},
+		},
+		{
+			name: "unknown module output",
+			expr: `{ for k, v in module.db : k => v.arn }`,
+			want: nil,
+		},
+		{
+			name: "template",
+			expr: `{ for k, v in module.db : k => "${v.host}:${v.port}" }`,
+			want: &jsonschema.Node{Type: "string"},
+		},
+		{
+			name: "wrapped template",
+			expr: `{ for k, v in module.db : k => "${v.port}" }`,
+			want: &jsonschema.Node{Type: "number"},
+		},
+		{
+			name: "function",
+			expr: `{ for k, v in module.db : k => split(",", v.host) }`,
+			want: &jsonschema.Node{Type: "array", Items: &jsonschema.Node{Type: "string"}},
+		},
+		{
+			name: "list",
+			expr: `{ for k, v in module.db : k => [v.port] }`,
+			want: &jsonschema.Node{Type: "array", Items: &jsonschema.Node{Type: "number"}},
+		},
+		{
+			name: "conditional",
+			expr: `{ for k, v in module.db : k => v.port > 0 ? v.host : null }`,
+			want: &jsonschema.Node{Type: "string"},
+		},
+		{
+			name: "comparison",
+			expr: `{ for k, v in module.db : k => v.port == 5432 }`,
+			want: &jsonschema.Node{Type: "boolean"},
+		},
+		{
+			name: "list of values",
+			expr: `[for v in module.db : v.host]`,
+			want: &jsonschema.Node{Type: "string"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, diags := hclsyntax.ParseExpression([]byte(tt.expr), "", hcl.InitialPos)
+			require.False(t, diags.HasErrors(), diags.Error())
+
+			assert.Equal(t, tt.want, InferElementSchema(expr, resolve))
+		})
+	}
+}
+
+func TestParseTypeSchema(t *testing.T) {
+	tests := []struct {
+		name    string
+		typeStr string
+		want    *jsonschema.Node
+		wantErr bool
+	}{
+		{
+			name:    "json schema type",
+			typeStr: "integer",
+			want:    &jsonschema.Node{Type: "integer"},
+		},
+		{
+			name:    "terraform primitive type",
+			typeStr: "bool",
+			want:    &jsonschema.Node{Type: "boolean"},
+		},
+		{
+			name:    "terraform collection type",
+			typeStr: "list(string)",
+			want:    &jsonschema.Node{Type: "array", Items: &jsonschema.Node{Type: "string"}},
+		},
+		{
+			name:    "terraform object type",
+			typeStr: "object({ name = string, port = optional(number) })",
+			want: &jsonschema.Node{
+				Type: "object",
+				Properties: map[string]*jsonschema.Node{
+					"name": {Type: "string"},
+					"port": {Type: "number"},
+				},
+				Required: []string{"name"},
+			},
+		},
+		{
+			name:    "terraform object type with optional defaults",
+			typeStr: `list(object({ name = string, port = optional(number, 5432), tags = optional(map(string), { env = "dev" }) }))`,
+			want: &jsonschema.Node{
+				Type: "array",
+				Items: &jsonschema.Node{
+					Type: "object",
+					Properties: map[string]*jsonschema.Node{
+						"name": {Type: "string"},
+						"port": {Type: "number", Default: 5432.0},
+						"tags": {Type: "object", Default: map[string]interface{}{"env": "dev"}},
+					},
+					Required: []string{"name"},
+				},
+			},
+		},
+		{
+			name:    "invalid type",
+			typeStr: "list(strng)",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTypeSchema(tt.typeStr)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
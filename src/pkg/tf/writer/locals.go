@@ -12,11 +12,23 @@ import (
 	"github.com/rotisserie/eris"
 )
 
+// WriteLocals writes the values as a terraform locals block. The values of type hclwrite.Tokens are
+// written as is, e.g. to keep the expression of a local as it is declared in the source module.
 func WriteLocals(val map[string]interface{}, out io.Writer) error {
+	values := make(map[string]interface{}, len(val))
+	rawExprs := map[string]hclwrite.Tokens{}
+	for k, v := range val {
+		if tokens, ok := v.(hclwrite.Tokens); ok {
+			rawExprs[k] = tokens
+		} else {
+			values[k] = v
+		}
+	}
+
 	// Convert the map to cty.Value
-	ctyData, err := utils.ToCtyValue(val)
+	ctyData, err := utils.ToCtyValue(values)
 	if err != nil {
-		return eris.Wrapf(err, "error converting given value to hcl: %v", val)
+		return eris.Wrapf(err, "error converting given value to hcl: %v", values)
 	}
 	data := ctyData.AsValueMap()
 
@@ -28,15 +40,19 @@ func WriteLocals(val map[string]interface{}, out io.Writer) error {
 	localsBlock := rootBody.AppendNewBlock("locals", nil)
 
 	// Sort the keys for deterministic output
-	keys := make([]string, 0, len(data))
-	for k := range data {
+	keys := make([]string, 0, len(val))
+	for k := range val {
 		keys = append(keys, k)
 	}
 	sort.Strings(keys)
 
 	// Set the local variables in the HCL block in sorted order
 	for _, k := range keys {
-		localsBlock.Body().SetAttributeValue(k, data[k])
+		if tokens, ok := rawExprs[k]; ok {
+			localsBlock.Body().SetAttributeRaw(k, tokens)
+		} else {
+			localsBlock.Body().SetAttributeValue(k, data[k])
+		}
 	}
 
 	// Write the HCL content to the provided io.Writer
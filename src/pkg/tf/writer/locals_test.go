@@ -7,6 +7,8 @@ import (
 	"bytes"
 	"testing"
 
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclwrite"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -48,6 +50,13 @@ func TestToTFLocals(t *testing.T) {
 			},
 			output: "locals {\n  key1 = [1, 2, 3]\n}\n",
 		},
+		{
+			input: map[string]interface{}{
+				"enabled":     hclwrite.TokensForTraversal(hcl.Traversal{hcl.TraverseRoot{Name: "local"}, hcl.TraverseAttr{Name: "switch"}}),
+				"instance_id": "i-123",
+			},
+			output: "locals {\n  enabled     = local.switch\n  instance_id = \"i-123\"\n}\n",
+		},
 		{
 			input: map[string]interface{}{
 				"key1": struct{}{},